@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+const (
+	apiKeyHeader        = "X-API-Key"
+	authorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+)
+
+type apiKeyContextKey struct{}
+
+// authenticates requests with api keys and authorizes them against the scope required by the route
+type Authenticator struct {
+	apiKeyService core.APIKeyService
+	enabled       bool
+}
+
+func NewAuthenticator(apiKeyService core.APIKeyService, enabled bool) *Authenticator {
+	return &Authenticator{apiKeyService: apiKeyService, enabled: enabled}
+}
+
+// wraps the handler so that it is only called for requests carrying a valid api key with the given scope
+func (authenticator Authenticator) Require(scope string, next http.HandlerFunc) http.HandlerFunc {
+	if !authenticator.enabled {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		rawKey := requestAPIKey(r)
+		if rawKey == "" {
+			unauthorizedResponse(w, "api key required")
+			return
+		}
+
+		apiKey, err := authenticator.apiKeyService.Authenticate(rawKey)
+		if err != nil {
+			log.Printf(support.Error, err)
+			errorResponse(w, http.StatusInternalServerError, "Unable to authenticate request")
+
+			return
+		}
+
+		if apiKey == nil {
+			unauthorizedResponse(w, "invalid api key")
+			return
+		}
+
+		if !apiKey.HasScope(scope) {
+			errorResponse(w, http.StatusForbidden, fmt.Sprintf("api key lacks required scope: %s", scope))
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, apiKey)))
+	}
+}
+
+// the api key that authenticated the request, nil when authentication is disabled
+func requestAPIKeyInfo(r *http.Request) *models.APIKey {
+	apiKey, _ := r.Context().Value(apiKeyContextKey{}).(*models.APIKey)
+	return apiKey
+}
+
+func requestAPIKey(r *http.Request) string {
+	if rawKey := r.Header.Get(apiKeyHeader); rawKey != "" {
+		return rawKey
+	}
+
+	if authorization := r.Header.Get(authorizationHeader); strings.HasPrefix(authorization, bearerPrefix) {
+		return strings.TrimSpace(strings.TrimPrefix(authorization, bearerPrefix))
+	}
+
+	return ""
+}
+
+func unauthorizedResponse(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="detection-api"`)
+	errorResponse(w, http.StatusUnauthorized, msg)
+}
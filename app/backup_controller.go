@@ -0,0 +1,53 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/db"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// rest controller for snapshots of the event db
+type BackupController struct {
+	eventDb   *db.SqLiteDb
+	backupDir string
+}
+
+// writes a snapshot of the event db to the backup dir while the server keeps serving
+func (controller BackupController) BackupHandler(w http.ResponseWriter, r *http.Request) {
+	backupDir := support.Resolve(controller.backupDir)
+	if err := os.MkdirAll(backupDir, 0750); err != nil {
+		log.Printf(support.Error, err)
+		errorResponse(w, http.StatusInternalServerError, "Unable to create backup")
+
+		return
+	}
+
+	path := filepath.Join(backupDir, fmt.Sprintf("event_db-%s.db",
+		time.Now().UTC().Format("20060102T150405.000Z")))
+
+	if err := controller.eventDb.Backup(path); err != nil {
+		log.Printf(support.Error, err)
+		errorResponse(w, http.StatusInternalServerError, "Unable to create backup")
+
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Printf(support.Error, err)
+		errorResponse(w, http.StatusInternalServerError, "Unable to create backup")
+
+		return
+	}
+
+	responseJSON(w, http.StatusCreated, map[string]interface{}{
+		"file":       path,
+		"size_bytes": info.Size(),
+	})
+}
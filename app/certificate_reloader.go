@@ -0,0 +1,136 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify-if-given":    tls.VerifyClientCertIfGiven,
+	"require-and-verify": tls.RequireAndVerifyClientCert,
+}
+
+// holds the server certificate and the client CAs, and replaces them when the files change on disk without
+// restarting the server. connections established before a reload keep the certificate they negotiated
+type CertificateReloader struct {
+	config      config.TLSConfig
+	minVersion  uint16
+	clientAuth  tls.ClientAuthType
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+	mutex       sync.RWMutex
+}
+
+func NewCertificateReloader(tlsConfig config.TLSConfig) (*CertificateReloader, error) {
+	minVersion, ok := tlsVersions[tlsConfig.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported tls minVersion: %s", tlsConfig.MinVersion)
+	}
+
+	clientAuth, ok := clientAuthTypes[tlsConfig.ClientAuth]
+	if !ok {
+		return nil, fmt.Errorf("unsupported tls clientAuth: %s", tlsConfig.ClientAuth)
+	}
+
+	reloader := &CertificateReloader{config: tlsConfig, minVersion: minVersion, clientAuth: clientAuth}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// reads the certificate, key and client CAs again, on failure the previously loaded ones stay in use
+func (reloader *CertificateReloader) Reload() error {
+	certificate, err := tls.LoadX509KeyPair(support.Resolve(reloader.config.CertFile),
+		support.Resolve(reloader.config.KeyFile))
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+
+	if reloader.verifiesClients() {
+		pem, err := ioutil.ReadFile(support.Resolve(reloader.config.ClientCAFile))
+		if err != nil {
+			return err
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA file: %s", reloader.config.ClientCAFile)
+		}
+	}
+
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+
+	reloader.certificate = &certificate
+	reloader.clientCAs = clientCAs
+
+	return nil
+}
+
+// reloads whenever one of the signals is received
+func (reloader *CertificateReloader) WatchSignals(signals ...os.Signal) {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, signals...)
+
+	go func() {
+		for sig := range sigc {
+			log.Printf(support.Info, fmt.Sprintf("%v received, reloading tls certificates", sig))
+
+			if err := reloader.Reload(); err != nil {
+				log.Printf(support.Error, fmt.Sprintf("tls reload failed, keeping previous certificates: %v", err))
+			}
+		}
+	}()
+}
+
+// the per connection config carries the current certificate and client CAs, GetCertificate is set as well
+// because http.Server.ServeTLS only skips loading certificate files when it is present
+func (reloader *CertificateReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: reloader.minVersion,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			reloader.mutex.RLock()
+			defer reloader.mutex.RUnlock()
+
+			return reloader.certificate, nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			reloader.mutex.RLock()
+			defer reloader.mutex.RUnlock()
+
+			return &tls.Config{
+				MinVersion:   reloader.minVersion,
+				Certificates: []tls.Certificate{*reloader.certificate},
+				ClientAuth:   reloader.clientAuth,
+				ClientCAs:    reloader.clientCAs,
+			}, nil
+		},
+	}
+}
+
+func (reloader *CertificateReloader) verifiesClients() bool {
+	return reloader.clientAuth == tls.VerifyClientCertIfGiven || reloader.clientAuth == tls.RequireAndVerifyClientCert
+}
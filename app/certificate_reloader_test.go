@@ -0,0 +1,266 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/support"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/stretchr/testify/require"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pair        tls.Certificate
+}
+
+func TestServe_With_TLS(t *testing.T) {
+	certDir := support.NewTemporaryDir("", "tls-test")
+	defer certDir.Clean()
+
+	req := require.New(t)
+	ca := newTestCertificate("test-ca", nil)
+	writeTestCertificate(certDir.Path(), "server", newTestCertificate("server-1", ca))
+
+	address, closeServer := startTestServer(config.TLSConfig{
+		Enabled:    true,
+		CertFile:   filepath.Join(certDir.Path(), "server.crt"),
+		KeyFile:    filepath.Join(certDir.Path(), "server.key"),
+		MinVersion: "1.2",
+		ClientAuth: "none",
+	})
+	defer closeServer()
+
+	response, err := newTestClient(ca, nil, tls.VersionTLS13).Get("https://" + address + "/api/health-check")
+	req.NoError(err)
+	req.NoError(response.Body.Close())
+	req.Equal(http.StatusOK, response.StatusCode)
+	req.Equal("server-1", response.TLS.PeerCertificates[0].Subject.CommonName)
+
+	_, err = newTestClient(ca, nil, tls.VersionTLS11).Get("https://" + address + "/api/health-check")
+	req.Error(err)
+}
+
+func TestServe_With_Mutual_TLS(t *testing.T) {
+	certDir := support.NewTemporaryDir("", "tls-test")
+	defer certDir.Clean()
+
+	req := require.New(t)
+	ca := newTestCertificate("test-ca", nil)
+	otherCA := newTestCertificate("other-ca", nil)
+	writeTestCertificate(certDir.Path(), "server", newTestCertificate("server-1", ca))
+	writeTestCertificate(certDir.Path(), "client-ca", ca)
+
+	address, closeServer := startTestServer(config.TLSConfig{
+		Enabled:      true,
+		CertFile:     filepath.Join(certDir.Path(), "server.crt"),
+		KeyFile:      filepath.Join(certDir.Path(), "server.key"),
+		ClientCAFile: filepath.Join(certDir.Path(), "client-ca.crt"),
+		MinVersion:   "1.2",
+		ClientAuth:   "require-and-verify",
+	})
+	defer closeServer()
+
+	client := newTestCertificate("collector", ca)
+	response, err := newTestClient(ca, client, tls.VersionTLS13).Get("https://" + address + "/api/health-check")
+	req.NoError(err)
+	req.NoError(response.Body.Close())
+	req.Equal(http.StatusOK, response.StatusCode)
+
+	_, err = newTestClient(ca, nil, tls.VersionTLS13).Get("https://" + address + "/api/health-check")
+	req.Error(err)
+
+	untrustedClient := newTestCertificate("intruder", otherCA)
+	_, err = newTestClient(ca, untrustedClient, tls.VersionTLS13).Get("https://" + address + "/api/health-check")
+	req.Error(err)
+}
+
+func TestCertificateReloader_Reload(t *testing.T) {
+	certDir := support.NewTemporaryDir("", "tls-test")
+	defer certDir.Clean()
+
+	req := require.New(t)
+	ca := newTestCertificate("test-ca", nil)
+	writeTestCertificate(certDir.Path(), "server", newTestCertificate("server-1", ca))
+
+	tlsConfig := config.TLSConfig{
+		Enabled:    true,
+		CertFile:   filepath.Join(certDir.Path(), "server.crt"),
+		KeyFile:    filepath.Join(certDir.Path(), "server.key"),
+		MinVersion: "1.2",
+		ClientAuth: "none",
+	}
+	reloader, err := NewCertificateReloader(tlsConfig)
+	req.NoError(err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", reloader.TLSConfig())
+	req.NoError(err)
+
+	httpServer := &http.Server{Handler: http.HandlerFunc(StatusHandler)}
+
+	go func() {
+		_ = httpServer.Serve(listener)
+	}()
+
+	defer func() {
+		req.NoError(httpServer.Close())
+	}()
+
+	url := "https://" + listener.Addr().String() + "/api/health-check"
+	req.Equal("server-1", servedCommonName(req, newTestClient(ca, nil, tls.VersionTLS13), url))
+
+	writeTestCertificate(certDir.Path(), "server", newTestCertificate("server-2", ca))
+	req.NoError(reloader.Reload())
+	req.Equal("server-2", servedCommonName(req, newTestClient(ca, nil, tls.VersionTLS13), url))
+
+	req.NoError(ioutil.WriteFile(tlsConfig.CertFile, []byte("not a certificate"), 0600))
+	req.Error(reloader.Reload())
+	req.Equal("server-2", servedCommonName(req, newTestClient(ca, nil, tls.VersionTLS13), url))
+}
+
+func TestNewCertificateReloader_When_Config_Is_Invalid(t *testing.T) {
+	req := require.New(t)
+
+	_, err := NewCertificateReloader(config.TLSConfig{MinVersion: "0.9", ClientAuth: "none"})
+	req.Error(err)
+
+	_, err = NewCertificateReloader(config.TLSConfig{MinVersion: "1.2", ClientAuth: "sometimes"})
+	req.Error(err)
+
+	_, err = NewCertificateReloader(config.TLSConfig{MinVersion: "1.2", ClientAuth: "none",
+		CertFile: "missing.crt", KeyFile: "missing.key"})
+	req.Error(err)
+}
+
+func startTestServer(tlsConfig config.TLSConfig) (string, func()) {
+	testSetup := test.SetUpWithConfig(func(appConfig *config.AppConfig) {
+		appConfig.Server.TLS = tlsConfig
+	})
+
+	httpServer, err := NewServiceContext(testSetup.AppServerContext()).NewHTTPServer()
+	if err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+
+	go func() {
+		_ = httpServer.ServeTLS(listener, "", "")
+	}()
+
+	return listener.Addr().String(), func() {
+		if err := httpServer.Close(); err != nil {
+			log.Printf(support.Warn, err)
+		}
+
+		testSetup.CleanUp()
+	}
+}
+
+func servedCommonName(req *require.Assertions, client *http.Client, url string) string {
+	response, err := client.Get(url)
+	req.NoError(err)
+	req.NoError(response.Body.Close())
+
+	return response.TLS.PeerCertificates[0].Subject.CommonName
+}
+
+func newTestClient(ca *testCertificate, clientCertificate *testCertificate, maxVersion uint16) *http.Client {
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.certificate)
+
+	tlsConfig := &tls.Config{RootCAs: rootCAs, MaxVersion: maxVersion}
+	if clientCertificate != nil {
+		tlsConfig.Certificates = []tls.Certificate{clientCertificate.pair}
+	}
+
+	return &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true},
+	}
+}
+
+// self signed when the issuer is nil, otherwise signed by the issuer
+func newTestCertificate(commonName string, issuer *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	parent, signer := template, key
+
+	if issuer == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		parent, signer = issuer.certificate, issuer.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+
+	return &testCertificate{
+		certificate: certificate,
+		key:         key,
+		pair:        tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+	}
+}
+
+func writeTestCertificate(dir, name string, certificate *testCertificate) {
+	keyDer, err := x509.MarshalECPrivateKey(certificate.key)
+	if err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.certificate.Raw})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".crt"), certPem, 0600); err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".key"), keyPem, 0600); err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+}
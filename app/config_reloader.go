@@ -0,0 +1,165 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/app/services"
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// reads the config file again when it changes or on a signal and replaces the detection parameters of the running
+// service. the reloadable keys are suspiciousSpeed and speedBands, that is speedBands.enabled,
+// speedBands.groundMaxMiles, speedBands.groundSpeed and speedBands.flightOverheadHours. a file that cannot be read
+// or fails the validation of the whole config is rejected and the previous parameters stay in use. every other key
+// still needs a restart
+type ConfigReloader struct {
+	configFile string
+	parameters *services.LiveDetectionParameters
+	modTime    time.Time
+	lock       sync.Mutex
+	stop       chan struct{}
+	stopOnce   sync.Once
+}
+
+func NewConfigReloader(configFile string, parameters *services.LiveDetectionParameters) *ConfigReloader {
+	reloader := &ConfigReloader{configFile: configFile, parameters: parameters, stop: make(chan struct{})}
+	if info, err := os.Stat(configFile); err == nil {
+		reloader.modTime = info.ModTime()
+	}
+
+	return reloader
+}
+
+// starts reloading the config file on SIGHUP, and on changes when intervalSeconds is set. nil when reloading is off,
+// or when there is no file because the embedded default config is used, which is logged as a warning
+func StartConfigReloader(reloadConfig config.ReloadConfig, configFile string,
+	parameters *services.LiveDetectionParameters) *ConfigReloader {
+	if !reloadConfig.Enabled {
+		return nil
+	}
+
+	if configFile == "" {
+		log.Printf(support.Warn, "config reload is enabled but no config file is set with CONFIG_FILE or -config, "+
+			"the embedded default config is in use and nothing will be reloaded; set CONFIG_RELOAD_ENABLED=false "+
+			"or give a config file")
+
+		return nil
+	}
+
+	reloader := NewConfigReloader(configFile, parameters)
+	reloader.WatchSignals(syscall.SIGHUP)
+
+	if reloadConfig.IntervalSeconds > 0 {
+		reloader.WatchFile(time.Duration(reloadConfig.IntervalSeconds) * time.Second)
+	}
+
+	return reloader
+}
+
+// reads the config file, validates the whole config and swaps in its detection parameters when they changed
+func (reloader *ConfigReloader) Reload() error {
+	reloader.lock.Lock()
+	defer reloader.lock.Unlock()
+
+	appConfig := config.AppConfig{}
+	if err := appConfig.ReadFile(reloader.configFile); err != nil {
+		return err
+	}
+
+	if err := appConfig.Validate(); err != nil {
+		return err
+	}
+
+	parameters := services.NewDetectionParameters(appConfig)
+
+	changes := parameters.Diff(reloader.parameters.Get())
+	if len(changes) == 0 {
+		log.Printf(support.Info, "config reloaded, detection parameters unchanged")
+		return nil
+	}
+
+	reloader.parameters.Set(parameters)
+	log.Printf(support.Info, fmt.Sprintf("config reloaded, detection parameters changed: %s",
+		strings.Join(changes, ", ")))
+
+	return nil
+}
+
+// reloads whenever one of the signals is received
+func (reloader *ConfigReloader) WatchSignals(signals ...os.Signal) {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, signals...)
+
+	go func() {
+		for {
+			select {
+			case sig := <-sigc:
+				log.Printf(support.Info, fmt.Sprintf("%v received, reloading config", sig))
+				reloader.reload()
+			case <-reloader.stop:
+				signal.Stop(sigc)
+				return
+			}
+		}
+	}()
+}
+
+// reloads when the modification time of the config file changes, checked every interval
+func (reloader *ConfigReloader) WatchFile(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if reloader.fileChanged() {
+					log.Printf(support.Info, fmt.Sprintf("%s changed, reloading config", reloader.configFile))
+					reloader.reload()
+				}
+			case <-reloader.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (reloader *ConfigReloader) Stop() {
+	reloader.stopOnce.Do(func() {
+		close(reloader.stop)
+	})
+}
+
+func (reloader *ConfigReloader) reload() {
+	if err := reloader.Reload(); err != nil {
+		log.Printf(support.Error, fmt.Sprintf("config reload rejected, keeping previous detection parameters: %v",
+			err))
+	}
+}
+
+func (reloader *ConfigReloader) fileChanged() bool {
+	info, err := os.Stat(reloader.configFile)
+	if err != nil {
+		return false
+	}
+
+	reloader.lock.Lock()
+	defer reloader.lock.Unlock()
+
+	if info.ModTime().Equal(reloader.modTime) {
+		return false
+	}
+
+	reloader.modTime = info.ModTime()
+
+	return true
+}
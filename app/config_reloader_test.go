@@ -0,0 +1,116 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/app/services"
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+	"github.com/stretchr/testify/require"
+)
+
+// writes the default config with the suspicious speed, modified at the time. the event db and the geo db are
+// placed next to the file so that the whole config is valid
+func writeTestConfig(t *testing.T, path, suspiciousSpeed string, modTime time.Time) {
+	writeTestConfigWith(t, path, modTime, map[string]string{"suspiciousSpeed": suspiciousSpeed})
+}
+
+// writes the default config with the top level keys replaced by the values, modified at the time
+func writeTestConfigWith(t *testing.T, path string, modTime time.Time, values map[string]string) {
+	yamlData, err := ioutil.ReadFile(support.Resolve("resources/config.yml"))
+	require.NoError(t, err)
+
+	dir := filepath.Dir(path)
+	geoDbFile := filepath.Join(dir, "geo.mmdb")
+	require.NoError(t, ioutil.WriteFile(geoDbFile, []byte{}, 0600))
+
+	yamlData = regexp.MustCompile(`(?m)^  file: .*$`).ReplaceAll(yamlData,
+		[]byte("  file: "+filepath.Join(dir, "event_db.db")))
+	yamlData = regexp.MustCompile(`(?m)^  location: .*$`).ReplaceAll(yamlData, []byte("  location: "+geoDbFile))
+
+	for key, value := range values {
+		yamlData = regexp.MustCompile(`(?m)^`+key+`: .*$`).ReplaceAll(yamlData, []byte(key+": "+value))
+	}
+
+	require.NoError(t, ioutil.WriteFile(path, yamlData, 0600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestConfigReloader_Swaps_Valid_Parameters(t *testing.T) {
+	configDir := support.NewTemporaryDir("", "config-reload-test")
+	defer configDir.Clean()
+
+	req := require.New(t)
+	configFile := filepath.Join(configDir.Path(), "config.yml")
+	parameters := services.NewLiveDetectionParameters(models.DetectionParameters{SuspiciousSpeed: 500})
+	reloader := NewConfigReloader(configFile, parameters)
+
+	writeTestConfig(t, configFile, "300", time.Now())
+	req.NoError(reloader.Reload())
+	req.Equal(300.0, parameters.Get().SuspiciousSpeed)
+
+	writeTestConfig(t, configFile, "-1", time.Now())
+	req.Error(reloader.Reload())
+	req.Equal(300.0, parameters.Get().SuspiciousSpeed)
+
+	req.NoError(os.Remove(configFile))
+	req.Error(reloader.Reload())
+	req.Equal(300.0, parameters.Get().SuspiciousSpeed)
+}
+
+func TestConfigReloader_Rejects_Invalid_Config(t *testing.T) {
+	configDir := support.NewTemporaryDir("", "config-reload-test")
+	defer configDir.Clean()
+
+	req := require.New(t)
+	configFile := filepath.Join(configDir.Path(), "config.yml")
+	parameters := services.NewLiveDetectionParameters(models.DetectionParameters{SuspiciousSpeed: 500})
+	reloader := NewConfigReloader(configFile, parameters)
+
+	// the detection parameters are valid, but a key that is not reloaded is not
+	writeTestConfigWith(t, configFile, time.Now(), map[string]string{"suspiciousSpeed": "300",
+		"calculator": "flat"})
+	err := reloader.Reload()
+	req.Error(err)
+	req.Contains(err.Error(), "calculator")
+	req.Equal(500.0, parameters.Get().SuspiciousSpeed)
+}
+
+func TestStartConfigReloader_Needs_File(t *testing.T) {
+	req := require.New(t)
+	parameters := services.NewLiveDetectionParameters(models.DetectionParameters{SuspiciousSpeed: 500})
+
+	req.Nil(StartConfigReloader(config.ReloadConfig{Enabled: true}, "", parameters))
+	req.Nil(StartConfigReloader(config.ReloadConfig{}, "config.yml", parameters))
+
+	reloader := StartConfigReloader(config.ReloadConfig{Enabled: true}, "config.yml", parameters)
+	req.NotNil(reloader)
+	reloader.Stop()
+}
+
+func TestConfigReloader_Watches_File(t *testing.T) {
+	configDir := support.NewTemporaryDir("", "config-reload-test")
+	defer configDir.Clean()
+
+	req := require.New(t)
+	configFile := filepath.Join(configDir.Path(), "config.yml")
+	modTime := time.Now().Add(-time.Hour)
+	writeTestConfig(t, configFile, "500", modTime)
+
+	parameters := services.NewLiveDetectionParameters(models.DetectionParameters{SuspiciousSpeed: 500})
+	reloader := NewConfigReloader(configFile, parameters)
+	reloader.WatchFile(10 * time.Millisecond)
+
+	defer reloader.Stop()
+
+	writeTestConfig(t, configFile, "250", modTime.Add(time.Minute))
+	req.Eventually(func() bool {
+		return parameters.Get().SuspiciousSpeed == 250
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -2,17 +2,24 @@ package app
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/frankiennamdi/detection-api/core"
 	"log"
 	"net/http"
 
 	"github.com/frankiennamdi/detection-api/models"
 	"github.com/frankiennamdi/detection-api/support"
+	"github.com/gorilla/mux"
 )
 
+// used when no limit is configured for the size of a request body
+const defaultMaxRequestBytes = int64(1 << 20)
+
 // rest controller for detection
 type EventDetectionController struct {
 	detectionService core.DetectionService
+	asyncService     core.AsyncDetectionService
+	maxRequestBytes  int64
 }
 
 func (controller EventDetectionController) EventDetectionHandler(w http.ResponseWriter, r *http.Request) {
@@ -20,10 +27,24 @@ func (controller EventDetectionController) EventDetectionHandler(w http.Response
 		errorResponse(w, http.StatusMethodNotAllowed, "POST Required")
 	}
 
+	maxRequestBytes := controller.maxRequestBytes
+	if maxRequestBytes <= 0 {
+		maxRequestBytes = defaultMaxRequestBytes
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBytes)
+
 	var event *models.Event
 
 	err := json.NewDecoder(r.Body).Decode(&event)
 
+	if err != nil && isRequestTooLarge(err) {
+		support.IncrementCounter(support.OversizedRequests)
+		errorResponse(w, http.StatusRequestEntityTooLarge, "request body too large")
+
+		return
+	}
+
 	if err != nil {
 		log.Printf(support.Error, err)
 		errorResponse(w, http.StatusBadRequest, "can pass request body")
@@ -31,6 +52,11 @@ func (controller EventDetectionController) EventDetectionHandler(w http.Response
 		return
 	}
 
+	if r.URL.Query().Get("async") == "true" {
+		controller.submitEvent(w, event)
+		return
+	}
+
 	suspiciousTravelResult, err := controller.detectionService.ProcessEvent(event)
 	if err != nil {
 		log.Printf(support.Error, err)
@@ -41,3 +67,52 @@ func (controller EventDetectionController) EventDetectionHandler(w http.Response
 
 	responseJSON(w, http.StatusOK, suspiciousTravelResult)
 }
+
+// queues the event for detection and responds with where the result can be fetched once it is processed
+func (controller EventDetectionController) submitEvent(w http.ResponseWriter, event *models.Event) {
+	if controller.asyncService == nil {
+		errorResponse(w, http.StatusBadRequest, "async ingestion is disabled")
+		return
+	}
+
+	if err := controller.asyncService.Submit(event); err != nil {
+		log.Printf(support.Error, err)
+		errorResponse(w, http.StatusInternalServerError, "Unable to queue request")
+
+		return
+	}
+
+	eventUUID := event.ToEventInfo().UUID
+	responseJSON(w, http.StatusAccepted, map[string]string{
+		"event_uuid": eventUUID,
+		"status":     models.QueueStatusPending,
+		"result_url": fmt.Sprintf("/api/events/%s/result", eventUUID),
+	})
+}
+
+func (controller EventDetectionController) EventResultHandler(w http.ResponseWriter, r *http.Request) {
+	if controller.asyncService == nil {
+		errorResponse(w, http.StatusNotFound, "async ingestion is disabled")
+		return
+	}
+
+	queuedEvent, err := controller.asyncService.FindResult(mux.Vars(r)["uuid"])
+	if err != nil {
+		log.Printf(support.Error, err)
+		errorResponse(w, http.StatusInternalServerError, "Unable to find result")
+
+		return
+	}
+
+	if queuedEvent == nil {
+		errorResponse(w, http.StatusNotFound, "no event submitted with this uuid")
+		return
+	}
+
+	responseJSON(w, http.StatusOK, queuedEvent)
+}
+
+// the error returned by the reader of http.MaxBytesReader once the limit is exceeded
+func isRequestTooLarge(err error) bool {
+	return err.Error() == "http: request body too large"
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/frankiennamdi/detection-api/models"
 	"github.com/frankiennamdi/detection-api/support"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/require"
 )
 
@@ -36,6 +37,24 @@ func TestEventDetectionHandler_When_Payload_Is_Bad(t *testing.T) {
 	req.Equal(`{"error":"can pass request body"}`, fmt.Sprint(requestRecorder.Body))
 }
 
+func TestEventDetectionHandler_When_Payload_Is_Too_Large(t *testing.T) {
+	detectionController := EventDetectionController{
+		detectionService: &BadMockDetectionService{},
+		maxRequestBytes:  64,
+	}
+
+	req := require.New(t)
+
+	requestRecorder := newRecordedRequest(detectionController, newPostRequest(`{
+		"username": "bob",
+		"unix_timestamp": 1514851200,
+		"event_uuid": "85ad929a-db03-4bf4-9541-8f728fa12e43",
+		"ip_address": "91.207.175.104"
+	}`))
+	req.Equal(http.StatusRequestEntityTooLarge, requestRecorder.Code)
+	req.Equal(`{"error":"request body too large"}`, fmt.Sprint(requestRecorder.Body))
+}
+
 func TestEventDetectionHandler_When_DetectionService_Fails(t *testing.T) {
 	detectionController := EventDetectionController{
 		detectionService: &BadMockDetectionService{},
@@ -78,6 +97,7 @@ func TestEventDetectionHandler(t *testing.T) {
 		Latitude:       30.5334,
 		Longitude:      -95.4559,
 		AccuracyRadius: 1000,
+		Country:        "US",
 	}
 	assertThatBodyResultEquals(expected, requestRecorder.Body, req)
 }
@@ -105,6 +125,7 @@ func TestEventDetectionHandler_With_Subsequent_Event(t *testing.T) {
 			Latitude:       30.5334,
 			Longitude:      -95.4559,
 			AccuracyRadius: 1000,
+			Country:        "US",
 		},
 	}, requestRecorder.Body, req)
 
@@ -121,6 +142,7 @@ func TestEventDetectionHandler_With_Subsequent_Event(t *testing.T) {
 			Latitude:       34.0549,
 			Longitude:      -118.2578,
 			AccuracyRadius: 200,
+			Country:        "US",
 		},
 		TravelToCurrentGeoSuspicious: boolean(false),
 		PrecedingIPAccess: &models.RelatedAccessInfo{
@@ -130,6 +152,7 @@ func TestEventDetectionHandler_With_Subsequent_Event(t *testing.T) {
 			Longitude:      -95.4559,
 			AccuracyRadius: 1000,
 			Timestamp:      1514764800,
+			Threshold:      500,
 		},
 	}, requestRecorder.Body, req)
 }
@@ -157,6 +180,7 @@ func TestEventDetectionHandler_With_Previous_And_Subsequent_Event(t *testing.T)
 			Latitude:       30.3773,
 			Longitude:      -97.71,
 			AccuracyRadius: 5,
+			Country:        "US",
 		},
 	}, requestRecorder.Body, req)
 
@@ -174,6 +198,7 @@ func TestEventDetectionHandler_With_Previous_And_Subsequent_Event(t *testing.T)
 			Latitude:       34.0549,
 			Longitude:      -118.2578,
 			AccuracyRadius: 200,
+			Country:        "US",
 		},
 		TravelFromCurrentGeoSuspicious: boolean(false),
 		SubsequentIPAccess: &models.RelatedAccessInfo{
@@ -183,6 +208,7 @@ func TestEventDetectionHandler_With_Previous_And_Subsequent_Event(t *testing.T)
 			Longitude:      -97.71,
 			AccuracyRadius: 5,
 			Timestamp:      1514851200,
+			Threshold:      500,
 		},
 	}, requestRecorder.Body, req)
 
@@ -199,6 +225,7 @@ func TestEventDetectionHandler_With_Previous_And_Subsequent_Event(t *testing.T)
 			Latitude:       30.5334,
 			Longitude:      -95.4559,
 			AccuracyRadius: 1000,
+			Country:        "US",
 		},
 		TravelToCurrentGeoSuspicious:   boolean(true),
 		TravelFromCurrentGeoSuspicious: boolean(false),
@@ -209,6 +236,7 @@ func TestEventDetectionHandler_With_Previous_And_Subsequent_Event(t *testing.T)
 			Longitude:      -118.2578,
 			AccuracyRadius: 200,
 			Timestamp:      1514761200,
+			Threshold:      500,
 		},
 		SubsequentIPAccess: &models.RelatedAccessInfo{
 			IP:             "24.242.71.20",
@@ -217,6 +245,7 @@ func TestEventDetectionHandler_With_Previous_And_Subsequent_Event(t *testing.T)
 			Longitude:      -97.71,
 			AccuracyRadius: 5,
 			Timestamp:      1514851200,
+			Threshold:      500,
 		},
 	}, requestRecorder.Body, req)
 }
@@ -265,3 +294,93 @@ func unmarshalToSuspiciousTravelResult(body string) (*models.SuspiciousTravelRes
 
 	return result, nil
 }
+
+type MockAsyncDetectionService struct {
+	queuedEvents map[string]*models.QueuedEvent
+}
+
+func (mockService *MockAsyncDetectionService) Submit(event *models.Event) error {
+	eventUUID := event.ToEventInfo().UUID
+	mockService.queuedEvents[eventUUID] = &models.QueuedEvent{
+		Event:  event,
+		UUID:   eventUUID,
+		Status: models.QueueStatusPending,
+	}
+
+	return nil
+}
+
+func (mockService *MockAsyncDetectionService) FindResult(uuid string) (*models.QueuedEvent, error) {
+	return mockService.queuedEvents[uuid], nil
+}
+
+func TestEventDetectionHandler_When_Async(t *testing.T) {
+	asyncService := &MockAsyncDetectionService{queuedEvents: make(map[string]*models.QueuedEvent)}
+	detectionController := EventDetectionController{
+		detectionService: &BadMockDetectionService{},
+		asyncService:     asyncService,
+	}
+
+	req := require.New(t)
+
+	request, err := http.NewRequest(http.MethodPost, "/api/events?async=true", strings.NewReader(`{
+		"username": "bob",
+		"unix_timestamp": 1514851200,
+		"event_uuid": "85ad929a-db03-4bf4-9541-8f728fa12e43",
+		"ip_address": "91.207.175.104"
+	}`))
+	req.NoError(err)
+
+	requestRecorder := newRecordedRequest(detectionController, request)
+	req.Equal(http.StatusAccepted, requestRecorder.Code)
+	req.JSONEq(`{
+		"event_uuid": "85ad929a-db03-4bf4-9541-8f728fa12e43",
+		"status": "pending",
+		"result_url": "/api/events/85ad929a-db03-4bf4-9541-8f728fa12e43/result"
+	}`, fmt.Sprint(requestRecorder.Body))
+
+	requestRecorder = newRecordedResultRequest(detectionController, "85ad929a-db03-4bf4-9541-8f728fa12e43")
+	req.Equal(http.StatusOK, requestRecorder.Code)
+	req.JSONEq(`{
+		"event_uuid": "85ad929a-db03-4bf4-9541-8f728fa12e43",
+		"status": "pending",
+		"enqueued_at": 0
+	}`, fmt.Sprint(requestRecorder.Body))
+
+	requestRecorder = newRecordedResultRequest(detectionController, "85ad929a-db03-4bf4-9541-8f728fa12e44")
+	req.Equal(http.StatusNotFound, requestRecorder.Code)
+}
+
+func TestEventDetectionHandler_When_Async_Is_Disabled(t *testing.T) {
+	detectionController := EventDetectionController{
+		detectionService: &BadMockDetectionService{},
+	}
+
+	req := require.New(t)
+
+	request, err := http.NewRequest(http.MethodPost, "/api/events?async=true", strings.NewReader(`{
+		"username": "bob",
+		"unix_timestamp": 1514851200,
+		"event_uuid": "85ad929a-db03-4bf4-9541-8f728fa12e43",
+		"ip_address": "91.207.175.104"
+	}`))
+	req.NoError(err)
+
+	requestRecorder := newRecordedRequest(detectionController, request)
+	req.Equal(http.StatusBadRequest, requestRecorder.Code)
+	req.Equal(`{"error":"async ingestion is disabled"}`, fmt.Sprint(requestRecorder.Body))
+}
+
+func newRecordedResultRequest(detectionController EventDetectionController,
+	eventUUID string) *httptest.ResponseRecorder {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/events/%s/result", eventUUID), nil)
+	if err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+
+	requestRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(detectionController.EventResultHandler)
+	handler.ServeHTTP(requestRecorder, mux.SetURLVars(request, map[string]string{"uuid": eventUUID}))
+
+	return requestRecorder
+}
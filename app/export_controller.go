@@ -0,0 +1,136 @@
+package app
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/app/services"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// rest controller for exports of events and alerts. the response is streamed, so an error once the export has
+// started ends the response early instead of changing its status
+type ExportController struct {
+	exporter *services.Exporter
+}
+
+type exportRequest struct {
+	query   models.ExportQuery
+	format  string
+	columns []string
+}
+
+var exportContentTypes = map[string]string{
+	services.ExportFormatNDJSON: "application/x-ndjson",
+	services.ExportFormatCSV:    "text/csv",
+}
+
+func (controller ExportController) EventsExportHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := parseExportRequest(r, services.EventExportColumns)
+	if err == nil && request.query.After != "" {
+		_, _, err = models.ParseEventCursor(request.query.After)
+	}
+
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	streamExport(w, r, request, func(out io.Writer) (int64, error) {
+		return controller.exporter.ExportEvents(out, request.query, request.format, request.columns)
+	})
+}
+
+func (controller ExportController) AlertsExportHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := parseExportRequest(r, services.AlertExportColumns)
+	if err == nil && request.query.After != "" {
+		_, err = models.ParseAlertCursor(request.query.After)
+	}
+
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	streamExport(w, r, request, func(out io.Writer) (int64, error) {
+		return controller.exporter.ExportAlerts(out, request.query, request.format, request.columns)
+	})
+}
+
+// reads format, columns, username, from, to, after and limit from the query string
+func parseExportRequest(r *http.Request, availableColumns []string) (*exportRequest, error) {
+	params := r.URL.Query()
+	request := &exportRequest{format: params.Get("format")}
+
+	if request.format == "" {
+		request.format = services.ExportFormatNDJSON
+	}
+
+	if !services.IsExportFormat(request.format) {
+		return nil, models.NewValidationError(request.format, "format")
+	}
+
+	columns, err := services.ParseExportColumns(params.Get("columns"), availableColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	request.columns = columns
+	request.query.Username = params.Get("username")
+	request.query.After = params.Get("after")
+
+	if value := params.Get("from"); value != "" {
+		if request.query.From, err = models.ParseTimestamp(value); err != nil {
+			return nil, err
+		}
+	}
+
+	if value := params.Get("to"); value != "" {
+		if request.query.To, err = models.ParseTimestamp(value); err != nil {
+			return nil, err
+		}
+	}
+
+	if value := params.Get("limit"); value != "" {
+		if request.query.Limit, err = strconv.Atoi(value); err != nil || request.query.Limit < 0 {
+			return nil, models.NewValidationError(value, "limit")
+		}
+	}
+
+	return request, nil
+}
+
+// writes the export to the response, gzip compressed when the client accepts it
+func streamExport(w http.ResponseWriter, r *http.Request, request *exportRequest,
+	export func(out io.Writer) (int64, error)) {
+	w.Header().Set("Content-Type", exportContentTypes[request.format])
+	w.Header().Set("Vary", "Accept-Encoding")
+
+	var out io.Writer = w
+
+	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+
+		gzipWriter := gzip.NewWriter(w)
+
+		defer func() {
+			if err := gzipWriter.Close(); err != nil {
+				log.Printf(support.Error, err)
+			}
+		}()
+
+		out = gzipWriter
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if written, err := export(out); err != nil {
+		log.Printf(support.Error, fmt.Sprintf("export ended after %d records: %v", written, err))
+	}
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportEventsHandler_Streams_Gzip_NDJSON(t *testing.T) {
+	testSetup := test.SetUpWithConfig(func(appConfig *config.AppConfig) {
+		appConfig.EventDb.Driver = config.MemoryDriver
+	})
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	serviceContext := NewServiceContext(testSetup.AppServerContext())
+	event, err := models.EventFromJSON(routeTestEvent)
+	req.NoError(err)
+
+	_, err = serviceContext.EventRepository().InsertEvents([]*models.Event{event})
+	req.NoError(err)
+
+	routes := Router{serviceContext: serviceContext}.InitRoutes()
+	request := httptest.NewRequest(http.MethodGet, "/api/export/events?username=bob&columns=event_uuid,cursor", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+
+	requestRecorder := httptest.NewRecorder()
+	routes.ServeHTTP(requestRecorder, request)
+	req.Equal(http.StatusOK, requestRecorder.Code)
+	req.Equal("gzip", requestRecorder.Header().Get("Content-Encoding"))
+	req.Equal("application/x-ndjson", requestRecorder.Header().Get("Content-Type"))
+
+	reader, err := gzip.NewReader(requestRecorder.Body)
+	req.NoError(err)
+
+	body, err := ioutil.ReadAll(reader)
+	req.NoError(err)
+	req.Equal(`{"event_uuid":"85ad929a-db03-4bf4-9541-8f728fa12e42",`+
+		`"cursor":"1514764800:85ad929a-db03-4bf4-9541-8f728fa12e42"}`+"\n", string(body))
+}
+
+func TestExportHandlers_Reject_Invalid_Parameters(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	routes := Router{serviceContext: NewServiceContext(testSetup.AppServerContext())}.InitRoutes()
+
+	for _, path := range []string{
+		"/api/export/events?format=parquet",
+		"/api/export/events?columns=password",
+		"/api/export/events?after=bad",
+		"/api/export/events?from=yesterday",
+		"/api/export/alerts?after=bad",
+		"/api/export/alerts?limit=-1",
+	} {
+		requestRecorder := serveRoute(routes, http.MethodGet, path, "", "")
+		req.Equal(http.StatusBadRequest, requestRecorder.Code, path)
+		req.True(strings.HasPrefix(requestRecorder.Header().Get("Content-Type"), "application/json"), path)
+	}
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+	"github.com/gorilla/mux"
+)
+
+// rest controller for the location profiles of users
+type LocationProfileController struct {
+	locationProfileRepository core.LocationProfileRepository
+}
+
+// the cells and countries of the user, the most frequent first. a user without events has none
+func (controller LocationProfileController) UserLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	userLocations, err := controller.locationProfileRepository.FindUserLocations(username)
+	if err != nil {
+		log.Printf(support.Error, err)
+		errorResponse(w, http.StatusInternalServerError, "Unable to find locations")
+
+		return
+	}
+
+	if userLocations == nil {
+		userLocations = []*models.UserLocation{}
+	}
+
+	responseJSON(w, http.StatusOK, map[string]interface{}{"username": username, "locations": userLocations})
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// buckets that have been full for this long are dropped, they are recreated full on the next request
+const idleBucketTTL = 10 * time.Minute
+
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// token bucket rate limiter keyed by client, the api key when the request is authenticated and the remote
+// address otherwise, or by the remote address alone
+type RateLimiter struct {
+	requestsPerSecond float64
+	burst             float64
+	buckets           map[string]*tokenBucket
+	lastSweep         time.Time
+	now               func() time.Time
+	mutex             sync.Mutex
+}
+
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		requestsPerSecond: requestsPerSecond,
+		burst:             float64(burst),
+		buckets:           make(map[string]*tokenBucket),
+		now:               time.Now,
+	}
+}
+
+// takes a token from the bucket of the client, when none is left it returns how long until one is available
+func (limiter *RateLimiter) Allow(client string) (bool, time.Duration) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	now := limiter.now()
+	limiter.sweep(now)
+
+	bucket, ok := limiter.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: limiter.burst, lastRefill: now}
+		limiter.buckets[client] = bucket
+	}
+
+	elapsed := now.Sub(bucket.lastRefill).Seconds()
+	bucket.tokens = math.Min(limiter.burst, bucket.tokens+elapsed*limiter.requestsPerSecond)
+	bucket.lastRefill = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	// a bucket that never refills, retry after the smallest interval the header can express
+	if limiter.requestsPerSecond <= 0 {
+		return false, time.Second
+	}
+
+	wait := (1 - bucket.tokens) / limiter.requestsPerSecond
+
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+func (limiter *RateLimiter) sweep(now time.Time) {
+	if now.Sub(limiter.lastSweep) < idleBucketTTL {
+		return
+	}
+
+	for client, bucket := range limiter.buckets {
+		if now.Sub(bucket.lastRefill) >= idleBucketTTL {
+			delete(limiter.buckets, client)
+		}
+	}
+
+	limiter.lastSweep = now
+}
+
+// wraps the handler so that clients exceeding their rate are rejected with 429 and a Retry-After header
+func (limiter *RateLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
+	return limiter.limit(requestClient, next)
+}
+
+// wraps the handler like Limit, keyed by the remote address alone so that it can run before the request is
+// authenticated and throttles clients sending bad keys too
+func (limiter *RateLimiter) LimitAddress(next http.Handler) http.Handler {
+	return limiter.limit(requestAddress, next.ServeHTTP)
+}
+
+func (limiter *RateLimiter) limit(client func(r *http.Request) string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed, wait := limiter.Allow(client(r))
+		if !allowed {
+			support.IncrementCounter(support.ThrottledRequests)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			errorResponse(w, http.StatusTooManyRequests, "rate limit exceeded")
+
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func requestClient(r *http.Request) string {
+	if apiKey := requestAPIKeyInfo(r); apiKey != nil {
+		return "key:" + apiKey.ID
+	}
+
+	return requestAddress(r)
+}
+
+func requestAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "addr:" + host
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/support"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	req := require.New(t)
+	now := time.Unix(1514764800, 0)
+	limiter := NewRateLimiter(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.Allow("bob")
+		req.True(allowed)
+	}
+
+	allowed, wait := limiter.Allow("bob")
+	req.False(allowed)
+	req.Equal(500*time.Millisecond, wait)
+
+	otherAllowed, _ := limiter.Allow("mary")
+	req.True(otherAllowed)
+
+	now = now.Add(500 * time.Millisecond)
+	allowed, _ = limiter.Allow("bob")
+	req.True(allowed)
+}
+
+func TestRateLimiter_Drops_Idle_Buckets(t *testing.T) {
+	req := require.New(t)
+	now := time.Unix(1514764800, 0)
+	limiter := NewRateLimiter(1, 1)
+	limiter.now = func() time.Time { return now }
+
+	allowed, _ := limiter.Allow("bob")
+	req.True(allowed)
+	req.Len(limiter.buckets, 1)
+
+	now = now.Add(idleBucketTTL)
+	allowed, _ = limiter.Allow("mary")
+	req.True(allowed)
+	req.Len(limiter.buckets, 1)
+}
+
+func TestRateLimiter_Limit(t *testing.T) {
+	req := require.New(t)
+	limiter := NewRateLimiter(0.5, 1)
+	handler := limiter.Limit(func(w http.ResponseWriter, r *http.Request) {
+		responseJSON(w, http.StatusOK, map[string]string{"result": "success"})
+	})
+	throttled := support.CounterValue(support.ThrottledRequests)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/events", nil)
+	request.RemoteAddr = "10.0.0.1:5000"
+
+	requestRecorder := httptest.NewRecorder()
+	handler(requestRecorder, request)
+	req.Equal(http.StatusOK, requestRecorder.Code)
+
+	requestRecorder = httptest.NewRecorder()
+	handler(requestRecorder, request)
+	req.Equal(http.StatusTooManyRequests, requestRecorder.Code)
+	req.Equal("2", requestRecorder.Header().Get("Retry-After"))
+	req.Equal(throttled+1, support.CounterValue(support.ThrottledRequests))
+
+	otherRequest := httptest.NewRequest(http.MethodPost, "/api/events", nil)
+	otherRequest.RemoteAddr = "10.0.0.2:5000"
+
+	requestRecorder = httptest.NewRecorder()
+	handler(requestRecorder, otherRequest)
+	req.Equal(http.StatusOK, requestRecorder.Code)
+}
+
+func TestRateLimiter_LimitAddress_Ignores_Key(t *testing.T) {
+	req := require.New(t)
+	limiter := NewRateLimiter(0.5, 1)
+	handler := limiter.LimitAddress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responseJSON(w, http.StatusOK, map[string]string{"result": "success"})
+	}))
+
+	serve := func(rawKey string) int {
+		request := httptest.NewRequest(http.MethodGet, "/api/metrics", nil)
+		request.RemoteAddr = "10.0.0.1:5000"
+		request.Header.Set(apiKeyHeader, rawKey)
+
+		requestRecorder := httptest.NewRecorder()
+		handler.ServeHTTP(requestRecorder, request)
+
+		return requestRecorder.Code
+	}
+
+	// a new key does not get the address a new bucket
+	req.Equal(http.StatusOK, serve("first.key"))
+	req.Equal(http.StatusTooManyRequests, serve("second.key"))
+}
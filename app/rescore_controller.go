@@ -0,0 +1,79 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/frankiennamdi/detection-api/app/services"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+	"github.com/gorilla/mux"
+)
+
+// rest controller for rescoring the stored history with candidate detection parameters
+type RescoreController struct {
+	rescoreJobs     *services.RescoreJobs
+	parameters      *services.LiveDetectionParameters
+	maxRequestBytes int64
+}
+
+// the candidate parameters override the ones the server runs with, the username limits the rescore to one user
+type rescoreRequest struct {
+	models.DetectionParametersOverride
+	Username string `json:"username"`
+}
+
+// starts a rescore in the background and responds with where its report can be fetched
+func (controller RescoreController) RescoreHandler(w http.ResponseWriter, r *http.Request) {
+	maxRequestBytes := controller.maxRequestBytes
+	if maxRequestBytes <= 0 {
+		maxRequestBytes = defaultMaxRequestBytes
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBytes)
+
+	var request rescoreRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		errorResponse(w, http.StatusBadRequest, "can pass request body")
+		return
+	}
+
+	parameters := controller.parameters.Get()
+	job, err := controller.rescoreJobs.Start(parameters,
+		parameters.WithOverride(request.DetectionParametersOverride), request.Username)
+
+	if err == services.ErrRescoreRunning {
+		errorResponse(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	if _, ok := err.(*models.ValidationError); ok {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		log.Printf(support.Error, err)
+		errorResponse(w, http.StatusInternalServerError, "Unable to start rescore")
+
+		return
+	}
+
+	responseJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job":        job,
+		"result_url": fmt.Sprintf("/api/rescore/%s", job.ID),
+	})
+}
+
+func (controller RescoreController) RescoreJobHandler(w http.ResponseWriter, r *http.Request) {
+	job := controller.rescoreJobs.Find(mux.Vars(r)["id"])
+	if job == nil {
+		errorResponse(w, http.StatusNotFound, "no rescore with this id")
+		return
+	}
+
+	responseJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+	"github.com/gorilla/mux"
+)
+
+// rest controller for the rolling risk of users. scores are decayed to the time of the request
+type RiskController struct {
+	userRiskRepository core.UserRiskRepository
+	riskConfig         config.RiskConfig
+}
+
+func (controller RiskController) UserRiskHandler(w http.ResponseWriter, r *http.Request) {
+	userRisk, err := controller.userRiskRepository.FindUserRisk(mux.Vars(r)["username"])
+	if err != nil {
+		log.Printf(support.Error, err)
+		errorResponse(w, http.StatusInternalServerError, "Unable to find risk")
+
+		return
+	}
+
+	if userRisk == nil {
+		errorResponse(w, http.StatusNotFound, "no risk for this user")
+		return
+	}
+
+	responseJSON(w, http.StatusOK, models.NewCurrentUserRisk(userRisk, time.Now().Unix(),
+		controller.riskConfig.HalfLife()))
+}
+
+// the users of the highest risk, leaderboardSize of them unless the limit query parameter asks for another number
+func (controller RiskController) LeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	limit := controller.riskConfig.LeaderboardSize
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > controller.riskConfig.MaxLeaderboardSize {
+			errorResponse(w, http.StatusBadRequest, models.NewValidationError(value, "limit").Error())
+			return
+		}
+
+		limit = parsed
+	}
+
+	userRisks, err := controller.userRiskRepository.FindTopUserRisks(limit)
+	if err != nil {
+		log.Printf(support.Error, err)
+		errorResponse(w, http.StatusInternalServerError, "Unable to find risk")
+
+		return
+	}
+
+	now := time.Now().Unix()
+	leaderboard := make([]*models.CurrentUserRisk, len(userRisks))
+
+	for i, userRisk := range userRisks {
+		leaderboard[i] = models.NewCurrentUserRisk(userRisk, now, controller.riskConfig.HalfLife())
+	}
+
+	responseJSON(w, http.StatusOK, map[string]interface{}{"users": leaderboard})
+}
@@ -1,8 +1,11 @@
 package app
 
 import (
+	"expvar"
 	"net/http"
 
+	"github.com/frankiennamdi/detection-api/app/services"
+	"github.com/frankiennamdi/detection-api/models"
 	"github.com/gorilla/mux"
 )
 
@@ -12,12 +15,76 @@ type Router struct {
 }
 
 func (router Router) setRoutes(routes *mux.Router) *mux.Router {
+	serverConfig := router.serviceContext.server.AppConfig().Server
 	detectionController := EventDetectionController{
 		detectionService: router.serviceContext.DetectionService(),
+		maxRequestBytes:  int64(serverConfig.MaxRequestBytes),
+	}
+
+	if asyncEventProcessor := router.serviceContext.AsyncEventProcessor(); asyncEventProcessor != nil {
+		detectionController.asyncService = asyncEventProcessor
+	}
+	backupController := BackupController{
+		eventDb:   router.serviceContext.server.EventDb(),
+		backupDir: router.serviceContext.server.AppConfig().Backup.Dir,
+	}
+	exportController := ExportController{
+		exporter: services.NewExporter(router.serviceContext.EventExportRepository(),
+			router.serviceContext.AlertRepository()),
+	}
+	rescoreController := RescoreController{
+		rescoreJobs:     router.serviceContext.RescoreJobs(),
+		parameters:      router.serviceContext.DetectionParameters(),
+		maxRequestBytes: int64(serverConfig.MaxRequestBytes),
+	}
+	simulationController := SimulationController{
+		simulator:       router.serviceContext.Simulator(),
+		parameters:      router.serviceContext.DetectionParameters(),
+		maxRequestBytes: int64(serverConfig.MaxRequestBytes),
+	}
+	riskController := RiskController{
+		userRiskRepository: router.serviceContext.UserRiskRepository(),
+		riskConfig:         router.serviceContext.server.AppConfig().Risk,
+	}
+	locationProfileController := LocationProfileController{
+		locationProfileRepository: router.serviceContext.LocationProfileRepository(),
+	}
+	authenticator := NewAuthenticator(router.serviceContext.APIKeyService(), serverConfig.AuthEnabled)
+	ingestionHandler := detectionController.EventDetectionHandler
+
+	// every route is limited by address before the key is checked, and ingestion by client once it is
+	if serverConfig.RateLimit.Enabled {
+		routes.Use(NewRateLimiter(serverConfig.RateLimit.AddressRequestsPerSecond,
+			serverConfig.RateLimit.AddressBurst).LimitAddress)
+		ingestionHandler = NewRateLimiter(serverConfig.RateLimit.RequestsPerSecond,
+			serverConfig.RateLimit.Burst).Limit(ingestionHandler)
 	}
 
 	routes.HandleFunc("/api/health-check", StatusHandler).Methods(http.MethodGet)
-	routes.HandleFunc("/api/events", detectionController.EventDetectionHandler).Methods(http.MethodPost)
+	routes.HandleFunc("/api/metrics", authenticator.Require(models.ScopeAdmin,
+		expvar.Handler().ServeHTTP)).Methods(http.MethodGet)
+	routes.HandleFunc("/api/admin/backup", authenticator.Require(models.ScopeAdmin,
+		backupController.BackupHandler)).Methods(http.MethodPost)
+	routes.HandleFunc("/api/rescore", authenticator.Require(models.ScopeAdmin,
+		rescoreController.RescoreHandler)).Methods(http.MethodPost)
+	routes.HandleFunc("/api/rescore/{id}", authenticator.Require(models.ScopeAdmin,
+		rescoreController.RescoreJobHandler)).Methods(http.MethodGet)
+	routes.HandleFunc("/api/simulate", authenticator.Require(models.ScopeAdmin,
+		simulationController.SimulationHandler)).Methods(http.MethodPost)
+	routes.HandleFunc("/api/export/events", authenticator.Require(models.ScopeEventsRead,
+		exportController.EventsExportHandler)).Methods(http.MethodGet)
+	routes.HandleFunc("/api/export/alerts", authenticator.Require(models.ScopeAlertsRead,
+		exportController.AlertsExportHandler)).Methods(http.MethodGet)
+	routes.HandleFunc("/api/users/{username}/risk", authenticator.Require(models.ScopeAlertsRead,
+		riskController.UserRiskHandler)).Methods(http.MethodGet)
+	routes.HandleFunc("/api/users/{username}/locations", authenticator.Require(models.ScopeAlertsRead,
+		locationProfileController.UserLocationsHandler)).Methods(http.MethodGet)
+	routes.HandleFunc("/api/risk/leaderboard", authenticator.Require(models.ScopeAlertsRead,
+		riskController.LeaderboardHandler)).Methods(http.MethodGet)
+	routes.HandleFunc("/api/events", authenticator.Require(models.ScopeEventsWrite,
+		ingestionHandler)).Methods(http.MethodPost)
+	routes.HandleFunc("/api/events/{uuid}/result", authenticator.Require(models.ScopeEventsWrite,
+		detectionController.EventResultHandler)).Methods(http.MethodGet)
 
 	return routes
 }
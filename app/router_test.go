@@ -0,0 +1,222 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDetectionService struct{}
+
+func (mockService MockDetectionService) ProcessEvent(
+	currEvent *models.Event) (*models.SuspiciousTravelResult, error) {
+	return &models.SuspiciousTravelResult{}, nil
+}
+
+const routeTestEvent = `{
+	"username": "bob",
+	"unix_timestamp": 1514764800,
+	"event_uuid": "85ad929a-db03-4bf4-9541-8f728fa12e42",
+	"ip_address": "206.81.252.6"
+}`
+
+type routeScopeTestCase struct {
+	method         string
+	path           string
+	body           string
+	requiredScope  string
+	expectedStatus int
+}
+
+var routeScopeTestCases = []routeScopeTestCase{
+	{method: http.MethodGet, path: "/api/health-check", requiredScope: ""},
+	{method: http.MethodGet, path: "/api/metrics", requiredScope: models.ScopeAdmin},
+	{method: http.MethodPost, path: "/api/admin/backup", requiredScope: models.ScopeAdmin,
+		expectedStatus: http.StatusCreated},
+	{method: http.MethodPost, path: "/api/rescore", requiredScope: models.ScopeAdmin,
+		body: `{"suspiciousSpeed": 100}`, expectedStatus: http.StatusAccepted},
+	{method: http.MethodGet, path: "/api/rescore/unknown", requiredScope: models.ScopeAdmin,
+		expectedStatus: http.StatusNotFound},
+	{method: http.MethodPost, path: "/api/simulate", requiredScope: models.ScopeAdmin,
+		body: `{"events": [{"unix_timestamp": 1514764800, "ip_address": "206.81.252.6"}]}`},
+	{method: http.MethodGet, path: "/api/export/events", requiredScope: models.ScopeEventsRead},
+	{method: http.MethodGet, path: "/api/export/alerts?format=csv", requiredScope: models.ScopeAlertsRead},
+	{method: http.MethodGet, path: "/api/users/bob/risk", requiredScope: models.ScopeAlertsRead,
+		expectedStatus: http.StatusNotFound},
+	{method: http.MethodGet, path: "/api/users/bob/locations", requiredScope: models.ScopeAlertsRead},
+	{method: http.MethodGet, path: "/api/risk/leaderboard?limit=5", requiredScope: models.ScopeAlertsRead},
+	{method: http.MethodPost, path: "/api/events", requiredScope: models.ScopeEventsWrite, body: routeTestEvent},
+	{method: http.MethodPost, path: "/api/events?async=true", requiredScope: models.ScopeEventsWrite,
+		body: routeTestEvent, expectedStatus: http.StatusAccepted},
+	{method: http.MethodGet, path: "/api/events/85ad929a-db03-4bf4-9541-8f728fa12e42/result",
+		requiredScope: models.ScopeEventsWrite},
+}
+
+func TestRoutes_Require_Scope(t *testing.T) {
+	testSetup := test.SetUpWithConfig(func(appConfig *config.AppConfig) {
+		appConfig.Server.AuthEnabled = true
+		appConfig.Async.Enabled = true
+	})
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	serviceContext := NewServiceContext(testSetup.AppServerContext())
+	serviceContext.detectionService = MockDetectionService{}
+	routes := Router{serviceContext: serviceContext}.InitRoutes()
+
+	keys := map[string]string{}
+
+	for _, scope := range []string{models.ScopeEventsWrite, models.ScopeEventsRead, models.ScopeAlertsRead,
+		models.ScopeAdmin} {
+		_, rawKey, err := serviceContext.APIKeyService().CreateAPIKey(scope+" client", []string{scope})
+		req.NoError(err)
+
+		keys[scope] = rawKey
+	}
+
+	for _, route := range routeScopeTestCases {
+		if route.requiredScope == "" {
+			req.Equal(http.StatusOK, serveRoute(routes, route.method, route.path, route.body, "").Code,
+				route.path)
+
+			continue
+		}
+
+		req.Equal(http.StatusUnauthorized, serveRoute(routes, route.method, route.path, route.body, "").Code,
+			route.path)
+		req.Equal(http.StatusUnauthorized,
+			serveRoute(routes, route.method, route.path, route.body, "bad.key").Code, route.path)
+
+		for scope, rawKey := range keys {
+			code := serveRoute(routes, route.method, route.path, route.body, rawKey).Code
+
+			if scope == route.requiredScope || scope == models.ScopeAdmin {
+				req.Equal(route.expectedStatusOrOK(), code, "%s with scope %s", route.path, scope)
+			} else {
+				req.Equal(http.StatusForbidden, code, "%s with scope %s", route.path, scope)
+			}
+		}
+	}
+}
+
+func TestRoutes_Accept_Bearer_Token(t *testing.T) {
+	testSetup := test.SetUpWithConfig(func(appConfig *config.AppConfig) {
+		appConfig.Server.AuthEnabled = true
+	})
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	serviceContext := NewServiceContext(testSetup.AppServerContext())
+	serviceContext.detectionService = MockDetectionService{}
+	routes := Router{serviceContext: serviceContext}.InitRoutes()
+
+	_, rawKey, err := serviceContext.APIKeyService().CreateAPIKey("collector", []string{models.ScopeEventsWrite})
+	req.NoError(err)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/events", strings.NewReader(routeTestEvent))
+	request.Header.Set("Authorization", "Bearer "+rawKey)
+
+	requestRecorder := httptest.NewRecorder()
+	routes.ServeHTTP(requestRecorder, request)
+	req.Equal(http.StatusOK, requestRecorder.Code)
+}
+
+func TestRoutes_Limit_Bad_Key_Flood_By_Address(t *testing.T) {
+	testSetup := test.SetUpWithConfig(func(appConfig *config.AppConfig) {
+		appConfig.Server.AuthEnabled = true
+		appConfig.Server.RateLimit = config.RateLimitConfig{Enabled: true, RequestsPerSecond: 0.01, Burst: 100,
+			AddressRequestsPerSecond: 0.01, AddressBurst: 5}
+	})
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	serviceContext := NewServiceContext(testSetup.AppServerContext())
+	serviceContext.detectionService = MockDetectionService{}
+	routes := Router{serviceContext: serviceContext}.InitRoutes()
+
+	_, rawKey, err := serviceContext.APIKeyService().CreateAPIKey("collector", []string{models.ScopeAdmin})
+	req.NoError(err)
+
+	// the bad keys are refused until the address is over its rate, on any route
+	for i := 0; i < 5; i++ {
+		req.Equal(http.StatusUnauthorized, serveRoute(routes, http.MethodGet, "/api/metrics", "", "bad.key").Code)
+	}
+
+	req.Equal(http.StatusTooManyRequests,
+		serveRoute(routes, http.MethodPost, "/api/events", routeTestEvent, "bad.key").Code)
+	req.Equal(http.StatusTooManyRequests, serveRoute(routes, http.MethodGet, "/api/users/bob/risk", "", rawKey).Code)
+
+	// another address has a bucket of its own
+	request := httptest.NewRequest(http.MethodGet, "/api/metrics", nil)
+	request.RemoteAddr = "10.0.0.2:5000"
+	request.Header.Set(apiKeyHeader, rawKey)
+
+	requestRecorder := httptest.NewRecorder()
+	routes.ServeHTTP(requestRecorder, request)
+	req.Equal(http.StatusOK, requestRecorder.Code)
+}
+
+func TestRoutes_Limit_Ingestion_Per_Key_Behind_One_Address(t *testing.T) {
+	testSetup := test.SetUpWithConfig(func(appConfig *config.AppConfig) {
+		appConfig.Server.AuthEnabled = true
+		appConfig.Server.RateLimit = config.RateLimitConfig{Enabled: true, RequestsPerSecond: 0.01, Burst: 2,
+			AddressRequestsPerSecond: 0.01, AddressBurst: 100}
+	})
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	serviceContext := NewServiceContext(testSetup.AppServerContext())
+	serviceContext.detectionService = MockDetectionService{}
+	routes := Router{serviceContext: serviceContext}.InitRoutes()
+
+	// two clients behind the same address each get the rate of their key
+	for _, name := range []string{"collector", "importer"} {
+		_, rawKey, err := serviceContext.APIKeyService().CreateAPIKey(name, []string{models.ScopeEventsWrite})
+		req.NoError(err)
+
+		for i := 0; i < 2; i++ {
+			req.Equal(http.StatusOK, serveRoute(routes, http.MethodPost, "/api/events", routeTestEvent, rawKey).Code)
+		}
+
+		req.Equal(http.StatusTooManyRequests,
+			serveRoute(routes, http.MethodPost, "/api/events", routeTestEvent, rawKey).Code)
+	}
+}
+
+func TestRoutes_When_Auth_Is_Disabled(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	serviceContext := NewServiceContext(testSetup.AppServerContext())
+	serviceContext.detectionService = MockDetectionService{}
+	routes := Router{serviceContext: serviceContext}.InitRoutes()
+
+	requestRecorder := serveRoute(routes, http.MethodPost, "/api/events", routeTestEvent, "")
+	require.New(t).Equal(http.StatusOK, requestRecorder.Code)
+}
+
+func (route routeScopeTestCase) expectedStatusOrOK() int {
+	if route.expectedStatus == 0 {
+		return http.StatusOK
+	}
+
+	return route.expectedStatus
+}
+
+func serveRoute(routes http.Handler, method, path, body, rawKey string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if rawKey != "" {
+		request.Header.Set(apiKeyHeader, rawKey)
+	}
+
+	requestRecorder := httptest.NewRecorder()
+	routes.ServeHTTP(requestRecorder, request)
+
+	return requestRecorder
+}
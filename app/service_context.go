@@ -3,33 +3,139 @@ package app
 import (
 	"fmt"
 	"github.com/frankiennamdi/detection-api/app/services"
+	"github.com/frankiennamdi/detection-api/config"
 	"github.com/frankiennamdi/detection-api/core"
 	"github.com/frankiennamdi/detection-api/repository"
 	"github.com/frankiennamdi/detection-api/support"
 	"log"
+	"net"
 	"net/http"
+	"syscall"
 )
 
 // provides a context for the services of the server. It initializes all the services and
 // provides a function for initializing the routes and listening for connections
 type ServiceContext struct {
-	detectionService core.DetectionService
-	eventRepository  core.EventRepository
-	server *core.ServerContext
+	detectionService    core.DetectionService
+	detectionParameters *services.LiveDetectionParameters
+	calculatorService   core.CalculatorService
+	eventRepository     eventStore
+	alertRepository     core.AlertRepository
+	userRiskRepository  core.UserRiskRepository
+	locationProfiles    core.LocationProfileRepository
+	ipGeoInfoRepository core.IPGeoInfoRepository
+	apiKeyService       core.APIKeyService
+	asyncEventProcessor *services.AsyncEventProcessor
+	retentionJanitor    *services.RetentionJanitor
+	rescorer            *services.Rescorer
+	rescoreJobs         *services.RescoreJobs
+	simulator           *services.Simulator
+	server              *core.ServerContext
+}
+
+// every event repository also enforces retention and exports events
+type eventStore interface {
+	core.EventRepository
+	core.EventRetentionRepository
+	core.EventExportRepository
 }
 
 func NewServiceContext(ctx *core.ServerContext) *ServiceContext {
-	eventRepository := repository.NewSQLLiteEventsRepository(ctx.EventDb())
-	detectionService := services.NewDetectionService(eventRepository,
-		repository.NewMaxMindIPGeoInfoRepository(ctx.GeoIPDb()),
-		services.DefaultCalculatorService{},
-		ctx.AppConfig().SuspiciousSpeed)
+	eventRepository := newEventRepository(ctx)
+	alertRepository := repository.NewSQLLiteAlertRepository(ctx.EventDb())
+	ipGeoInfoRepository := repository.NewMaxMindIPGeoInfoRepository(ctx.GeoIPDb())
+	userRiskRepository := repository.NewSQLLiteUserRiskRepository(ctx.EventDb())
+	locationProfiles := repository.NewSQLLiteLocationProfileRepository(ctx.EventDb())
+	detectionParameters := services.NewLiveDetectionParameters(services.NewDetectionParameters(ctx.AppConfig()))
+	calculatorService := newCalculatorService(ctx)
+
+	var detectionService core.DetectionService = services.NewAlertingDetectionService(
+		services.NewLiveDetectionService(eventRepository,
+			ipGeoInfoRepository,
+			calculatorService,
+			detectionParameters), alertRepository)
+	if windowConfig := ctx.AppConfig().Window; windowConfig.Enabled {
+		detectionService = services.NewWindowDetectionService(detectionService, eventRepository,
+			ipGeoInfoRepository, calculatorService, detectionParameters, windowConfig)
+	}
+
+	if sessionConfig := ctx.AppConfig().Session; sessionConfig.Enabled {
+		detectionService = services.NewSessionDetectionService(detectionService, eventRepository,
+			ipGeoInfoRepository, calculatorService, sessionConfig)
+	}
+
+	if noveltyConfig := ctx.AppConfig().Novelty; noveltyConfig.Enabled {
+		detectionService = services.NewNoveltyDetectionService(detectionService, locationProfiles, noveltyConfig)
+	}
+
+	if riskConfig := ctx.AppConfig().Risk; riskConfig.Enabled {
+		detectionService = services.NewRiskScoringDetectionService(detectionService, userRiskRepository,
+			calculatorService, detectionParameters, riskConfig.HalfLife())
+	}
+
+	// the whole chain runs for one event of a user at a time
+	detectionService = services.NewUserSerializedDetectionService(detectionService)
+
+	apiKeyService := services.NewAPIKeyService(repository.NewSQLLiteAPIKeyRepository(ctx.EventDb()))
+
+	var asyncEventProcessor *services.AsyncEventProcessor
+	if ctx.AppConfig().Async.Enabled {
+		asyncEventProcessor = services.NewAsyncEventProcessor(
+			repository.NewSQLLiteEventQueueRepository(ctx.EventDb()), detectionService, ctx.AppConfig().Async)
+	}
+
+	var retentionJanitor *services.RetentionJanitor
+	if ctx.AppConfig().Retention.Enabled {
+		janitor, err := services.NewRetentionJanitor(eventRepository,
+			repository.NewSQLLiteDerivedRetentionRepository(ctx.EventDb()), ctx.AppConfig().Retention)
+		if err != nil {
+			log.Panicf(support.Fatal, err)
+		}
+
+		retentionJanitor = janitor
+	}
+
+	rescorer := services.NewRescorer(eventRepository, ipGeoInfoRepository, calculatorService)
 
 	return &ServiceContext{
-		detectionService: detectionService,
-		eventRepository:  eventRepository,
-		server: ctx,
+		detectionService:    detectionService,
+		detectionParameters: detectionParameters,
+		calculatorService:   calculatorService,
+		eventRepository:     eventRepository,
+		alertRepository:     alertRepository,
+		userRiskRepository:  userRiskRepository,
+		locationProfiles:    locationProfiles,
+		ipGeoInfoRepository: ipGeoInfoRepository,
+		apiKeyService:       apiKeyService,
+		asyncEventProcessor: asyncEventProcessor,
+		retentionJanitor:    retentionJanitor,
+		rescorer:            rescorer,
+		rescoreJobs:         services.NewRescoreJobs(rescorer),
+		simulator:           services.NewSimulator(ipGeoInfoRepository, calculatorService),
+		server:              ctx,
+	}
+}
+
+// selects the event store configured by eventDb.driver
+func newEventRepository(ctx *core.ServerContext) eventStore {
+	if ctx.AppConfig().EventDb.IsPostgres() {
+		return repository.NewPostgresEventsRepository(ctx.PostgresDb())
+	}
+
+	if ctx.AppConfig().EventDb.IsMemory() {
+		return repository.NewMemoryEventsRepository()
+	}
+
+	return repository.NewSQLLiteEventsRepository(ctx.EventDb())
+}
+
+// selects the distance calculator configured by calculator
+func newCalculatorService(ctx *core.ServerContext) core.CalculatorService {
+	if ctx.AppConfig().Calculator == config.VincentyCalculator {
+		return services.VincentyCalculatorService{}
 	}
+
+	return services.DefaultCalculatorService{}
 }
 
 func (serviceContext *ServiceContext) DetectionService() core.DetectionService {
@@ -40,16 +146,115 @@ func (serviceContext *ServiceContext) EventRepository() core.EventRepository {
 	return serviceContext.eventRepository
 }
 
+func (serviceContext *ServiceContext) EventExportRepository() core.EventExportRepository {
+	return serviceContext.eventRepository
+}
+
+func (serviceContext *ServiceContext) AlertRepository() core.AlertRepository {
+	return serviceContext.alertRepository
+}
+
+func (serviceContext *ServiceContext) UserRiskRepository() core.UserRiskRepository {
+	return serviceContext.userRiskRepository
+}
+
+func (serviceContext *ServiceContext) LocationProfileRepository() core.LocationProfileRepository {
+	return serviceContext.locationProfiles
+}
+
+func (serviceContext *ServiceContext) LocationProfiler() *services.LocationProfiler {
+	return services.NewLocationProfiler(serviceContext.eventRepository, serviceContext.ipGeoInfoRepository,
+		serviceContext.locationProfiles, serviceContext.server.AppConfig().Novelty)
+}
+
+func (serviceContext *ServiceContext) IPGeoInfoRepository() core.IPGeoInfoRepository {
+	return serviceContext.ipGeoInfoRepository
+}
+
+func (serviceContext *ServiceContext) CalculatorService() core.CalculatorService {
+	return serviceContext.calculatorService
+}
+
+// the parameters the detection service runs with, replaced when the config is reloaded
+func (serviceContext *ServiceContext) DetectionParameters() *services.LiveDetectionParameters {
+	return serviceContext.detectionParameters
+}
+
+func (serviceContext *ServiceContext) Rescorer() *services.Rescorer {
+	return serviceContext.rescorer
+}
+
+func (serviceContext *ServiceContext) RescoreJobs() *services.RescoreJobs {
+	return serviceContext.rescoreJobs
+}
+
+func (serviceContext *ServiceContext) Simulator() *services.Simulator {
+	return serviceContext.simulator
+}
+
+func (serviceContext *ServiceContext) APIKeyService() core.APIKeyService {
+	return serviceContext.apiKeyService
+}
+
+// nil when async ingestion is disabled
+func (serviceContext *ServiceContext) AsyncEventProcessor() *services.AsyncEventProcessor {
+	return serviceContext.asyncEventProcessor
+}
+
 func (serviceContext *ServiceContext) Listen() {
-	router := Router{serviceContext: serviceContext}
-	routes := router.InitRoutes()
+	serverConfig := serviceContext.server.AppConfig().Server
 
-	log.Printf(support.Info, fmt.Sprintf("service starting on Port : %d ...",
-		serviceContext.server.AppConfig().Server.Port))
+	if serviceContext.asyncEventProcessor != nil {
+		if err := serviceContext.asyncEventProcessor.Start(); err != nil {
+			log.Panicf(support.Fatal, err)
+		}
+	}
 
-	if err := http.ListenAndServe(fmt.Sprintf(":%d",
-		serviceContext.server.AppConfig().Server.Port), routes); err != nil {
+	if serviceContext.retentionJanitor != nil {
+		serviceContext.retentionJanitor.Start()
+	}
+
+	StartConfigReloader(serviceContext.server.AppConfig().Reload, config.File(), serviceContext.detectionParameters)
+
+	httpServer, err := serviceContext.NewHTTPServer()
+	if err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", serverConfig.Port))
+	if err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+
+	log.Printf(support.Info, fmt.Sprintf("service starting on Port : %d, tls : %t ...",
+		serverConfig.Port, serverConfig.TLS.Enabled))
+
+	if serverConfig.TLS.Enabled {
+		err = httpServer.ServeTLS(listener, "", "")
+	} else {
+		err = httpServer.Serve(listener)
+	}
+
+	if err != nil {
 		log.Panicf(support.Fatal, err)
 	}
 }
 
+// creates the server with the routes, and when tls is enabled with certificates that are reloaded on SIGHUP
+func (serviceContext *ServiceContext) NewHTTPServer() (*http.Server, error) {
+	router := Router{serviceContext: serviceContext}
+	httpServer := &http.Server{Handler: router.InitRoutes()}
+	tlsConfig := serviceContext.server.AppConfig().Server.TLS
+
+	if tlsConfig.Enabled {
+		reloader, err := NewCertificateReloader(tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		reloader.WatchSignals(syscall.SIGHUP)
+		httpServer.TLSConfig = reloader.TLSConfig()
+	}
+
+	return httpServer, nil
+}
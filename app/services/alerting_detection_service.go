@@ -0,0 +1,38 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// detection service that records an alert for every suspicious travel found by the detection service it wraps
+type AlertingDetectionService struct {
+	detectionService core.DetectionService
+	alertRepository  core.AlertRepository
+}
+
+func NewAlertingDetectionService(detectionService core.DetectionService,
+	alertRepository core.AlertRepository) *AlertingDetectionService {
+	return &AlertingDetectionService{detectionService: detectionService, alertRepository: alertRepository}
+}
+
+// a failure to record the alerts is logged, the result of the detection is still returned
+func (service AlertingDetectionService) ProcessEvent(
+	currEvent *models.Event) (*models.SuspiciousTravelResult, error) {
+	result, err := service.detectionService.ProcessEvent(currEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	if alerts := models.NewAlerts(currEvent, result, time.Now().Unix()); len(alerts) > 0 {
+		if _, err := service.alertRepository.InsertAlerts(alerts); err != nil {
+			log.Printf(support.Error, err)
+		}
+	}
+
+	return result, nil
+}
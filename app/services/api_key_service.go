@@ -0,0 +1,122 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+const (
+	apiKeyIDBytes     = 8
+	apiKeySecretBytes = 32
+	apiKeySeparator   = "."
+)
+
+// service for issuing and authenticating api keys. a raw key has the form <id>.<secret>, the id is used to
+// find the stored key and the secret is compared in constant time against the stored hash
+type APIKeyService struct {
+	apiKeyRepository core.APIKeyRepository
+	// compared against when the key id is unknown so that unknown and wrong keys take the same time
+	unknownKeyHash string
+}
+
+func NewAPIKeyService(apiKeyRepository core.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepository: apiKeyRepository,
+		unknownKeyHash:   hashSecret(""),
+	}
+}
+
+func (service APIKeyService) CreateAPIKey(name string, scopes []string) (*models.APIKey, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", support.NewIllegalArgumentError("name cannot be empty")
+	}
+
+	if len(scopes) == 0 {
+		return nil, "", support.NewIllegalArgumentError("at least one scope is required")
+	}
+
+	for _, scope := range scopes {
+		if !models.IsValidScope(scope) {
+			return nil, "", support.NewIllegalArgumentError(fmt.Sprintf("unknown scope: %s", scope))
+		}
+	}
+
+	id, err := randomHex(apiKeyIDBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := randomHex(apiKeySecretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiKey := &models.APIKey{
+		ID:        id,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: time.Now().Unix(),
+	}
+
+	if err := service.apiKeyRepository.InsertAPIKey(apiKey, hashSecret(secret)); err != nil {
+		return nil, "", err
+	}
+
+	return apiKey, id + apiKeySeparator + secret, nil
+}
+
+// returns nil without an error when the key is malformed, unknown or revoked
+func (service APIKeyService) Authenticate(rawKey string) (*models.APIKey, error) {
+	parts := strings.SplitN(rawKey, apiKeySeparator, 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, nil
+	}
+
+	apiKey, secretHash, err := service.apiKeyRepository.FindAPIKey(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey == nil {
+		secretHash = service.unknownKeyHash
+	}
+
+	matched := subtle.ConstantTimeCompare([]byte(hashSecret(parts[1])), []byte(secretHash)) == 1
+
+	if apiKey == nil || !matched || apiKey.Revoked {
+		return nil, nil
+	}
+
+	return apiKey, nil
+}
+
+func (service APIKeyService) ListAPIKeys() ([]*models.APIKey, error) {
+	return service.apiKeyRepository.FindAPIKeys()
+}
+
+func (service APIKeyService) RevokeAPIKey(id string) (bool, error) {
+	return service.apiKeyRepository.RevokeAPIKey(id)
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	value := make([]byte, size)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(value), nil
+}
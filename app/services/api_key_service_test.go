@@ -0,0 +1,66 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/repository"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAndAuthenticateAPIKey(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	apiKeyService := NewAPIKeyService(repository.NewSQLLiteAPIKeyRepository(testSetup.AppServerContext().EventDb()))
+
+	apiKey, rawKey, err := apiKeyService.CreateAPIKey("collector", []string{models.ScopeEventsWrite})
+	req.NoError(err)
+	req.True(strings.HasPrefix(rawKey, apiKey.ID+"."))
+
+	authenticated, err := apiKeyService.Authenticate(rawKey)
+	req.NoError(err)
+	req.Equal(apiKey, authenticated)
+}
+
+func TestAuthenticate_When_Key_Is_Wrong_Or_Revoked(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	apiKeyService := NewAPIKeyService(repository.NewSQLLiteAPIKeyRepository(testSetup.AppServerContext().EventDb()))
+
+	apiKey, rawKey, err := apiKeyService.CreateAPIKey("collector", []string{models.ScopeEventsWrite})
+	req.NoError(err)
+
+	for _, badKey := range []string{"", ".", apiKey.ID, apiKey.ID + ".wrong", "unknown." + strings.Split(rawKey, ".")[1]} {
+		authenticated, err := apiKeyService.Authenticate(badKey)
+		req.NoError(err)
+		req.Nil(authenticated, badKey)
+	}
+
+	revoked, err := apiKeyService.RevokeAPIKey(apiKey.ID)
+	req.NoError(err)
+	req.True(revoked)
+
+	authenticated, err := apiKeyService.Authenticate(rawKey)
+	req.NoError(err)
+	req.Nil(authenticated)
+}
+
+func TestCreateAPIKey_When_Arguments_Are_Invalid(t *testing.T) {
+	apiKeyService := NewAPIKeyService(nil)
+	req := require.New(t)
+
+	_, _, err1 := apiKeyService.CreateAPIKey("", []string{models.ScopeEventsWrite})
+	req.Error(err1)
+
+	_, _, err2 := apiKeyService.CreateAPIKey("collector", nil)
+	req.Error(err2)
+
+	_, _, err3 := apiKeyService.CreateAPIKey("collector", []string{"root"})
+	req.Error(err3)
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// finished events are pruned at most this often
+const queuePruneInterval = 10 * time.Minute
+
+// drains the durable event queue through the detection service with a pool of workers. events of a user are
+// always handled by the same worker, so they are processed in the order they were queued. a failed event is tried
+// again by its worker, before the later events of its user, until it failed the configured attempts, and finished
+// events are deleted once their result expired
+type AsyncEventProcessor struct {
+	queueRepository  core.EventQueueRepository
+	detectionService core.DetectionService
+	batchSize        int
+	pollInterval     time.Duration
+	maxAttempts      int
+	resultTTL        time.Duration
+	lastPrune        time.Time
+	workers          []chan *models.QueuedEvent
+	wake             chan struct{}
+	stop             chan struct{}
+	waitGroup        sync.WaitGroup
+}
+
+func NewAsyncEventProcessor(queueRepository core.EventQueueRepository, detectionService core.DetectionService,
+	asyncConfig config.AsyncConfig) *AsyncEventProcessor {
+	workers, batchSize, pollInterval := 4, 100, 500*time.Millisecond
+	maxAttempts, resultTTL := 3, 24*time.Hour
+
+	if asyncConfig.Workers > 0 {
+		workers = asyncConfig.Workers
+	}
+
+	if asyncConfig.BatchSize > 0 {
+		batchSize = asyncConfig.BatchSize
+	}
+
+	if asyncConfig.PollIntervalMillis > 0 {
+		pollInterval = time.Duration(asyncConfig.PollIntervalMillis) * time.Millisecond
+	}
+
+	if asyncConfig.MaxAttempts > 0 {
+		maxAttempts = asyncConfig.MaxAttempts
+	}
+
+	if asyncConfig.ResultTTLHours > 0 {
+		resultTTL = time.Duration(asyncConfig.ResultTTLHours) * time.Hour
+	}
+
+	processor := &AsyncEventProcessor{
+		queueRepository:  queueRepository,
+		detectionService: detectionService,
+		batchSize:        batchSize,
+		pollInterval:     pollInterval,
+		maxAttempts:      maxAttempts,
+		resultTTL:        resultTTL,
+		workers:          make([]chan *models.QueuedEvent, workers),
+		wake:             make(chan struct{}, 1),
+		stop:             make(chan struct{}),
+	}
+
+	for i := range processor.workers {
+		processor.workers[i] = make(chan *models.QueuedEvent, batchSize)
+	}
+
+	return processor
+}
+
+func (processor *AsyncEventProcessor) Submit(event *models.Event) error {
+	if event == nil {
+		return support.NewIllegalArgumentError("event cannot be nil")
+	}
+
+	if _, err := processor.queueRepository.Enqueue(event); err != nil {
+		return err
+	}
+
+	select {
+	case processor.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// returns nil when no event with the uuid was submitted
+func (processor *AsyncEventProcessor) FindResult(uuid string) (*models.QueuedEvent, error) {
+	return processor.queueRepository.FindQueuedEvent(uuid)
+}
+
+// requeues events left in processing by a previous run and starts the workers
+func (processor *AsyncEventProcessor) Start() error {
+	reset, err := processor.queueRepository.ResetProcessing()
+	if err != nil {
+		return err
+	}
+
+	if reset > 0 {
+		log.Printf(support.Info, fmt.Sprintf("requeued %d events interrupted by the previous run", reset))
+	}
+
+	for _, worker := range processor.workers {
+		processor.waitGroup.Add(1)
+
+		go processor.work(worker)
+	}
+
+	processor.waitGroup.Add(1)
+
+	go processor.poll()
+
+	return nil
+}
+
+// stops claiming events and waits for the claimed ones to be processed
+func (processor *AsyncEventProcessor) Stop() {
+	close(processor.stop)
+	processor.waitGroup.Wait()
+}
+
+func (processor *AsyncEventProcessor) poll() {
+	defer processor.waitGroup.Done()
+
+	defer func() {
+		for _, worker := range processor.workers {
+			close(worker)
+		}
+	}()
+
+	ticker := time.NewTicker(processor.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		processor.prune()
+
+		claimed, err := processor.queueRepository.ClaimPending(processor.batchSize)
+		if err != nil {
+			log.Printf(support.Error, err)
+		}
+
+		for _, queuedEvent := range claimed {
+			processor.workers[processor.workerIndex(queuedEvent)] <- queuedEvent
+		}
+
+		// a full batch means more events are likely waiting
+		if len(claimed) == processor.batchSize {
+			continue
+		}
+
+		select {
+		case <-processor.stop:
+			return
+		case <-processor.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deletes the finished events whose result expired, once every prune interval
+func (processor *AsyncEventProcessor) prune() {
+	now := time.Now()
+	if now.Sub(processor.lastPrune) < queuePruneInterval {
+		return
+	}
+
+	processor.lastPrune = now
+
+	pruned, err := processor.queueRepository.PruneFinished(now.Add(-processor.resultTTL).Unix())
+	if err != nil {
+		log.Printf(support.Error, err)
+		return
+	}
+
+	if pruned > 0 {
+		log.Printf(support.Info, fmt.Sprintf("pruned %d finished events from the queue", pruned))
+	}
+}
+
+func (processor *AsyncEventProcessor) work(queuedEvents <-chan *models.QueuedEvent) {
+	defer processor.waitGroup.Done()
+
+	for queuedEvent := range queuedEvents {
+		result, attempts, processErr := processor.process(queuedEvent)
+		if processErr != nil {
+			log.Printf(support.Error, processErr)
+		}
+
+		if err := processor.queueRepository.Complete(queuedEvent.UUID, result, processErr, attempts); err != nil {
+			log.Printf(support.Error, err)
+		}
+	}
+}
+
+// processes the event until it succeeds or failed the max attempts, waiting longer after each failure. returns
+// the result and the attempts made
+func (processor *AsyncEventProcessor) process(
+	queuedEvent *models.QueuedEvent) (*models.SuspiciousTravelResult, int, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := processor.detectionService.ProcessEvent(queuedEvent.Event)
+		if err == nil || attempt >= processor.maxAttempts {
+			return result, attempt, err
+		}
+
+		log.Printf(support.Error, fmt.Sprintf("attempt %d at event %s failed, trying again: %v", attempt,
+			queuedEvent.UUID, err))
+		time.Sleep(time.Duration(attempt) * processor.pollInterval)
+	}
+}
+
+func (processor *AsyncEventProcessor) workerIndex(queuedEvent *models.QueuedEvent) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(queuedEvent.Event.ToEventInfo().Username))
+
+	return int(hash.Sum32() % uint32(len(processor.workers)))
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/repository"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type RecordingDetectionService struct {
+	processed map[string][]int64
+	mutex     sync.Mutex
+}
+
+func (recordingService *RecordingDetectionService) ProcessEvent(
+	currEvent *models.Event) (*models.SuspiciousTravelResult, error) {
+	recordingService.mutex.Lock()
+	defer recordingService.mutex.Unlock()
+
+	eventInfo := currEvent.ToEventInfo()
+	if eventInfo.IP == "0.0.0.0" {
+		return nil, fmt.Errorf("cannot find geo information for event: %+v", eventInfo)
+	}
+
+	recordingService.processed[eventInfo.Username] = append(recordingService.processed[eventInfo.Username],
+		eventInfo.Timestamp)
+
+	return &models.SuspiciousTravelResult{CurrentGeo: &models.GeoPoint{Latitude: 10, Longitude: 10}}, nil
+}
+
+func TestAsyncEventProcessor_Processes_User_Events_In_Order(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	queueRepository := repository.NewSQLLiteEventQueueRepository(testSetup.AppServerContext().EventDb())
+	recordingService := &RecordingDetectionService{processed: make(map[string][]int64)}
+	processor := NewAsyncEventProcessor(queueRepository, recordingService, config.AsyncConfig{
+		Workers:            3,
+		BatchSize:          7,
+		PollIntervalMillis: 10,
+	})
+
+	users := []string{"bob", "mary", "john", "kevin"}
+	expected := make(map[string][]int64)
+
+	var submitted []*models.Event
+
+	for i := 0; i < 40; i++ {
+		username := users[i%len(users)]
+		// timestamps are out of order on purpose, the queue order is what must be kept
+		timestamp := int64(1514764800 + (i%5)*3600 - i)
+		event := newEvent(models.EventInfo{
+			UUID:      uuid.New().String(),
+			Username:  username,
+			Timestamp: timestamp,
+			IP:        "1.0.0.0",
+		})
+		req.NoError(processor.Submit(event))
+
+		expected[username] = append(expected[username], timestamp)
+		submitted = append(submitted, event)
+	}
+
+	req.NoError(processor.Start())
+	waitForQueue(req, processor, submitted)
+	processor.Stop()
+
+	req.Equal(expected, recordingService.processed)
+
+	queuedEvent, err := processor.FindResult(submitted[0].ToEventInfo().UUID)
+	req.NoError(err)
+	req.Equal(models.QueueStatusDone, queuedEvent.Status)
+	req.Equal(&models.GeoPoint{Latitude: 10, Longitude: 10}, queuedEvent.Result.CurrentGeo)
+}
+
+func TestAsyncEventProcessor_Records_Failures(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	queueRepository := repository.NewSQLLiteEventQueueRepository(testSetup.AppServerContext().EventDb())
+	processor := NewAsyncEventProcessor(queueRepository,
+		&RecordingDetectionService{processed: make(map[string][]int64)}, config.AsyncConfig{PollIntervalMillis: 10,
+			MaxAttempts: 2})
+
+	event := newEvent(models.EventInfo{
+		UUID:      uuid.New().String(),
+		Username:  "bob",
+		Timestamp: 1514764800,
+		IP:        "0.0.0.0",
+	})
+
+	req.NoError(processor.Start())
+	req.NoError(processor.Submit(event))
+	waitForQueue(req, processor, []*models.Event{event})
+	processor.Stop()
+
+	queuedEvent, err := processor.FindResult(event.ToEventInfo().UUID)
+	req.NoError(err)
+	req.Equal(models.QueueStatusFailed, queuedEvent.Status)
+	req.Equal(2, queuedEvent.Attempts)
+	req.Nil(queuedEvent.Result)
+	req.Contains(queuedEvent.Error, "cannot find geo information")
+}
+
+// fails the first attempt at every event, or at the events of the failing uuids when set, and records the
+// timestamps of the events it processed by user
+type FlakyDetectionService struct {
+	attempted map[string]bool
+	failing   map[string]bool
+	processed map[string][]int64
+	mutex     sync.Mutex
+}
+
+func (flakyService *FlakyDetectionService) ProcessEvent(
+	currEvent *models.Event) (*models.SuspiciousTravelResult, error) {
+	flakyService.mutex.Lock()
+	defer flakyService.mutex.Unlock()
+
+	eventInfo := currEvent.ToEventInfo()
+	if !flakyService.attempted[eventInfo.UUID] && (flakyService.failing == nil || flakyService.failing[eventInfo.UUID]) {
+		flakyService.attempted[eventInfo.UUID] = true
+		return nil, fmt.Errorf("database is locked")
+	}
+
+	if flakyService.processed != nil {
+		flakyService.processed[eventInfo.Username] = append(flakyService.processed[eventInfo.Username],
+			eventInfo.Timestamp)
+	}
+
+	return &models.SuspiciousTravelResult{CurrentGeo: &models.GeoPoint{Latitude: 10, Longitude: 10}}, nil
+}
+
+func TestAsyncEventProcessor_Retries_Failed_Events(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	queueRepository := repository.NewSQLLiteEventQueueRepository(testSetup.AppServerContext().EventDb())
+	processor := NewAsyncEventProcessor(queueRepository, &FlakyDetectionService{attempted: make(map[string]bool)},
+		config.AsyncConfig{PollIntervalMillis: 10, MaxAttempts: 2})
+
+	event := newEvent(models.EventInfo{
+		UUID:      uuid.New().String(),
+		Username:  "bob",
+		Timestamp: 1514764800,
+		IP:        "1.0.0.0",
+	})
+
+	req.NoError(processor.Start())
+	req.NoError(processor.Submit(event))
+	waitForQueue(req, processor, []*models.Event{event})
+	processor.Stop()
+
+	queuedEvent, err := processor.FindResult(event.ToEventInfo().UUID)
+	req.NoError(err)
+	req.Equal(models.QueueStatusDone, queuedEvent.Status)
+	req.Equal(2, queuedEvent.Attempts)
+	req.NotNil(queuedEvent.Result)
+	req.Empty(queuedEvent.Error)
+}
+
+func TestAsyncEventProcessor_Retries_Failed_Event_Before_Later_Events_Of_User(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	queueRepository := repository.NewSQLLiteEventQueueRepository(testSetup.AppServerContext().EventDb())
+
+	var events []*models.Event
+
+	for i := 0; i < 3; i++ {
+		event := newEvent(models.EventInfo{
+			UUID:      uuid.New().String(),
+			Username:  "bob",
+			Timestamp: 1514764800 + int64(i),
+			IP:        "1.0.0.0",
+		})
+
+		// queued before the processor starts, so the events of bob are claimed together
+		queued, err := queueRepository.Enqueue(event)
+		req.NoError(err)
+		req.True(queued)
+
+		events = append(events, event)
+	}
+
+	flakyService := &FlakyDetectionService{attempted: make(map[string]bool),
+		failing: map[string]bool{events[0].ToEventInfo().UUID: true}, processed: make(map[string][]int64)}
+	processor := NewAsyncEventProcessor(queueRepository, flakyService,
+		config.AsyncConfig{PollIntervalMillis: 10, MaxAttempts: 2})
+
+	req.NoError(processor.Start())
+	waitForQueue(req, processor, events)
+	processor.Stop()
+
+	req.Equal(map[string][]int64{"bob": {1514764800, 1514764801, 1514764802}}, flakyService.processed)
+
+	for i, event := range events {
+		queuedEvent, err := processor.FindResult(event.ToEventInfo().UUID)
+		req.NoError(err)
+		req.Equal(models.QueueStatusDone, queuedEvent.Status)
+
+		if i == 0 {
+			req.Equal(2, queuedEvent.Attempts)
+		} else {
+			req.Equal(1, queuedEvent.Attempts)
+		}
+	}
+}
+
+func TestEventQueue_Prunes_Finished_Events(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	queueRepository := repository.NewSQLLiteEventQueueRepository(testSetup.AppServerContext().EventDb())
+
+	var uuids []string
+
+	for i := 0; i < 3; i++ {
+		event := newEvent(models.EventInfo{
+			UUID:      uuid.New().String(),
+			Username:  "bob",
+			Timestamp: 1514764800 + int64(i),
+			IP:        "1.0.0.0",
+		})
+
+		queued, err := queueRepository.Enqueue(event)
+		req.NoError(err)
+		req.True(queued)
+
+		uuids = append(uuids, event.ToEventInfo().UUID)
+	}
+
+	req.NoError(queueRepository.Complete(uuids[0], &models.SuspiciousTravelResult{}, nil, 1))
+	req.NoError(queueRepository.Complete(uuids[1], nil, fmt.Errorf("cannot find geo information"), 1))
+
+	pruned, err := queueRepository.PruneFinished(time.Now().Add(-time.Hour).Unix())
+	req.NoError(err)
+	req.Equal(int64(0), pruned)
+
+	pruned, err = queueRepository.PruneFinished(time.Now().Add(time.Hour).Unix())
+	req.NoError(err)
+	req.Equal(int64(2), pruned)
+
+	for i, uuid := range uuids {
+		queuedEvent, err := queueRepository.FindQueuedEvent(uuid)
+		req.NoError(err)
+
+		if i < 2 {
+			req.Nil(queuedEvent)
+		} else {
+			req.Equal(models.QueueStatusPending, queuedEvent.Status)
+		}
+	}
+}
+
+func TestAsyncEventProcessor_Requeues_Interrupted_Events(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	queueRepository := repository.NewSQLLiteEventQueueRepository(testSetup.AppServerContext().EventDb())
+	event := newEvent(models.EventInfo{
+		UUID:      uuid.New().String(),
+		Username:  "bob",
+		Timestamp: 1514764800,
+		IP:        "1.0.0.0",
+	})
+
+	queued, err := queueRepository.Enqueue(event)
+	req.NoError(err)
+	req.True(queued)
+
+	// claimed by a run that stopped before completing it
+	claimed, err := queueRepository.ClaimPending(10)
+	req.NoError(err)
+	req.Len(claimed, 1)
+
+	recordingService := &RecordingDetectionService{processed: make(map[string][]int64)}
+	processor := NewAsyncEventProcessor(queueRepository, recordingService, config.AsyncConfig{PollIntervalMillis: 10})
+	req.NoError(processor.Start())
+	waitForQueue(req, processor, []*models.Event{event})
+	processor.Stop()
+
+	req.Equal(map[string][]int64{"bob": {1514764800}}, recordingService.processed)
+}
+
+func TestFindResult_When_Event_Was_Not_Submitted(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	processor := NewAsyncEventProcessor(
+		repository.NewSQLLiteEventQueueRepository(testSetup.AppServerContext().EventDb()), nil, config.AsyncConfig{})
+
+	queuedEvent, err := processor.FindResult(uuid.New().String())
+	req.NoError(err)
+	req.Nil(queuedEvent)
+}
+
+func waitForQueue(req *require.Assertions, processor *AsyncEventProcessor, events []*models.Event) {
+	deadline := time.Now().Add(10 * time.Second)
+
+	for _, event := range events {
+		for {
+			queuedEvent, err := processor.FindResult(event.ToEventInfo().UUID)
+			req.NoError(err)
+
+			if queuedEvent.Status == models.QueueStatusDone || queuedEvent.Status == models.QueueStatusFailed {
+				break
+			}
+
+			req.True(time.Now().Before(deadline), "queue was not drained in time")
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"github.com/frankiennamdi/detection-api/core"
 	"github.com/frankiennamdi/detection-api/support"
 	"math"
 	"time"
@@ -46,18 +47,24 @@ func (service DefaultCalculatorService) TimeDifferenceInHours(currentTimeStamp,
 }
 
 func (service DefaultCalculatorService) SpeedToTravelDistanceInMPH(
+	eventGeoInfoFrom, eventGeoInfoTo *models.EventGeoInfo) (*float64, error) {
+	return speedToTravelDistanceInMPH(service, eventGeoInfoFrom, eventGeoInfoTo)
+}
+
+// the speed of the travel between the events over the distance of the calculator service, in miles per hour
+func speedToTravelDistanceInMPH(calculatorService core.CalculatorService,
 	eventGeoInfoFrom, eventGeoInfoTo *models.EventGeoInfo) (*float64, error) {
 	if eventGeoInfoFrom == nil || eventGeoInfoTo == nil {
 		return nil, support.NewIllegalArgumentError("to and from geo information cannot be nil")
 	}
 
-	distanceDiff, err := service.HaversineDistance(eventGeoInfoFrom.GeoPoint(), eventGeoInfoTo.GeoPoint())
+	distanceDiff, err := calculatorService.HaversineDistance(eventGeoInfoFrom.GeoPoint(), eventGeoInfoTo.GeoPoint())
 
 	if err != nil {
 		return nil, nil
 	}
 
-	timeDiff := service.TimeDifferenceInHours(eventGeoInfoFrom.EventInfo().Timestamp,
+	timeDiff := calculatorService.TimeDifferenceInHours(eventGeoInfoFrom.EventInfo().Timestamp,
 		eventGeoInfoTo.EventInfo().Timestamp)
 	speed := math.Abs(math.Round(distanceDiff.Miles() / timeDiff))
 
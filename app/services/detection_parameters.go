@@ -0,0 +1,20 @@
+package services
+
+import (
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+// the detection parameters of the configuration
+func NewDetectionParameters(appConfig config.AppConfig) models.DetectionParameters {
+	parameters := models.DetectionParameters{SuspiciousSpeed: appConfig.SuspiciousSpeed}
+	if speedBands := appConfig.SpeedBands; speedBands.Enabled {
+		parameters.SpeedBands = models.SpeedBands{
+			GroundMaxMiles:      speedBands.GroundMaxMiles,
+			GroundSpeed:         speedBands.GroundSpeed,
+			FlightOverheadHours: speedBands.FlightOverheadHours,
+		}
+	}
+
+	return parameters
+}
@@ -10,12 +10,13 @@ import (
 	"github.com/frankiennamdi/detection-api/models"
 )
 
-// service for detecting event characteristics relative to other events
+// service for detecting event characteristics relative to other events. it does not serialize the events of a
+// user, wrap the detection services in a UserSerializedDetectionService for that
 type EventDetectionService struct {
 	eventRepository     core.EventRepository
 	ipGeoInfoRepository core.IPGeoInfoRepository
 	calculatorService   core.CalculatorService
-	suspiciousSpeed     float64
+	parameters          *LiveDetectionParameters
 }
 
 func NewDetectionService(
@@ -23,11 +24,21 @@ func NewDetectionService(
 	ipGeoInfoRepository core.IPGeoInfoRepository,
 	calculatorService core.CalculatorService,
 	suspiciousSpeed float64) *EventDetectionService {
+	return NewLiveDetectionService(eventRepository, ipGeoInfoRepository, calculatorService,
+		NewLiveDetectionParameters(models.DetectionParameters{SuspiciousSpeed: suspiciousSpeed}))
+}
+
+// detection service that picks up the parameters whenever they are replaced
+func NewLiveDetectionService(
+	eventRepository core.EventRepository,
+	ipGeoInfoRepository core.IPGeoInfoRepository,
+	calculatorService core.CalculatorService,
+	parameters *LiveDetectionParameters) *EventDetectionService {
 	return &EventDetectionService{
 		eventRepository:     eventRepository,
 		ipGeoInfoRepository: ipGeoInfoRepository,
 		calculatorService:   calculatorService,
-		suspiciousSpeed:     suspiciousSpeed,
+		parameters:          parameters,
 	}
 }
 
@@ -50,9 +61,24 @@ func (service EventDetectionService) ProcessEvent(currEvent *models.Event) (*mod
 	return suspiciousTravelResult, err
 }
 
+// runs the detection of the event against the stored events of the user without storing it
+func (service EventDetectionService) EvaluateEvent(currEvent *models.Event) (*models.SuspiciousTravelResult, error) {
+	if currEvent == nil {
+		return nil, support.NewIllegalArgumentError("currEvent cannot be nil")
+	}
+
+	relatedEventInfo, err := service.findStoredRelatedEvents(currEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.findSuspiciousTravel(relatedEventInfo)
+}
+
 func (service EventDetectionService) findSuspiciousTravel(
 	relatedEventInfo *models.RelatedEventInfo) (*models.SuspiciousTravelResult, error) {
 	result := &models.SuspiciousTravelResult{}
+	parameters := service.parameters.Get()
 
 	currEventInfo := relatedEventInfo.CurrentEvent.ToEventInfo()
 	currEventGeoInfo, err := service.ipGeoInfoRepository.FindGeoPoint(net.ParseIP(currEventInfo.IP))
@@ -84,7 +110,12 @@ func (service EventDetectionService) findSuspiciousTravel(
 				return nil, err
 			}
 
-			value := *travelToCurrentGeoSpeed >= service.suspiciousSpeed
+			band, threshold, err := service.speedThreshold(parameters, currEventGeoInfo, preEventGeoInfo)
+			if err != nil {
+				return nil, err
+			}
+
+			value := *travelToCurrentGeoSpeed >= threshold
 			result.TravelToCurrentGeoSuspicious = &value
 			result.PrecedingIPAccess = &models.RelatedAccessInfo{
 				IP:             preEventInfo.IP,
@@ -93,6 +124,8 @@ func (service EventDetectionService) findSuspiciousTravel(
 				Longitude:      preEventGeoInfo.Longitude,
 				AccuracyRadius: preEventGeoInfo.AccuracyRadius,
 				Timestamp:      preEventInfo.Timestamp,
+				Band:           band,
+				Threshold:      threshold,
 			}
 		}
 	}
@@ -114,7 +147,12 @@ func (service EventDetectionService) findSuspiciousTravel(
 				return nil, err
 			}
 
-			value := *travelFromCurrentGeoSpeed >= service.suspiciousSpeed
+			band, threshold, err := service.speedThreshold(parameters, currEventGeoInfo, subEventGeoInfo)
+			if err != nil {
+				return nil, err
+			}
+
+			value := *travelFromCurrentGeoSpeed >= threshold
 			result.TravelFromCurrentGeoSuspicious = &value
 			result.SubsequentIPAccess = &models.RelatedAccessInfo{
 				IP:             subEventInfo.IP,
@@ -123,6 +161,8 @@ func (service EventDetectionService) findSuspiciousTravel(
 				Longitude:      subEventGeoInfo.Longitude,
 				AccuracyRadius: subEventGeoInfo.AccuracyRadius,
 				Timestamp:      subEventInfo.Timestamp,
+				Band:           band,
+				Threshold:      threshold,
 			}
 		}
 	}
@@ -130,6 +170,19 @@ func (service EventDetectionService) findSuspiciousTravel(
 	return result, nil
 }
 
+// the band of the travel between the locations and the speed from which it is suspicious
+func (service EventDetectionService) speedThreshold(parameters models.DetectionParameters,
+	fromGeoInfo, toGeoInfo *models.GeoPoint) (string, float64, error) {
+	distance, err := service.calculatorService.HaversineDistance(fromGeoInfo, toGeoInfo)
+	if err != nil {
+		return "", 0, err
+	}
+
+	band, threshold := parameters.SpeedThreshold(distance.Miles())
+
+	return band, threshold, nil
+}
+
 func (service EventDetectionService) findRelatedEvents(currEvent *models.Event) (*models.RelatedEventInfo, error) {
 	filter := repository.NewRelatedEventsFilter(currEvent)
 	err := service.eventRepository.InsertAndFindRelatedEvents(currEvent, filter)
@@ -140,3 +193,15 @@ func (service EventDetectionService) findRelatedEvents(currEvent *models.Event)
 
 	return filter.GetRelatedEvents(), nil
 }
+
+// like findRelatedEvents, without storing the event
+func (service EventDetectionService) findStoredRelatedEvents(
+	currEvent *models.Event) (*models.RelatedEventInfo, error) {
+	filter := repository.NewRelatedEventsFilter(currEvent)
+
+	if err := service.eventRepository.FindRelatedEvents(currEvent, filter); err != nil {
+		return nil, err
+	}
+
+	return filter.GetRelatedEvents(), nil
+}
@@ -3,10 +3,13 @@ package services
 import (
 	"database/sql"
 	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/repository"
 	"github.com/frankiennamdi/detection-api/test"
 	"log"
 	"math"
+	"math/rand"
 	"net"
+	"sync"
 	"testing"
 	"time"
 
@@ -392,3 +395,142 @@ func generateEvents(currentEvent *models.Event, increment int) (*models.Event, [
 
 	return closestEvent, events
 }
+
+func TestProcessEvent_Evaluates_Every_Adjacent_Pair_Once_Under_Concurrency(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	detectionService := NewUserSerializedDetectionService(NewDetectionService(
+		repository.NewSQLLiteEventsRepository(testSetup.AppServerContext().EventDb()),
+		&MockIPGeoInfoRepository{geoMap: map[string]*models.GeoPoint{
+			"1.0.0.0": {Latitude: 10, Longitude: 10, AccuracyRadius: 10},
+		}},
+		&MockCalculatorService{},
+		500))
+
+	const numEvents = 300
+
+	initialTime := int64(1514764800)
+	timestamps := rand.Perm(numEvents)
+	results := make([]*models.SuspiciousTravelResult, numEvents)
+	errs := make([]error, numEvents)
+	waitGroup := sync.WaitGroup{}
+
+	for i := 0; i < numEvents; i++ {
+		waitGroup.Add(1)
+
+		go func(index int) {
+			defer waitGroup.Done()
+
+			results[index], errs[index] = detectionService.ProcessEvent(newEvent(models.EventInfo{
+				UUID:      uuid.New().String(),
+				Username:  "bob",
+				Timestamp: test.AddTime(initialTime, timestamps[index], time.Minute),
+				IP:        "1.0.0.0",
+			}))
+		}(i)
+	}
+
+	waitGroup.Wait()
+
+	evaluatedPairs := make(map[[2]int64]int)
+
+	for i, result := range results {
+		req.NoError(errs[i])
+
+		current := test.AddTime(initialTime, timestamps[i], time.Minute)
+		if result.PrecedingIPAccess != nil {
+			evaluatedPairs[[2]int64{result.PrecedingIPAccess.Timestamp, current}]++
+		}
+
+		if result.SubsequentIPAccess != nil {
+			evaluatedPairs[[2]int64{current, result.SubsequentIPAccess.Timestamp}]++
+		}
+	}
+
+	for i := 0; i < numEvents-1; i++ {
+		pair := [2]int64{test.AddTime(initialTime, i, time.Minute), test.AddTime(initialTime, i+1, time.Minute)}
+		req.Equal(1, evaluatedPairs[pair], "pair %v", pair)
+	}
+}
+
+func TestEvaluateEvent_Does_Not_Store_Event(t *testing.T) {
+	req := require.New(t)
+	eventRepository := repository.NewMemoryEventsRepository()
+	detectionService := NewDetectionService(eventRepository,
+		&MockIPGeoInfoRepository{geoMap: map[string]*models.GeoPoint{
+			"1.0.0.0": {Latitude: 40.7128, Longitude: -74.0060},
+			"2.0.0.0": {Latitude: 34.0522, Longitude: -118.2437},
+		}},
+		DefaultCalculatorService{},
+		500)
+
+	_, err := detectionService.ProcessEvent(newEvent(models.EventInfo{
+		UUID:      uuid.New().String(),
+		Username:  "bob",
+		Timestamp: 1514764800,
+		IP:        "1.0.0.0",
+	}))
+	req.NoError(err)
+
+	checked := newEvent(models.EventInfo{
+		UUID:      uuid.New().String(),
+		Username:  "bob",
+		Timestamp: 1514768400,
+		IP:        "2.0.0.0",
+	})
+
+	result, err := detectionService.EvaluateEvent(checked)
+	req.NoError(err)
+	req.True(*result.TravelToCurrentGeoSuspicious)
+	req.Equal("1.0.0.0", result.PrecedingIPAccess.IP)
+
+	var stored int
+
+	req.NoError(eventRepository.ExportEvents(models.ExportQuery{Username: "bob"}, func(event *models.Event) error {
+		stored++
+		return nil
+	}))
+	req.Equal(1, stored)
+}
+
+func TestProcessEvent_Applies_Threshold_Of_Distance_Band(t *testing.T) {
+	req := require.New(t)
+	detectionService := NewLiveDetectionService(repository.NewMemoryEventsRepository(),
+		&MockIPGeoInfoRepository{geoMap: map[string]*models.GeoPoint{
+			"1.0.0.0": {Latitude: 40.7128, Longitude: -74.0060},
+			"1.0.0.1": {Latitude: 40.7306, Longitude: -73.9352},
+			"2.0.0.0": {Latitude: 34.0522, Longitude: -118.2437},
+		}},
+		DefaultCalculatorService{},
+		NewLiveDetectionParameters(models.DetectionParameters{SuspiciousSpeed: 500,
+			SpeedBands: models.SpeedBands{GroundMaxMiles: 300, GroundSpeed: 100, FlightOverheadHours: 2}}))
+
+	processEvent := func(timestamp int64, ip string) *models.SuspiciousTravelResult {
+		result, err := detectionService.ProcessEvent(newEvent(models.EventInfo{
+			UUID:      uuid.New().String(),
+			Username:  "bob",
+			Timestamp: timestamp,
+			IP:        ip,
+		}))
+		req.NoError(err)
+
+		return result
+	}
+
+	processEvent(1514764800, "1.0.0.0")
+
+	// a few miles across new york in two minutes
+	result := processEvent(1514764920, "1.0.0.1")
+	req.True(*result.TravelToCurrentGeoSuspicious)
+	req.Equal(models.SpeedBandGround, result.PrecedingIPAccess.Band)
+	req.Equal(100.0, result.PrecedingIPAccess.Threshold)
+
+	// to los angeles in 6 hours is below 500 mph, but not with the time spent at the airports
+	result = processEvent(1514764920+6*3600, "2.0.0.0")
+	req.Less(result.PrecedingIPAccess.Speed, 500.0)
+	req.True(*result.TravelToCurrentGeoSuspicious)
+	req.Equal(models.SpeedBandFlight, result.PrecedingIPAccess.Band)
+	req.Less(result.PrecedingIPAccess.Threshold, result.PrecedingIPAccess.Speed)
+}
@@ -0,0 +1,393 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+// longest NDJSON line accepted by the import
+const maxImportLineBytes = 1 << 20
+
+// the header columns of a csv file that hold the fields of an event. the session id and the type are optional, they
+// are read when the header has their columns
+type CSVColumns struct {
+	UUID      string
+	Username  string
+	Timestamp string
+	IP        string
+	SessionID string
+	Type      string
+}
+
+type ImportOptions struct {
+	Format    string
+	Columns   CSVColumns
+	BatchSize int
+	// runs the detection over the imported events once they are all stored, to back-fill alerts
+	Detect bool
+	// receives a line for every rejected record, may be nil
+	Rejects io.Writer
+}
+
+type ImportReport struct {
+	Records          int64 `json:"records"`
+	Imported         int64 `json:"imported"`
+	Duplicates       int64 `json:"duplicates"`
+	Rejected         int64 `json:"rejected"`
+	Detected         int64 `json:"detected"`
+	Suspicious       int64 `json:"suspicious"`
+	DetectionsFailed int64 `json:"detections_failed"`
+}
+
+// loads historical events from CSV or NDJSON files into the event repository
+type EventImporter struct {
+	eventRepository  core.EventRepository
+	detectionService core.DetectionService
+}
+
+// a record of an import file, with the event or the reason it was rejected
+type importRecord struct {
+	line  int
+	raw   string
+	event *models.Event
+	err   error
+}
+
+// returns the next record of a file, io.EOF once the file is read
+type importRecordReader func() (*importRecord, error)
+
+// the columns named after the json fields of an event
+func DefaultCSVColumns() CSVColumns {
+	return CSVColumns{UUID: "event_uuid", Username: "username", Timestamp: "unix_timestamp", IP: "ip_address",
+		SessionID: "session_id", Type: "event_type"}
+}
+
+// parses comma separated field=column pairs over the default columns, e.g. event_uuid=id,ip_address=client_ip
+func ParseCSVColumns(value string) (CSVColumns, error) {
+	columns := DefaultCSVColumns()
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return columns, models.NewValidationError(entry, "column mapping")
+		}
+
+		column := strings.TrimSpace(parts[1])
+
+		switch strings.TrimSpace(parts[0]) {
+		case "event_uuid":
+			columns.UUID = column
+		case "username":
+			columns.Username = column
+		case "unix_timestamp":
+			columns.Timestamp = column
+		case "ip_address":
+			columns.IP = column
+		case "session_id":
+			columns.SessionID = column
+		case "event_type":
+			columns.Type = column
+		default:
+			return columns, models.NewValidationError(entry, "column mapping")
+		}
+	}
+
+	return columns, nil
+}
+
+func NewEventImporter(eventRepository core.EventRepository, detectionService core.DetectionService) *EventImporter {
+	return &EventImporter{eventRepository: eventRepository, detectionService: detectionService}
+}
+
+// imports the events of the file at path, a batch per transaction. with detect the file is read a second time once
+// the whole history is stored, so every event is evaluated against its final neighbours
+func (importer EventImporter) ImportFile(path string, options ImportOptions) (*ImportReport, error) {
+	if options.Format != ImportFormatCSV && options.Format != ImportFormatNDJSON {
+		return nil, support.NewIllegalArgumentError(fmt.Sprintf("unsupported import format: %s", options.Format))
+	}
+
+	if options.BatchSize <= 0 {
+		options.BatchSize = 1000
+	}
+
+	report := &ImportReport{}
+
+	if err := withImportRecords(path, options, func(next importRecordReader) error {
+		return importer.importRecords(next, options, report)
+	}); err != nil {
+		return report, err
+	}
+
+	if !options.Detect {
+		return report, nil
+	}
+
+	err := withImportRecords(path, options, func(next importRecordReader) error {
+		return importer.detectRecords(next, report)
+	})
+
+	return report, err
+}
+
+func (importer EventImporter) importRecords(next importRecordReader, options ImportOptions,
+	report *ImportReport) error {
+	batch := make([]*models.Event, 0, options.BatchSize)
+
+	for {
+		record, err := next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		report.Records++
+
+		if record.err != nil {
+			report.Rejected++
+
+			if err := rejectRecord(options.Rejects, record); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if batch = append(batch, record.event); len(batch) == options.BatchSize {
+			if err := importer.insertBatch(batch, report); err != nil {
+				return err
+			}
+
+			batch = batch[:0]
+		}
+	}
+
+	return importer.insertBatch(batch, report)
+}
+
+func (importer EventImporter) insertBatch(batch []*models.Event, report *ImportReport) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	results, err := importer.eventRepository.InsertEvents(batch)
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows > 0 {
+			report.Imported++
+		} else {
+			report.Duplicates++
+		}
+	}
+
+	return nil
+}
+
+func (importer EventImporter) detectRecords(next importRecordReader, report *ImportReport) error {
+	for {
+		record, err := next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if record.err != nil {
+			continue
+		}
+
+		result, err := importer.detectionService.ProcessEvent(record.event)
+		if err != nil {
+			report.DetectionsFailed++
+			continue
+		}
+
+		report.Detected++
+
+		if len(models.NewAlerts(record.event, result, 0)) > 0 {
+			report.Suspicious++
+		}
+	}
+}
+
+func rejectRecord(rejects io.Writer, record *importRecord) error {
+	if rejects == nil {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(rejects, "line %d: %v: %s\n", record.line, record.err, record.raw)
+
+	return err
+}
+
+func withImportRecords(path string, options ImportOptions, fnx func(next importRecordReader) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	if options.Format == ImportFormatCSV {
+		next, err := newCSVRecordReader(file, options.Columns)
+		if err != nil {
+			return err
+		}
+
+		return fnx(next)
+	}
+
+	return fnx(newNDJSONRecordReader(file))
+}
+
+func newNDJSONRecordReader(reader io.Reader) importRecordReader {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLineBytes)
+	line := 0
+
+	return func() (*importRecord, error) {
+		for scanner.Scan() {
+			line++
+
+			raw := strings.TrimSpace(scanner.Text())
+			if raw == "" {
+				continue
+			}
+
+			record := &importRecord{line: line, raw: raw}
+
+			var eventInfo models.EventInfo
+			if record.err = json.Unmarshal([]byte(raw), &eventInfo); record.err == nil {
+				record.event, record.err = models.NewEvent(eventInfo)
+			}
+
+			return record, nil
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+
+		return nil, io.EOF
+	}
+}
+
+// the first row of the file must be the header that names the columns
+func newCSVRecordReader(reader io.Reader, columns CSVColumns) (importRecordReader, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the csv header: %v", err)
+	}
+
+	indexes := make(map[string]int)
+	for index, name := range header {
+		indexes[strings.TrimSpace(name)] = index
+	}
+
+	var columnIndexes [4]int
+
+	// rows are numbered like lines, which they are unless a quoted field spans lines
+	row := 1
+
+	for i, column := range []string{columns.UUID, columns.Username, columns.Timestamp, columns.IP} {
+		index, ok := indexes[column]
+		if !ok {
+			return nil, fmt.Errorf("csv header has no column %s", column)
+		}
+
+		columnIndexes[i] = index
+	}
+
+	optionalIndexes := [2]int{-1, -1}
+
+	for i, column := range []string{columns.SessionID, columns.Type} {
+		if index, ok := indexes[column]; ok {
+			optionalIndexes[i] = index
+		}
+	}
+
+	return func() (*importRecord, error) {
+		fields, err := csvReader.Read()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+
+		row++
+		record := &importRecord{line: row, raw: strings.Join(fields, ",")}
+
+		if parseErr, ok := err.(*csv.ParseError); ok {
+			record.err = parseErr
+			return record, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, index := range columnIndexes {
+			if index >= len(fields) {
+				record.err = fmt.Errorf("record has %d fields", len(fields))
+				return record, nil
+			}
+		}
+
+		timestamp, err := models.ParseTimestamp(fields[columnIndexes[2]])
+		if err != nil {
+			record.err = err
+			return record, nil
+		}
+
+		optionalField := func(index int) string {
+			if index < 0 || index >= len(fields) {
+				return ""
+			}
+
+			return strings.TrimSpace(fields[index])
+		}
+
+		record.event, record.err = models.NewEvent(models.EventInfo{
+			UUID:      strings.TrimSpace(fields[columnIndexes[0]]),
+			Username:  strings.TrimSpace(fields[columnIndexes[1]]),
+			Timestamp: timestamp,
+			IP:        strings.TrimSpace(fields[columnIndexes[3]]),
+			SessionID: optionalField(optionalIndexes[0]),
+			Type:      optionalField(optionalIndexes[1]),
+		})
+
+		return record, nil
+	}, nil
+}
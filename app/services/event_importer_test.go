@@ -0,0 +1,117 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/repository"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventImporter_Imports_CSV_With_Column_Mapping(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	eventRepository := repository.NewMemoryEventsRepository()
+	importer := NewEventImporter(eventRepository, nil)
+	duplicateUUID := uuid.New().String()
+	csvFile := writeImportFile(t, testSetup, "events.csv", strings.Join([]string{
+		"ts,user,id,client_ip",
+		fmt.Sprintf("1514764800,bob,%s,1.0.0.0", duplicateUUID),
+		fmt.Sprintf("2018-01-01T01:00:00Z,bob,%s,2.0.0.0", uuid.New().String()),
+		fmt.Sprintf("1514772000,bob,%s,1.0.0.0", duplicateUUID),
+		"1514775600,bob,not-a-uuid,1.0.0.0",
+		fmt.Sprintf("yesterday,bob,%s,1.0.0.0", uuid.New().String()),
+	}, "\n"))
+
+	columns, err := ParseCSVColumns("event_uuid=id,username=user,unix_timestamp=ts,ip_address=client_ip")
+	req.NoError(err)
+
+	rejects := &bytes.Buffer{}
+	report, err := importer.ImportFile(csvFile, ImportOptions{Format: ImportFormatCSV, Columns: columns,
+		BatchSize: 2, Rejects: rejects})
+	req.NoError(err)
+	req.Equal(ImportReport{Records: 5, Imported: 2, Duplicates: 1, Rejected: 2}, *report)
+	req.Contains(rejects.String(), "line 5: ")
+	req.Contains(rejects.String(), "line 6: ")
+
+	expired, err := eventRepository.FindExpiredEvents(models.RetentionRule{Before: 1514800000}, 10)
+	req.NoError(err)
+	req.Len(expired, 1)
+	req.Equal(int64(1514764800), expired[0].ToEventInfo().Timestamp)
+}
+
+func TestEventImporter_Rejects_Missing_CSV_Column(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	csvFile := writeImportFile(t, testSetup, "events.csv", "event_uuid,username,ip_address\n")
+	_, err := NewEventImporter(repository.NewMemoryEventsRepository(), nil).ImportFile(csvFile,
+		ImportOptions{Format: ImportFormatCSV, Columns: DefaultCSVColumns()})
+	require.Error(t, err)
+}
+
+func TestEventImporter_Imports_NDJSON_And_Back_Fills_Alerts(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	initialTime := int64(1514764800)
+	alertRepository := repository.NewSQLLiteAlertRepository(testSetup.AppServerContext().EventDb())
+	eventRepository := repository.NewMemoryEventsRepository()
+	detectionService := NewAlertingDetectionService(NewDetectionService(eventRepository,
+		&MockIPGeoInfoRepository{geoMap: map[string]*models.GeoPoint{
+			"1.0.0.0": {Latitude: 0, Longitude: 0, AccuracyRadius: 10},
+			"2.0.0.0": {Latitude: 50, Longitude: 50, AccuracyRadius: 10},
+		}}, DefaultCalculatorService{}, 500), alertRepository)
+
+	monthLater := newEvent(models.EventInfo{UUID: uuid.New().String(), Username: "bob",
+		Timestamp: test.AddTime(initialTime, 30*24, time.Hour), IP: "1.0.0.0"})
+	var lines []string
+
+	// the file is not in time order, the later login is imported before the earlier one
+	for _, event := range []*models.Event{
+		monthLater,
+		newEvent(models.EventInfo{UUID: uuid.New().String(), Username: "bob",
+			Timestamp: test.AddTime(initialTime, 1, time.Hour), IP: "2.0.0.0"}),
+		newEvent(models.EventInfo{UUID: uuid.New().String(), Username: "bob", Timestamp: initialTime,
+			IP: "1.0.0.0"}),
+		monthLater,
+	} {
+		line, err := event.MarshalJSON()
+		req.NoError(err)
+
+		lines = append(lines, string(line))
+	}
+
+	lines = append(lines, `{"event_uuid": "not-a-uuid"`)
+	ndjsonFile := writeImportFile(t, testSetup, "events.ndjson", strings.Join(lines, "\n"))
+
+	report, err := NewEventImporter(eventRepository, detectionService).ImportFile(ndjsonFile,
+		ImportOptions{Format: ImportFormatNDJSON, Detect: true})
+	req.NoError(err)
+	req.Equal(ImportReport{Records: 5, Imported: 3, Duplicates: 1, Rejected: 1, Detected: 4, Suspicious: 2},
+		*report)
+
+	alerts, err := alertRepository.FindAlerts("bob")
+	req.NoError(err)
+	req.Len(alerts, 1)
+	req.Equal(initialTime, alerts[0].FromTimestamp)
+	req.Equal("2.0.0.0", alerts[0].ToIP)
+}
+
+func writeImportFile(t *testing.T, testSetup *test.Test, name, content string) string {
+	path := filepath.Join(testSetup.Dir(), name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	return path
+}
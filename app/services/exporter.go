@@ -0,0 +1,263 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+const (
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatCSV    = "csv"
+)
+
+// the columns an export of events can select, the cursor resumes the export after the event
+var EventExportColumns = []string{"event_uuid", "username", "unix_timestamp", "ip_address", "session_id",
+	"event_type", "cursor"}
+
+// the columns an export of alerts can select, seq is the cursor that resumes the export after the alert
+var AlertExportColumns = []string{"seq", "event_uuid", "username", "from_timestamp", "from_ip", "from_lat",
+	"from_lon", "to_timestamp", "to_ip", "to_lat", "to_lon", "speed", "created_at"}
+
+// streams events and alerts as NDJSON or CSV, a record is written as soon as it is read
+type Exporter struct {
+	eventRepository core.EventExportRepository
+	alertRepository core.AlertRepository
+}
+
+// writes the selected columns of a record
+type exportWriter interface {
+	Write(values []interface{}) error
+	Flush() error
+}
+
+func NewExporter(eventRepository core.EventExportRepository, alertRepository core.AlertRepository) *Exporter {
+	return &Exporter{eventRepository: eventRepository, alertRepository: alertRepository}
+}
+
+func IsExportFormat(format string) bool {
+	return format == ExportFormatNDJSON || format == ExportFormatCSV
+}
+
+// parses comma separated columns, every available column when empty
+func ParseExportColumns(value string, available []string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return available, nil
+	}
+
+	var columns []string
+
+	for _, column := range strings.Split(value, ",") {
+		column = strings.TrimSpace(column)
+		if !containsColumn(available, column) {
+			return nil, models.NewValidationError(column, "column")
+		}
+
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+// returns the number of events written
+func (exporter Exporter) ExportEvents(out io.Writer, query models.ExportQuery, format string,
+	columns []string) (int64, error) {
+	writer, err := newExportWriter(out, format, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+
+	values := make([]interface{}, len(columns))
+	err = exporter.eventRepository.ExportEvents(query, func(event *models.Event) error {
+		eventInfo := event.ToEventInfo()
+		for i, column := range columns {
+			values[i] = eventColumnValue(eventInfo, column)
+		}
+
+		written++
+
+		return writer.Write(values)
+	})
+
+	if flushErr := writer.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+
+	return written, err
+}
+
+// returns the number of alerts written
+func (exporter Exporter) ExportAlerts(out io.Writer, query models.ExportQuery, format string,
+	columns []string) (int64, error) {
+	writer, err := newExportWriter(out, format, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+
+	values := make([]interface{}, len(columns))
+	err = exporter.alertRepository.ExportAlerts(query, func(alert *models.Alert) error {
+		for i, column := range columns {
+			values[i] = alertColumnValue(alert, column)
+		}
+
+		written++
+
+		return writer.Write(values)
+	})
+
+	if flushErr := writer.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+
+	return written, err
+}
+
+func eventColumnValue(eventInfo models.EventInfo, column string) interface{} {
+	switch column {
+	case "event_uuid":
+		return eventInfo.UUID
+	case "username":
+		return eventInfo.Username
+	case "unix_timestamp":
+		return eventInfo.Timestamp
+	case "ip_address":
+		return eventInfo.IP
+	case "session_id":
+		return eventInfo.SessionID
+	case "event_type":
+		return eventInfo.Type
+	default:
+		return models.EventCursor(eventInfo)
+	}
+}
+
+func alertColumnValue(alert *models.Alert, column string) interface{} {
+	switch column {
+	case "seq":
+		return alert.Seq
+	case "event_uuid":
+		return alert.EventUUID
+	case "username":
+		return alert.Username
+	case "from_timestamp":
+		return alert.FromTimestamp
+	case "from_ip":
+		return alert.FromIP
+	case "from_lat":
+		return alert.FromLatitude
+	case "from_lon":
+		return alert.FromLongitude
+	case "to_timestamp":
+		return alert.ToTimestamp
+	case "to_ip":
+		return alert.ToIP
+	case "to_lat":
+		return alert.ToLatitude
+	case "to_lon":
+		return alert.ToLongitude
+	case "speed":
+		return alert.Speed
+	default:
+		return alert.CreatedAt
+	}
+}
+
+func containsColumn(columns []string, column string) bool {
+	for _, candidate := range columns {
+		if candidate == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newExportWriter(out io.Writer, format string, columns []string) (exportWriter, error) {
+	switch format {
+	case ExportFormatNDJSON:
+		return &ndjsonExportWriter{out: bufio.NewWriter(out), columns: columns}, nil
+	case ExportFormatCSV:
+		writer := &csvExportWriter{out: csv.NewWriter(out), record: make([]string, len(columns))}
+		if err := writer.out.Write(columns); err != nil {
+			return nil, err
+		}
+
+		return writer, nil
+	default:
+		return nil, models.NewValidationError(format, "format")
+	}
+}
+
+// writes each record as a json object with the columns in the selected order
+type ndjsonExportWriter struct {
+	out     *bufio.Writer
+	columns []string
+}
+
+func (writer *ndjsonExportWriter) Write(values []interface{}) error {
+	if err := writer.out.WriteByte('{'); err != nil {
+		return err
+	}
+
+	for i, value := range values {
+		if i > 0 {
+			if err := writer.out.WriteByte(','); err != nil {
+				return err
+			}
+		}
+
+		name, _ := json.Marshal(writer.columns[i])
+		encoded, err := json.Marshal(value)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(writer.out, "%s:%s", name, encoded); err != nil {
+			return err
+		}
+	}
+
+	_, err := writer.out.WriteString("}\n")
+
+	return err
+}
+
+func (writer *ndjsonExportWriter) Flush() error {
+	return writer.out.Flush()
+}
+
+// writes a header row with the columns and a row per record
+type csvExportWriter struct {
+	out    *csv.Writer
+	record []string
+}
+
+func (writer *csvExportWriter) Write(values []interface{}) error {
+	for i, value := range values {
+		switch typed := value.(type) {
+		case float64:
+			writer.record[i] = strconv.FormatFloat(typed, 'f', -1, 64)
+		default:
+			writer.record[i] = fmt.Sprint(typed)
+		}
+	}
+
+	return writer.out.Write(writer.record)
+}
+
+func (writer *csvExportWriter) Flush() error {
+	writer.out.Flush()
+	return writer.out.Error()
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/repository"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter_Exports_Selected_Event_Columns(t *testing.T) {
+	req := require.New(t)
+	eventRepository := repository.NewMemoryEventsRepository()
+	_, err := eventRepository.InsertEvents([]*models.Event{
+		newEvent(models.EventInfo{UUID: "85ad929a-db03-4bf4-9541-8f728fa12e42", Username: "bob",
+			Timestamp: 1514764800, IP: "1.0.0.0"}),
+		newEvent(models.EventInfo{UUID: "b9bd4ab4-d5c2-4c58-88c3-b4fdc2f1d30b", Username: "bob, jr",
+			Timestamp: 1514768400, IP: "2.0.0.0"}),
+	})
+	req.NoError(err)
+
+	exporter := NewExporter(eventRepository, nil)
+	columns, err := ParseExportColumns("username,unix_timestamp,cursor", EventExportColumns)
+	req.NoError(err)
+
+	out := &bytes.Buffer{}
+	written, err := exporter.ExportEvents(out, models.ExportQuery{}, ExportFormatCSV, columns)
+	req.NoError(err)
+	req.Equal(int64(2), written)
+	req.Equal("username,unix_timestamp,cursor\n"+
+		"bob,1514764800,1514764800:85ad929a-db03-4bf4-9541-8f728fa12e42\n"+
+		"\"bob, jr\",1514768400,1514768400:b9bd4ab4-d5c2-4c58-88c3-b4fdc2f1d30b\n", out.String())
+
+	out.Reset()
+	written, err = exporter.ExportEvents(out, models.ExportQuery{Limit: 1}, ExportFormatNDJSON,
+		[]string{"ip_address", "event_uuid"})
+	req.NoError(err)
+	req.Equal(int64(1), written)
+	req.Equal(`{"ip_address":"1.0.0.0","event_uuid":"85ad929a-db03-4bf4-9541-8f728fa12e42"}`+"\n", out.String())
+
+	_, err = ParseExportColumns("username,password", EventExportColumns)
+	req.Error(err)
+}
+
+func TestExporter_Exports_Alerts_After_Cursor(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	alertRepository := repository.NewSQLLiteAlertRepository(testSetup.AppServerContext().EventDb())
+	_, err := alertRepository.InsertAlerts([]*models.Alert{
+		{EventUUID: "a", Username: "bob", FromTimestamp: 1, ToTimestamp: 2, Speed: 600.5},
+		{EventUUID: "b", Username: "bob", FromTimestamp: 2, ToTimestamp: 3, Speed: 700},
+		{EventUUID: "c", Username: "jane", FromTimestamp: 2, ToTimestamp: 3, Speed: 800},
+	})
+	req.NoError(err)
+
+	exporter := NewExporter(nil, alertRepository)
+	out := &bytes.Buffer{}
+	written, err := exporter.ExportAlerts(out, models.ExportQuery{After: "1"}, ExportFormatCSV,
+		[]string{"seq", "username", "speed"})
+	req.NoError(err)
+	req.Equal(int64(2), written)
+	req.Equal([]string{"seq,username,speed", "2,bob,700", "3,jane,800"},
+		strings.Split(strings.TrimSpace(out.String()), "\n"))
+
+	out.Reset()
+	written, err = exporter.ExportAlerts(out, models.ExportQuery{Username: "bob", From: 3}, ExportFormatNDJSON,
+		[]string{"event_uuid", "speed"})
+	req.NoError(err)
+	req.Equal(int64(1), written)
+	req.Equal(`{"event_uuid":"b","speed":700}`+"\n", out.String())
+}
+
+func TestExporter_CSV_Export_Imports_With_Sessions(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	eventRepository := repository.NewMemoryEventsRepository()
+	eventInfos := []models.EventInfo{
+		{UUID: "85ad929a-db03-4bf4-9541-8f728fa12e42", Username: "bob", Timestamp: 1514764800, IP: "1.0.0.0",
+			SessionID: "s1"},
+		{UUID: "b9bd4ab4-d5c2-4c58-88c3-b4fdc2f1d30b", Username: "bob", Timestamp: 1514768400, IP: "1.0.0.0",
+			SessionID: "s1", Type: models.EventTypeLogout},
+	}
+
+	for _, eventInfo := range eventInfos {
+		_, err := eventRepository.InsertEvents([]*models.Event{newEvent(eventInfo)})
+		req.NoError(err)
+	}
+
+	out := &bytes.Buffer{}
+	_, err := NewExporter(eventRepository, nil).ExportEvents(out, models.ExportQuery{}, ExportFormatCSV,
+		EventExportColumns)
+	req.NoError(err)
+
+	imported := repository.NewMemoryEventsRepository()
+	report, err := NewEventImporter(imported, nil).ImportFile(writeImportFile(t, testSetup, "events.csv",
+		out.String()), ImportOptions{Format: ImportFormatCSV, Columns: DefaultCSVColumns()})
+	req.NoError(err)
+	req.Equal(int64(2), report.Imported)
+
+	var exported []models.EventInfo
+
+	req.NoError(imported.ExportEvents(models.ExportQuery{}, func(event *models.Event) error {
+		exported = append(exported, event.ToEventInfo())
+		return nil
+	}))
+	req.Equal(eventInfos, exported)
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"sync/atomic"
+
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+// detection parameters that can be replaced while events are processed. a reader gets either the previous or the
+// new parameters as a whole, never a mix of both
+type LiveDetectionParameters struct {
+	value atomic.Value
+}
+
+func NewLiveDetectionParameters(parameters models.DetectionParameters) *LiveDetectionParameters {
+	live := &LiveDetectionParameters{}
+	live.value.Store(parameters)
+
+	return live
+}
+
+func (live *LiveDetectionParameters) Get() models.DetectionParameters {
+	return live.value.Load().(models.DetectionParameters)
+}
+
+func (live *LiveDetectionParameters) Set(parameters models.DetectionParameters) {
+	live.value.Store(parameters)
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"log"
+	"net"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// builds the location profiles of every user from the stored events, for events stored before the profiles were
+// kept or after the geohash precision changed
+type LocationProfiler struct {
+	eventRepository           core.EventExportRepository
+	ipGeoInfoRepository       core.IPGeoInfoRepository
+	locationProfileRepository core.LocationProfileRepository
+	noveltyConfig             config.NoveltyConfig
+}
+
+func NewLocationProfiler(eventRepository core.EventExportRepository,
+	ipGeoInfoRepository core.IPGeoInfoRepository,
+	locationProfileRepository core.LocationProfileRepository,
+	noveltyConfig config.NoveltyConfig) *LocationProfiler {
+	return &LocationProfiler{
+		eventRepository:           eventRepository,
+		ipGeoInfoRepository:       ipGeoInfoRepository,
+		locationProfileRepository: locationProfileRepository,
+		noveltyConfig:             noveltyConfig,
+	}
+}
+
+// replaces the profiles with the ones of the stored events, each event with the history it would have got when
+// processed in timestamp order. events of an unknown location are skipped
+func (profiler *LocationProfiler) Rebuild() (*models.LocationProfileReport, error) {
+	report := &models.LocationProfileReport{}
+	users := make(map[string]bool)
+	locations := make(map[models.UserLocation]*models.UserLocation)
+
+	// the located events of each user so far
+	profileEvents := make(map[string]int64)
+
+	var userLocations []*models.UserLocation
+
+	var observations []*models.LocationObservation
+
+	observe := func(username, kind, location string, timestamp int64) *models.UserLocation {
+		key := models.UserLocation{Username: username, Kind: kind, Location: location}
+		userLocation, ok := locations[key]
+
+		if !ok {
+			userLocation = &models.UserLocation{Username: username, Kind: kind, Location: location,
+				FirstSeen: timestamp, LastSeen: timestamp}
+			locations[key] = userLocation
+			userLocations = append(userLocations, userLocation)
+		}
+
+		// the location as it was before the event
+		before := *userLocation
+		userLocation.Count++
+
+		if timestamp < userLocation.FirstSeen {
+			userLocation.FirstSeen = timestamp
+		}
+
+		if timestamp > userLocation.LastSeen {
+			userLocation.LastSeen = timestamp
+		}
+
+		return &before
+	}
+
+	err := profiler.eventRepository.ExportEvents(models.ExportQuery{}, func(event *models.Event) error {
+		report.Events++
+
+		eventInfo := event.ToEventInfo()
+		users[eventInfo.Username] = true
+
+		geoPoint, err := profiler.ipGeoInfoRepository.FindGeoPoint(net.ParseIP(eventInfo.IP))
+		if err != nil {
+			log.Printf(support.Warn, err)
+		}
+
+		if geoPoint == nil {
+			report.UnknownLocations++
+			return nil
+		}
+
+		geohash := models.Geohash(geoPoint.Latitude, geoPoint.Longitude, profiler.noveltyConfig.GeohashPrecision)
+		observation := &models.LocationObservation{Username: eventInfo.Username, Timestamp: eventInfo.Timestamp,
+			LocationHistory: models.LocationHistory{Geohash: geohash, Country: geoPoint.Country,
+				ProfileEvents: profileEvents[eventInfo.Username]}}
+		profileEvents[eventInfo.Username]++
+
+		cell := observe(eventInfo.Username, models.LocationKindCell, geohash, eventInfo.Timestamp)
+		observation.LocationCount = cell.Count
+
+		if cell.Count > 0 {
+			observation.LocationLastSeen = cell.LastSeen
+		}
+
+		if geoPoint.Country != "" {
+			observation.CountryCount = observe(eventInfo.Username, models.LocationKindCountry, geoPoint.Country,
+				eventInfo.Timestamp).Count
+		}
+
+		observations = append(observations, observation)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := profiler.locationProfileRepository.ReplaceUserLocations(userLocations, observations); err != nil {
+		return nil, err
+	}
+
+	report.Users = int64(len(users))
+	report.Locations = int64(len(userLocations))
+
+	return report, nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"log"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// detection service that adds the location of every event found by the detection service it wraps to the location
+// profile of the user, and returns how new the location is to the user with the result
+type NoveltyDetectionService struct {
+	detectionService          core.DetectionService
+	locationProfileRepository core.LocationProfileRepository
+	noveltyConfig             config.NoveltyConfig
+}
+
+func NewNoveltyDetectionService(detectionService core.DetectionService,
+	locationProfileRepository core.LocationProfileRepository,
+	noveltyConfig config.NoveltyConfig) *NoveltyDetectionService {
+	return &NoveltyDetectionService{
+		detectionService:          detectionService,
+		locationProfileRepository: locationProfileRepository,
+		noveltyConfig:             noveltyConfig,
+	}
+}
+
+// a failure to update the profile is logged, the result of the detection is still returned without the novelty
+func (service NoveltyDetectionService) ProcessEvent(
+	currEvent *models.Event) (*models.SuspiciousTravelResult, error) {
+	result, err := service.detectionService.ProcessEvent(currEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.CurrentGeo == nil {
+		return result, nil
+	}
+
+	eventInfo := currEvent.ToEventInfo()
+	history, err := service.locationProfileRepository.ObserveLocation(eventInfo.Username,
+		models.Geohash(result.CurrentGeo.Latitude, result.CurrentGeo.Longitude, service.noveltyConfig.GeohashPrecision),
+		result.CurrentGeo.Country, eventInfo.Timestamp)
+
+	if err != nil {
+		log.Printf(support.Error, err)
+		return result, nil
+	}
+
+	result.Novelty = models.NewLocationNovelty(*history, int64(service.noveltyConfig.MinEvents),
+		service.noveltyConfig.RareShare)
+
+	return result, nil
+}
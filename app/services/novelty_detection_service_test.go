@@ -0,0 +1,102 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/repository"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var noveltyTestGeoMap = map[string]*models.GeoPoint{
+	"1.0.0.0": {Latitude: 40.7128, Longitude: -74.0060, Country: "US"},
+	"1.0.0.1": {Latitude: 40.7306, Longitude: -73.9352, Country: "US"},
+	"2.0.0.0": {Latitude: 51.5074, Longitude: -0.1278, Country: "GB"},
+}
+
+func TestNoveltyDetectionService_Flags_First_Seen_Country(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	service := NewNoveltyDetectionService(
+		NewDetectionService(repository.NewMemoryEventsRepository(), MockIPGeoInfoRepository{geoMap: noveltyTestGeoMap},
+			DefaultCalculatorService{}, 500),
+		repository.NewSQLLiteLocationProfileRepository(testSetup.AppServerContext().EventDb()),
+		config.NoveltyConfig{GeohashPrecision: 4, MinEvents: 5, RareShare: 0.05})
+
+	processEvent := func(hour int64, ip string) *models.LocationNovelty {
+		event, err := models.NewEvent(models.EventInfo{UUID: uuid.New().String(), Username: "bob",
+			Timestamp: rescoreStart + hour*3600, IP: ip})
+		req.NoError(err)
+
+		result, err := service.ProcessEvent(event)
+		req.NoError(err)
+		req.NotNil(result.Novelty)
+
+		return result.Novelty
+	}
+
+	// a new location while the profile is learning is not flagged
+	req.False(processEvent(0, "1.0.0.0").FirstSeenLocation)
+
+	for hour := int64(1); hour < 10; hour++ {
+		novelty := processEvent(hour, "1.0.0.1")
+		req.False(novelty.FirstSeenLocation || novelty.FirstSeenCountry)
+	}
+
+	// london two days later is a plausible pace, but a first for bob
+	novelty := processEvent(48, "2.0.0.0")
+	req.True(novelty.FirstSeenLocation)
+	req.True(novelty.FirstSeenCountry)
+	req.Equal("GB", novelty.Country)
+	req.Equal(int64(10), novelty.ProfileEvents)
+	req.Equal(0.67, novelty.Confidence)
+
+	// processed again, london is still a first and is not counted twice
+	req.Equal(novelty, processEvent(48, "2.0.0.0"))
+	req.Equal(int64(11), processEvent(49, "2.0.0.0").ProfileEvents)
+}
+
+func TestLocationProfiler_Rebuilds_From_Stored_Events(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	eventRepository := repository.NewMemoryEventsRepository()
+	profileRepository := repository.NewSQLLiteLocationProfileRepository(testSetup.AppServerContext().EventDb())
+
+	for i, ip := range []string{"1.0.0.0", "1.0.0.1", "2.0.0.0", "3.0.0.0"} {
+		event, err := models.NewEvent(models.EventInfo{UUID: uuid.New().String(), Username: "bob",
+			Timestamp: rescoreStart + int64(i)*3600, IP: ip})
+		req.NoError(err)
+
+		_, err = eventRepository.InsertEvents([]*models.Event{event})
+		req.NoError(err)
+	}
+
+	report, err := NewLocationProfiler(eventRepository, MockIPGeoInfoRepository{geoMap: noveltyTestGeoMap},
+		profileRepository, config.NoveltyConfig{GeohashPrecision: 4}).Rebuild()
+	req.NoError(err)
+	req.Equal(&models.LocationProfileReport{Users: 1, Events: 4, UnknownLocations: 1, Locations: 4}, report)
+
+	userLocations, err := profileRepository.FindUserLocations("bob")
+	req.NoError(err)
+	req.Len(userLocations, 4)
+	req.Equal(&models.UserLocation{Username: "bob", Kind: models.LocationKindCell, Location: "dr5r", Count: 2,
+		FirstSeen: rescoreStart, LastSeen: rescoreStart + 3600}, userLocations[0])
+
+	// a stored event processed after the rebuild is not added again
+	history, err := profileRepository.ObserveLocation("bob", "dr5r", "US", rescoreStart+3600)
+	req.NoError(err)
+	req.Equal(int64(1), history.ProfileEvents)
+	req.Equal(int64(1), history.LocationCount)
+	req.Equal(rescoreStart, history.LocationLastSeen)
+
+	userLocations, err = profileRepository.FindUserLocations("bob")
+	req.NoError(err)
+	req.Equal(int64(2), userLocations[0].Count)
+}
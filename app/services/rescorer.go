@@ -0,0 +1,301 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// alerts listed in each of the new and removed alerts of a report
+const maxRescoreAlerts = 1000
+
+// finished jobs kept for their report to be fetched
+const maxRescoreJobs = 20
+
+const rescoreJobIDBytes = 8
+
+var ErrRescoreRunning = errors.New("a rescore is already running")
+
+// replays the stored history of events through the detection rules under two sets of parameters and reports how
+// the alerts differ. nothing is written, the events are read in timestamp order and every user is paired with the
+// event of the user that preceded it, the same pairs the detection of each event looks at
+type Rescorer struct {
+	eventRepository     core.EventExportRepository
+	ipGeoInfoRepository core.IPGeoInfoRepository
+	calculatorService   core.CalculatorService
+}
+
+// an event of the user with its location, nil when it is unknown
+type locatedEvent struct {
+	event     *models.Event
+	eventInfo models.EventInfo
+	geoPoint  *models.GeoPoint
+}
+
+// the latest event of the user read so far
+type rescoreUser struct {
+	last   *locatedEvent
+	counts *models.RescoreUserCounts
+}
+
+func NewRescorer(eventRepository core.EventExportRepository, ipGeoInfoRepository core.IPGeoInfoRepository,
+	calculatorService core.CalculatorService) *Rescorer {
+	return &Rescorer{
+		eventRepository:     eventRepository,
+		ipGeoInfoRepository: ipGeoInfoRepository,
+		calculatorService:   calculatorService,
+	}
+}
+
+// rescores the events of the username, every user when it is empty
+func (rescorer *Rescorer) Rescore(baseline, candidate models.DetectionParameters,
+	username string) (*models.RescoreReport, error) {
+	if err := baseline.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := candidate.Validate(); err != nil {
+		return nil, err
+	}
+
+	report := &models.RescoreReport{NewAlerts: []*models.Alert{}, RemovedAlerts: []*models.Alert{}}
+	users := make(map[string]*rescoreUser)
+
+	err := rescorer.eventRepository.ExportEvents(models.ExportQuery{Username: username},
+		func(event *models.Event) error {
+			report.Events++
+
+			eventInfo := event.ToEventInfo()
+			user, ok := users[eventInfo.Username]
+
+			if !ok {
+				user = &rescoreUser{counts: &models.RescoreUserCounts{Username: eventInfo.Username}}
+				users[eventInfo.Username] = user
+			}
+
+			current := &locatedEvent{event: event, eventInfo: eventInfo, geoPoint: rescorer.findGeoPoint(eventInfo)}
+			if current.geoPoint == nil {
+				report.UnknownLocations++
+			}
+
+			previous := user.last
+			user.last = current
+
+			return rescorer.scorePair(report, user.counts, previous, current, baseline, candidate)
+		})
+
+	if err != nil {
+		return nil, err
+	}
+
+	report.Users = int64(len(users))
+	report.ChangedUsers = changedUsers(users)
+
+	return report, nil
+}
+
+func (rescorer *Rescorer) findGeoPoint(eventInfo models.EventInfo) *models.GeoPoint {
+	geoPoint, err := rescorer.ipGeoInfoRepository.FindGeoPoint(net.ParseIP(eventInfo.IP))
+	if err != nil {
+		log.Printf(support.Warn, err)
+		return nil
+	}
+
+	return geoPoint
+}
+
+// scores the travel from the previous event to the current one under both parameters
+func (rescorer *Rescorer) scorePair(report *models.RescoreReport, counts *models.RescoreUserCounts,
+	previous, current *locatedEvent, baseline, candidate models.DetectionParameters) error {
+	if previous == nil || previous.geoPoint == nil || current.geoPoint == nil {
+		return nil
+	}
+
+	speed, err := rescorer.calculatorService.SpeedToTravelDistanceInMPH(
+		models.NewEventGeoInfo(&current.eventInfo, current.geoPoint),
+		models.NewEventGeoInfo(&previous.eventInfo, previous.geoPoint))
+
+	if err != nil {
+		return err
+	}
+
+	if speed == nil {
+		return nil
+	}
+
+	distance, err := rescorer.calculatorService.HaversineDistance(current.geoPoint, previous.geoPoint)
+	if err != nil {
+		return err
+	}
+
+	report.Pairs++
+
+	baselineAlert := baseline.IsSuspiciousTravel(*speed, distance.Miles())
+	candidateAlert := candidate.IsSuspiciousTravel(*speed, distance.Miles())
+
+	if baselineAlert {
+		report.BaselineAlerts++
+		counts.BaselineAlerts++
+	}
+
+	if candidateAlert {
+		report.CandidateAlerts++
+		counts.CandidateAlerts++
+	}
+
+	switch {
+	case candidateAlert && !baselineAlert:
+		counts.NewAlerts++
+		report.NewAlerts = appendRescoreAlert(report, report.NewAlerts, previous, current, *speed)
+	case baselineAlert && !candidateAlert:
+		counts.RemovedAlerts++
+		report.RemovedAlerts = appendRescoreAlert(report, report.RemovedAlerts, previous, current, *speed)
+	}
+
+	return nil
+}
+
+func appendRescoreAlert(report *models.RescoreReport, alerts []*models.Alert, previous, current *locatedEvent,
+	speed float64) []*models.Alert {
+	if len(alerts) >= maxRescoreAlerts {
+		report.Truncated = true
+		return alerts
+	}
+
+	suspicious := true
+	result := &models.SuspiciousTravelResult{
+		CurrentGeo:                   current.geoPoint,
+		TravelToCurrentGeoSuspicious: &suspicious,
+		PrecedingIPAccess: &models.RelatedAccessInfo{
+			IP:        previous.eventInfo.IP,
+			Speed:     speed,
+			Latitude:  previous.geoPoint.Latitude,
+			Longitude: previous.geoPoint.Longitude,
+			Timestamp: previous.eventInfo.Timestamp,
+		},
+	}
+
+	return append(alerts, models.NewAlerts(current.event, result, 0)...)
+}
+
+func changedUsers(users map[string]*rescoreUser) []*models.RescoreUserCounts {
+	changed := []*models.RescoreUserCounts{}
+
+	for _, user := range users {
+		if user.counts.NewAlerts > 0 || user.counts.RemovedAlerts > 0 {
+			changed = append(changed, user.counts)
+		}
+	}
+
+	sort.Slice(changed, func(i, j int) bool {
+		return changed[i].Username < changed[j].Username
+	})
+
+	return changed
+}
+
+// runs rescores in the background one at a time and keeps the latest jobs
+type RescoreJobs struct {
+	rescorer *Rescorer
+	lock     sync.Mutex
+	jobs     map[string]*models.RescoreJob
+	order    []string
+	running  bool
+	now      func() time.Time
+}
+
+func NewRescoreJobs(rescorer *Rescorer) *RescoreJobs {
+	return &RescoreJobs{rescorer: rescorer, jobs: make(map[string]*models.RescoreJob), now: time.Now}
+}
+
+// starts a rescore of the username, every user when it is empty, and returns the running job
+func (rescoreJobs *RescoreJobs) Start(baseline, candidate models.DetectionParameters,
+	username string) (*models.RescoreJob, error) {
+	if err := candidate.Validate(); err != nil {
+		return nil, err
+	}
+
+	id, err := randomHex(rescoreJobIDBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rescoreJobs.lock.Lock()
+	defer rescoreJobs.lock.Unlock()
+
+	if rescoreJobs.running {
+		return nil, ErrRescoreRunning
+	}
+
+	job := &models.RescoreJob{
+		ID:        id,
+		Status:    models.RescoreStatusRunning,
+		Username:  username,
+		Baseline:  baseline,
+		Candidate: candidate,
+		StartedAt: rescoreJobs.now().Unix(),
+	}
+	rescoreJobs.running = true
+	rescoreJobs.add(job)
+
+	go rescoreJobs.run(*job)
+
+	copied := *job
+
+	return &copied, nil
+}
+
+// the job with the id, nil when there is none
+func (rescoreJobs *RescoreJobs) Find(id string) *models.RescoreJob {
+	rescoreJobs.lock.Lock()
+	defer rescoreJobs.lock.Unlock()
+
+	job, ok := rescoreJobs.jobs[id]
+	if !ok {
+		return nil
+	}
+
+	copied := *job
+
+	return &copied
+}
+
+func (rescoreJobs *RescoreJobs) run(job models.RescoreJob) {
+	report, err := rescoreJobs.rescorer.Rescore(job.Baseline, job.Candidate, job.Username)
+
+	rescoreJobs.lock.Lock()
+	defer rescoreJobs.lock.Unlock()
+
+	finished := rescoreJobs.jobs[job.ID]
+	finished.FinishedAt = rescoreJobs.now().Unix()
+	rescoreJobs.running = false
+
+	if err != nil {
+		log.Printf(support.Error, err)
+		finished.Status = models.RescoreStatusFailed
+		finished.Error = err.Error()
+
+		return
+	}
+
+	finished.Status = models.RescoreStatusDone
+	finished.Report = report
+}
+
+// keeps the job, dropping the oldest finished jobs beyond the limit
+func (rescoreJobs *RescoreJobs) add(job *models.RescoreJob) {
+	rescoreJobs.jobs[job.ID] = job
+	rescoreJobs.order = append(rescoreJobs.order, job.ID)
+
+	for len(rescoreJobs.order) > maxRescoreJobs {
+		delete(rescoreJobs.jobs, rescoreJobs.order[0])
+		rescoreJobs.order = rescoreJobs.order[1:]
+	}
+}
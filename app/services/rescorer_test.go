@@ -0,0 +1,120 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const rescoreStart = int64(1514764800)
+
+func newTestRescorer(t *testing.T) *Rescorer {
+	eventRepository := repository.NewMemoryEventsRepository()
+	newEvent := func(username string, timestamp int64, ip string) *models.Event {
+		event, err := models.NewEvent(models.EventInfo{UUID: uuid.New().String(), Username: username,
+			Timestamp: timestamp, IP: ip})
+		require.NoError(t, err)
+
+		return event
+	}
+
+	_, err := eventRepository.InsertEvents([]*models.Event{
+		// new york to los angeles in 3 hours and back in 7
+		newEvent("bob", rescoreStart, "1.0.0.0"),
+		newEvent("bob", rescoreStart+3*3600, "2.0.0.0"),
+		newEvent("bob", rescoreStart+10*3600, "1.0.0.0"),
+		// new york to los angeles in an hour, then an unknown location
+		newEvent("alice", rescoreStart, "1.0.0.0"),
+		newEvent("alice", rescoreStart+3600, "2.0.0.0"),
+		newEvent("alice", rescoreStart+2*3600, "3.0.0.0"),
+	})
+	require.NoError(t, err)
+
+	ipGeoInfoRepository := MockIPGeoInfoRepository{geoMap: map[string]*models.GeoPoint{
+		"1.0.0.0": {Latitude: 40.7128, Longitude: -74.0060},
+		"2.0.0.0": {Latitude: 34.0522, Longitude: -118.2437},
+	}}
+
+	return NewRescorer(eventRepository, ipGeoInfoRepository, DefaultCalculatorService{})
+}
+
+func TestRescorer_Reports_New_Alerts_Of_Lower_Speed(t *testing.T) {
+	req := require.New(t)
+	rescorer := newTestRescorer(t)
+
+	report, err := rescorer.Rescore(models.DetectionParameters{SuspiciousSpeed: 500},
+		models.DetectionParameters{SuspiciousSpeed: 200}, "")
+	req.NoError(err)
+	req.Equal(int64(2), report.Users)
+	req.Equal(int64(6), report.Events)
+	req.Equal(int64(3), report.Pairs)
+	req.Equal(int64(1), report.UnknownLocations)
+	req.Equal(int64(2), report.BaselineAlerts)
+	req.Equal(int64(3), report.CandidateAlerts)
+	req.Empty(report.RemovedAlerts)
+	req.False(report.Truncated)
+
+	req.Len(report.NewAlerts, 1)
+	req.Equal("bob", report.NewAlerts[0].Username)
+	req.Equal("2.0.0.0", report.NewAlerts[0].FromIP)
+	req.Equal(rescoreStart+3*3600, report.NewAlerts[0].FromTimestamp)
+	req.Equal("1.0.0.0", report.NewAlerts[0].ToIP)
+	req.Equal(rescoreStart+10*3600, report.NewAlerts[0].ToTimestamp)
+
+	req.Equal([]*models.RescoreUserCounts{
+		{Username: "bob", BaselineAlerts: 1, CandidateAlerts: 2, NewAlerts: 1},
+	}, report.ChangedUsers)
+}
+
+func TestRescorer_Reports_Removed_Alerts_Of_One_User(t *testing.T) {
+	req := require.New(t)
+	rescorer := newTestRescorer(t)
+
+	report, err := rescorer.Rescore(models.DetectionParameters{SuspiciousSpeed: 500},
+		models.DetectionParameters{SuspiciousSpeed: 1000}, "bob")
+	req.NoError(err)
+	req.Equal(int64(1), report.Users)
+	req.Equal(int64(3), report.Events)
+	req.Equal(int64(1), report.BaselineAlerts)
+	req.Equal(int64(0), report.CandidateAlerts)
+	req.Empty(report.NewAlerts)
+	req.Len(report.RemovedAlerts, 1)
+	req.Equal("1.0.0.0", report.RemovedAlerts[0].FromIP)
+	req.Equal([]*models.RescoreUserCounts{
+		{Username: "bob", BaselineAlerts: 1, RemovedAlerts: 1},
+	}, report.ChangedUsers)
+}
+
+func TestRescorer_Rejects_Invalid_Parameters(t *testing.T) {
+	_, err := newTestRescorer(t).Rescore(models.DetectionParameters{SuspiciousSpeed: 500},
+		models.DetectionParameters{SuspiciousSpeed: 0}, "")
+	require.Error(t, err)
+}
+
+func TestRescoreJobs_Run_In_Background(t *testing.T) {
+	req := require.New(t)
+	rescoreJobs := NewRescoreJobs(newTestRescorer(t))
+
+	_, err := rescoreJobs.Start(models.DetectionParameters{SuspiciousSpeed: 500},
+		models.DetectionParameters{SuspiciousSpeed: -1}, "")
+	req.Error(err)
+
+	job, err := rescoreJobs.Start(models.DetectionParameters{SuspiciousSpeed: 500},
+		models.DetectionParameters{SuspiciousSpeed: 200}, "")
+	req.NoError(err)
+	req.Equal(models.RescoreStatusRunning, job.Status)
+	req.Nil(rescoreJobs.Find("unknown"))
+
+	req.Eventually(func() bool {
+		return rescoreJobs.Find(job.ID).Status != models.RescoreStatusRunning
+	}, 5*time.Second, 10*time.Millisecond)
+
+	finished := rescoreJobs.Find(job.ID)
+	req.Equal(models.RescoreStatusDone, finished.Status)
+	req.Len(finished.Report.NewAlerts, 1)
+	req.Equal(200.0, finished.Candidate.SuspiciousSpeed)
+}
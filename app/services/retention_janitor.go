@@ -0,0 +1,240 @@
+package services
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// deletes the events past their retention in the background, a batch at a time so the event db is never locked
+// for long, and what was derived from them. the latest event of every user is kept
+type RetentionJanitor struct {
+	repository        core.EventRetentionRepository
+	derivedRepository core.DerivedRetentionRepository
+	policy            models.RetentionPolicy
+	batchSize         int
+	interval          time.Duration
+	archiveDir        string
+	vacuum            bool
+	now               func() time.Time
+	stop              chan struct{}
+	waitGroup         sync.WaitGroup
+}
+
+func NewRetentionJanitor(repository core.EventRetentionRepository,
+	derivedRepository core.DerivedRetentionRepository,
+	retentionConfig config.RetentionConfig) (*RetentionJanitor, error) {
+	tenantDays, err := models.ParseTenantRetention(retentionConfig.TenantDays)
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize, interval := 500, time.Hour
+
+	if retentionConfig.BatchSize > 0 {
+		batchSize = retentionConfig.BatchSize
+	}
+
+	if retentionConfig.IntervalMinutes > 0 {
+		interval = time.Duration(retentionConfig.IntervalMinutes) * time.Minute
+	}
+
+	var archiveDir string
+	if retentionConfig.Archive {
+		archiveDir = support.Resolve(retentionConfig.ArchiveDir)
+	}
+
+	return &RetentionJanitor{
+		repository:        repository,
+		derivedRepository: derivedRepository,
+		policy:            models.RetentionPolicy{Days: retentionConfig.Days, TenantDays: tenantDays},
+		batchSize:         batchSize,
+		interval:          interval,
+		archiveDir:        archiveDir,
+		vacuum:            retentionConfig.Vacuum,
+		now:               time.Now,
+		stop:              make(chan struct{}),
+	}, nil
+}
+
+// purges right away and then on every interval
+func (janitor *RetentionJanitor) Start() {
+	janitor.waitGroup.Add(1)
+
+	go func() {
+		defer janitor.waitGroup.Done()
+
+		ticker := time.NewTicker(janitor.interval)
+		defer ticker.Stop()
+
+		for {
+			if purged, err := janitor.Purge(); err != nil {
+				log.Printf(support.Error, err)
+			} else if purged > 0 {
+				log.Printf(support.Info, fmt.Sprintf("purged %d events past their retention", purged))
+			}
+
+			select {
+			case <-janitor.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stops the janitor and waits for a running purge to finish
+func (janitor *RetentionJanitor) Stop() {
+	close(janitor.stop)
+	janitor.waitGroup.Wait()
+}
+
+// deletes every event past its retention and the data derived from them, returns the number of events deleted
+func (janitor *RetentionJanitor) Purge() (purged int64, err error) {
+	archive := &eventArchive{dir: janitor.archiveDir, now: janitor.now}
+
+	defer func() {
+		if closeErr := archive.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for _, rule := range janitor.policy.Rules(janitor.now()) {
+		for {
+			select {
+			case <-janitor.stop:
+				return purged, nil
+			default:
+			}
+
+			deleted, more, err := janitor.purgeBatch(rule, archive)
+			purged += deleted
+
+			if err != nil {
+				return purged, err
+			}
+
+			if !more {
+				break
+			}
+		}
+
+		derived, err := janitor.derivedRepository.PurgeDerivedData(rule)
+		if err != nil {
+			return purged, err
+		}
+
+		if derived > 0 {
+			log.Printf(support.Info, fmt.Sprintf("purged %d rows derived from events past their retention", derived))
+		}
+	}
+
+	if purged > 0 && janitor.vacuum {
+		if err := janitor.repository.Vacuum(); err != nil {
+			return purged, err
+		}
+	}
+
+	return purged, nil
+}
+
+// returns whether a full batch was purged, as more events are then likely to be expired
+func (janitor *RetentionJanitor) purgeBatch(rule models.RetentionRule, archive *eventArchive) (int64, bool,
+	error) {
+	events, err := janitor.repository.FindExpiredEvents(rule, janitor.batchSize)
+	if err != nil || len(events) == 0 {
+		return 0, false, err
+	}
+
+	if err := archive.Write(events); err != nil {
+		return 0, false, err
+	}
+
+	uuids := make([]string, 0, len(events))
+	for _, event := range events {
+		uuids = append(uuids, event.ToEventInfo().UUID)
+	}
+
+	deleted, err := janitor.repository.DeleteEvents(uuids)
+	if err != nil {
+		return 0, false, err
+	}
+
+	support.AddToCounter(support.PurgedEvents, deleted)
+
+	return deleted, len(events) == janitor.batchSize, nil
+}
+
+// gzip compressed NDJSON file of purged events, created on the first write. without a dir nothing is archived
+type eventArchive struct {
+	dir     string
+	now     func() time.Time
+	file    *os.File
+	writer  *gzip.Writer
+	encoder *json.Encoder
+}
+
+func (archive *eventArchive) Write(events []*models.Event) error {
+	if archive.dir == "" {
+		return nil
+	}
+
+	if archive.encoder == nil {
+		if err := os.MkdirAll(archive.dir, 0750); err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("events-%s.ndjson.gz", archive.now().UTC().Format("20060102T150405.000000000Z"))
+
+		file, err := os.OpenFile(filepath.Join(archive.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+		if err != nil {
+			return err
+		}
+
+		archive.file = file
+		archive.writer = gzip.NewWriter(file)
+		archive.encoder = json.NewEncoder(archive.writer)
+	}
+
+	for _, event := range events {
+		if err := archive.encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+
+	// flushed before the events are deleted, so they are on disk should the process die
+	if err := archive.writer.Flush(); err != nil {
+		return err
+	}
+
+	if err := archive.file.Sync(); err != nil {
+		return err
+	}
+
+	support.AddToCounter(support.ArchivedEvents, int64(len(events)))
+
+	return nil
+}
+
+func (archive *eventArchive) Close() error {
+	if archive.file == nil {
+		return nil
+	}
+
+	if err := archive.writer.Close(); err != nil {
+		_ = archive.file.Close()
+		return err
+	}
+
+	return archive.file.Close()
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/repository"
+	"github.com/frankiennamdi/detection-api/support"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// records the rules it was asked to purge the derived data of
+type RecordingDerivedRetentionRepository struct {
+	rules []models.RetentionRule
+}
+
+func (recordingRepository *RecordingDerivedRetentionRepository) PurgeDerivedData(
+	rule models.RetentionRule) (int64, error) {
+	recordingRepository.rules = append(recordingRepository.rules, rule)
+	return 0, nil
+}
+
+func TestRetentionJanitor_Purges_Expired_Events_And_Archives_Them(t *testing.T) {
+	req := require.New(t)
+	archiveDir := support.NewTemporaryDir("", "retention-archive-test")
+
+	defer archiveDir.Clean()
+
+	now := time.Unix(1514764800, 0)
+	eventRepository := repository.NewMemoryEventsRepository()
+
+	var events []*models.Event
+
+	for _, username := range []string{"john@example.com", "jane@example.org"} {
+		for days := 0; days < 10; days++ {
+			events = append(events, newEvent(models.EventInfo{
+				UUID:      uuid.New().String(),
+				Username:  username,
+				Timestamp: now.Add(-time.Duration(days) * 24 * time.Hour).Add(-time.Minute).Unix(),
+				IP:        "1.0.0.0",
+			}))
+		}
+	}
+
+	// only old events for this user, the latest must survive
+	events = append(events, newEvent(models.EventInfo{UUID: uuid.New().String(), Username: "bob",
+		Timestamp: now.Add(-100 * 24 * time.Hour).Unix(), IP: "1.0.0.0"}))
+
+	_, err := eventRepository.InsertEvents(events)
+	req.NoError(err)
+
+	derivedRepository := &RecordingDerivedRetentionRepository{}
+	janitor, err := NewRetentionJanitor(eventRepository, derivedRepository, config.RetentionConfig{
+		Enabled:    true,
+		Days:       5,
+		TenantDays: "example.org:2",
+		BatchSize:  2,
+		Archive:    true,
+		ArchiveDir: archiveDir.Path(),
+		Vacuum:     true,
+	})
+	req.NoError(err)
+
+	janitor.now = func() time.Time { return now }
+	purgedBefore := support.CounterValue(support.PurgedEvents)
+
+	purged, err := janitor.Purge()
+	req.NoError(err)
+	req.Equal(int64(5+8), purged)
+	req.Equal(purged, support.CounterValue(support.PurgedEvents)-purgedBefore)
+	req.Equal(models.RetentionPolicy{Days: 5, TenantDays: map[string]int{"example.org": 2}}.Rules(now),
+		derivedRepository.rules)
+
+	remaining, err := eventRepository.FindExpiredEvents(models.RetentionRule{Before: now.Unix()}, 100)
+	req.NoError(err)
+	req.Len(remaining, 5-1+2-1)
+
+	archives, err := filepath.Glob(filepath.Join(archiveDir.Path(), "events-*.ndjson.gz"))
+	req.NoError(err)
+	req.Len(archives, 1)
+	req.Len(readArchive(t, archives[0]), int(purged))
+
+	purged, err = janitor.Purge()
+	req.NoError(err)
+	req.Zero(purged)
+}
+
+func TestNewRetentionJanitor_Rejects_Invalid_Tenant_Retention(t *testing.T) {
+	_, err := NewRetentionJanitor(repository.NewMemoryEventsRepository(), &RecordingDerivedRetentionRepository{},
+		config.RetentionConfig{Days: 5, TenantDays: "example.com"})
+	require.Error(t, err)
+}
+
+func readArchive(t *testing.T, path string) []models.EventInfo {
+	req := require.New(t)
+
+	file, err := os.Open(path)
+	req.NoError(err)
+
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	req.NoError(err)
+
+	var eventInfos []models.EventInfo
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var eventInfo models.EventInfo
+		req.NoError(json.Unmarshal(scanner.Bytes(), &eventInfo))
+		eventInfos = append(eventInfos, eventInfo)
+	}
+
+	req.NoError(scanner.Err())
+
+	return eventInfos
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// detection service that adds the risk of the travels found by the detection service it wraps to the rolling risk
+// of the user, and returns both with the result
+type RiskScoringDetectionService struct {
+	detectionService   core.DetectionService
+	userRiskRepository core.UserRiskRepository
+	calculatorService  core.CalculatorService
+	parameters         *LiveDetectionParameters
+	halfLife           time.Duration
+}
+
+func NewRiskScoringDetectionService(detectionService core.DetectionService,
+	userRiskRepository core.UserRiskRepository,
+	calculatorService core.CalculatorService,
+	parameters *LiveDetectionParameters,
+	halfLife time.Duration) *RiskScoringDetectionService {
+	return &RiskScoringDetectionService{
+		detectionService:   detectionService,
+		userRiskRepository: userRiskRepository,
+		calculatorService:  calculatorService,
+		parameters:         parameters,
+		halfLife:           halfLife,
+	}
+}
+
+// a failure to score the risk is logged, the result of the detection is still returned without the risk. the
+// travels are added to the user once, so processing an event again leaves the risk of the user as it is
+func (service RiskScoringDetectionService) ProcessEvent(
+	currEvent *models.Event) (*models.SuspiciousTravelResult, error) {
+	result, err := service.detectionService.ProcessEvent(currEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	eventInfo := currEvent.ToEventInfo()
+
+	travelRisks, err := service.TravelRisks(eventInfo.Timestamp, result)
+	if err != nil {
+		log.Printf(support.Error, err)
+		return result, nil
+	}
+
+	var userRisk *models.UserRisk
+	if len(travelRisks) > 0 {
+		userRisk, err = service.userRiskRepository.AddUserRisk(eventInfo.Username, travelRisks,
+			supersededTravel(result), service.halfLife)
+	} else {
+		userRisk, err = service.userRiskRepository.FindUserRisk(eventInfo.Username)
+	}
+
+	if err != nil {
+		log.Printf(support.Error, err)
+		return result, nil
+	}
+
+	result.Risk = &models.RiskResult{}
+	for _, travelRisk := range travelRisks {
+		result.Risk.EventScore += travelRisk.Score
+	}
+
+	if userRisk != nil {
+		result.Risk.UserScore = userRisk.Score
+		result.Risk.UserRuleHits = userRisk.RuleHits
+	}
+
+	return result, nil
+}
+
+// the risk of the travels to and from the location of the event at timestamp, the travel to it first
+func (service RiskScoringDetectionService) TravelRisks(timestamp int64,
+	result *models.SuspiciousTravelResult) ([]models.TravelRisk, error) {
+	if result == nil || result.CurrentGeo == nil {
+		return nil, nil
+	}
+
+	suspiciousSpeed := service.parameters.Get().SuspiciousSpeed
+	travels := []struct {
+		access     *models.RelatedAccessInfo
+		suspicious *bool
+		subsequent bool
+	}{
+		{result.PrecedingIPAccess, result.TravelToCurrentGeoSuspicious, false},
+		{result.SubsequentIPAccess, result.TravelFromCurrentGeoSuspicious, true},
+	}
+
+	var travelRisks []models.TravelRisk
+
+	for _, travel := range travels {
+		if travel.access == nil {
+			continue
+		}
+
+		distance, err := service.calculatorService.HaversineDistance(result.CurrentGeo,
+			&models.GeoPoint{Latitude: travel.access.Latitude, Longitude: travel.access.Longitude})
+		if err != nil {
+			return nil, err
+		}
+
+		// the threshold of the band of the travel, when the detection reported one
+		threshold := suspiciousSpeed
+		if travel.access.Threshold > 0 {
+			threshold = travel.access.Threshold
+		}
+
+		eventRisk := &models.EventRisk{}
+		eventRisk.AddTravel(travel.access.Speed, distance.Miles(), threshold,
+			travel.suspicious != nil && *travel.suspicious)
+
+		travelRisk := models.TravelRisk{From: travel.access.Timestamp, To: timestamp, Score: eventRisk.Score,
+			RuleHits: eventRisk.RuleHits}
+		if travel.subsequent {
+			travelRisk.From, travelRisk.To = timestamp, travel.access.Timestamp
+		}
+
+		travelRisks = append(travelRisks, travelRisk)
+	}
+
+	return travelRisks, nil
+}
+
+// the travel from the preceding to the subsequent event, which an event arriving between them replaces with its
+// own two
+func supersededTravel(result *models.SuspiciousTravelResult) *models.TravelRisk {
+	if result == nil || result.PrecedingIPAccess == nil || result.SubsequentIPAccess == nil {
+		return nil
+	}
+
+	return &models.TravelRisk{From: result.PrecedingIPAccess.Timestamp, To: result.SubsequentIPAccess.Timestamp}
+}
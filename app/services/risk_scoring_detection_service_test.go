@@ -0,0 +1,113 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/repository"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRiskScoringDetectionService_Scores_Travels_Of_User(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	ipGeoInfoRepository := MockIPGeoInfoRepository{geoMap: map[string]*models.GeoPoint{
+		"1.0.0.0": {Latitude: 40.7128, Longitude: -74.0060},
+		"2.0.0.0": {Latitude: 34.0522, Longitude: -118.2437},
+	}}
+	userRiskRepository := repository.NewSQLLiteUserRiskRepository(testSetup.AppServerContext().EventDb())
+	parameters := NewLiveDetectionParameters(models.DetectionParameters{SuspiciousSpeed: 500})
+	service := NewRiskScoringDetectionService(
+		NewLiveDetectionService(repository.NewMemoryEventsRepository(), ipGeoInfoRepository,
+			DefaultCalculatorService{}, parameters),
+		userRiskRepository, DefaultCalculatorService{}, parameters, 24*time.Hour)
+
+	processEvent := func(timestamp int64, ip string) *models.RiskResult {
+		event, err := models.NewEvent(models.EventInfo{UUID: uuid.New().String(), Username: "bob",
+			Timestamp: timestamp, IP: ip})
+		req.NoError(err)
+
+		result, err := service.ProcessEvent(event)
+		req.NoError(err)
+		req.NotNil(result.Risk)
+
+		return result.Risk
+	}
+
+	// new york, los angeles an hour later and new york again after twenty hours
+	req.Equal(&models.RiskResult{}, processEvent(rescoreStart, "1.0.0.0"))
+
+	impossible := processEvent(rescoreStart+3600, "2.0.0.0")
+	req.True(impossible.EventScore > models.RiskRuleHitWeight)
+	req.Equal(impossible.EventScore, impossible.UserScore)
+	req.Equal(int64(1), impossible.UserRuleHits)
+
+	plausible := processEvent(rescoreStart+20*3600, "1.0.0.0")
+	req.Equal(0.0, plausible.EventScore)
+	req.True(plausible.UserScore > 0 && plausible.UserScore < impossible.UserScore)
+	req.Equal(int64(1), plausible.UserRuleHits)
+
+	userRisk, err := userRiskRepository.FindUserRisk("bob")
+	req.NoError(err)
+	req.Equal(plausible.UserScore, userRisk.Score)
+	req.Equal(int64(2), userRisk.Travels)
+}
+
+func TestRiskScoringDetectionService_Scores_Travels_Once(t *testing.T) {
+	testSetup := test.SetUp()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	ipGeoInfoRepository := MockIPGeoInfoRepository{geoMap: map[string]*models.GeoPoint{
+		"1.0.0.0": {Latitude: 40.7128, Longitude: -74.0060},
+		"2.0.0.0": {Latitude: 34.0522, Longitude: -118.2437},
+	}}
+	userRiskRepository := repository.NewSQLLiteUserRiskRepository(testSetup.AppServerContext().EventDb())
+	parameters := NewLiveDetectionParameters(models.DetectionParameters{SuspiciousSpeed: 500})
+	service := NewRiskScoringDetectionService(
+		NewLiveDetectionService(repository.NewMemoryEventsRepository(), ipGeoInfoRepository,
+			DefaultCalculatorService{}, parameters),
+		userRiskRepository, DefaultCalculatorService{}, parameters, 24*time.Hour)
+
+	newEvent := func(timestamp int64, ip string) *models.Event {
+		event, err := models.NewEvent(models.EventInfo{UUID: uuid.New().String(), Username: "bob",
+			Timestamp: timestamp, IP: ip})
+		req.NoError(err)
+
+		return event
+	}
+
+	processEvent := func(event *models.Event) *models.RiskResult {
+		result, err := service.ProcessEvent(event)
+		req.NoError(err)
+		req.NotNil(result.Risk)
+
+		return result.Risk
+	}
+
+	// new york twice, two hours apart
+	processEvent(newEvent(rescoreStart, "1.0.0.0"))
+	processEvent(newEvent(rescoreStart+2*3600, "1.0.0.0"))
+
+	// los angeles in between arrives late, it replaces the travel between the new york events with two impossible
+	// ones
+	late := newEvent(rescoreStart+3600, "2.0.0.0")
+	scored := processEvent(late)
+	req.True(scored.EventScore > 2*models.RiskRuleHitWeight)
+	req.Equal(int64(2), scored.UserRuleHits)
+
+	// the same event again, as resubmitted or replayed, adds nothing
+	replayed := processEvent(late)
+	req.Equal(scored, replayed)
+
+	userRisk, err := userRiskRepository.FindUserRisk("bob")
+	req.NoError(err)
+	req.Equal(scored.UserScore, userRisk.Score)
+	req.Equal(int64(2), userRisk.RuleHits)
+	req.Equal(int64(2), userRisk.Travels)
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"log"
+	"net"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// detection service that returns the other sessions of the user open at the time of an event found by the
+// detection service it wraps, when they are far from the location of the event. only events that carry a session
+// and do not end it are checked
+type SessionDetectionService struct {
+	detectionService    core.DetectionService
+	eventRepository     core.EventExportRepository
+	ipGeoInfoRepository core.IPGeoInfoRepository
+	calculatorService   core.CalculatorService
+	sessionConfig       config.SessionConfig
+}
+
+func NewSessionDetectionService(detectionService core.DetectionService,
+	eventRepository core.EventExportRepository,
+	ipGeoInfoRepository core.IPGeoInfoRepository,
+	calculatorService core.CalculatorService,
+	sessionConfig config.SessionConfig) *SessionDetectionService {
+	return &SessionDetectionService{
+		detectionService:    detectionService,
+		eventRepository:     eventRepository,
+		ipGeoInfoRepository: ipGeoInfoRepository,
+		calculatorService:   calculatorService,
+		sessionConfig:       sessionConfig,
+	}
+}
+
+// a failure to find the sessions is logged, the result of the detection is still returned without them
+func (service SessionDetectionService) ProcessEvent(
+	currEvent *models.Event) (*models.SuspiciousTravelResult, error) {
+	result, err := service.detectionService.ProcessEvent(currEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	eventInfo := currEvent.ToEventInfo()
+	if result.CurrentGeo == nil || eventInfo.SessionID == "" || eventInfo.EndsSession() {
+		return result, nil
+	}
+
+	sessions, err := service.FindConcurrentSessions(currEvent, result.CurrentGeo)
+	if err != nil {
+		log.Printf(support.Error, err)
+		return result, nil
+	}
+
+	result.ConcurrentSessions = sessions
+
+	return result, nil
+}
+
+// the other sessions of the user open at the time of the event, idle for no more than the configured minutes,
+// whose latest location is at least the configured miles from the location of the event
+func (service SessionDetectionService) FindConcurrentSessions(currEvent *models.Event,
+	currGeo *models.GeoPoint) ([]*models.ConcurrentSession, error) {
+	eventInfo := currEvent.ToEventInfo()
+	query := models.ExportQuery{
+		Username: eventInfo.Username,
+		From:     eventInfo.Timestamp - int64(service.sessionConfig.IdleMinutes)*60,
+		To:       eventInfo.Timestamp + 1,
+	}
+
+	var events []*models.Event
+
+	if err := service.eventRepository.ExportEvents(query, func(event *models.Event) error {
+		events = append(events, event)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var sessions []*models.ConcurrentSession
+
+	for _, event := range models.OpenSessions(events, eventInfo.SessionID) {
+		sessionEventInfo := event.ToEventInfo()
+
+		geoPoint, err := service.ipGeoInfoRepository.FindGeoPoint(net.ParseIP(sessionEventInfo.IP))
+		if err != nil {
+			return nil, err
+		}
+
+		if geoPoint == nil {
+			continue
+		}
+
+		distance, err := service.calculatorService.HaversineDistance(currGeo, geoPoint)
+		if err != nil {
+			return nil, err
+		}
+
+		if distance.Miles() < service.sessionConfig.MinMiles {
+			continue
+		}
+
+		sessions = append(sessions, &models.ConcurrentSession{
+			SessionID: sessionEventInfo.SessionID,
+			IP:        sessionEventInfo.IP,
+			Latitude:  geoPoint.Latitude,
+			Longitude: geoPoint.Longitude,
+			Timestamp: sessionEventInfo.Timestamp,
+			Miles:     distance.Miles(),
+		})
+	}
+
+	return sessions, nil
+}
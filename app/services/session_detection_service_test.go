@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionDetectionService_Flags_Open_Session_In_Distant_Location(t *testing.T) {
+	req := require.New(t)
+	eventRepository := repository.NewMemoryEventsRepository()
+	ipGeoInfoRepository := MockIPGeoInfoRepository{geoMap: map[string]*models.GeoPoint{
+		"1.0.0.0": {Latitude: 40.7128, Longitude: -74.0060},
+		"1.0.0.1": {Latitude: 40.7306, Longitude: -73.9352},
+		"2.0.0.0": {Latitude: 34.0522, Longitude: -118.2437},
+	}}
+	service := NewSessionDetectionService(
+		NewDetectionService(eventRepository, ipGeoInfoRepository, DefaultCalculatorService{}, 500),
+		eventRepository, ipGeoInfoRepository, DefaultCalculatorService{},
+		config.SessionConfig{IdleMinutes: 30, MinMiles: 100})
+
+	processEvent := func(minute int64, ip, sessionID, eventType string) []*models.ConcurrentSession {
+		event, err := models.NewEvent(models.EventInfo{UUID: uuid.New().String(), Username: "bob",
+			Timestamp: rescoreStart + minute*60, IP: ip, SessionID: sessionID, Type: eventType})
+		req.NoError(err)
+
+		result, err := service.ProcessEvent(event)
+		req.NoError(err)
+
+		return result.ConcurrentSessions
+	}
+
+	req.Empty(processEvent(0, "1.0.0.0", "ny", ""))
+	// a second session nearby is not flagged
+	req.Empty(processEvent(5, "1.0.0.1", "ny-phone", ""))
+
+	sessions := processEvent(10, "2.0.0.0", "la", "")
+	req.Len(sessions, 2)
+	req.Equal("ny", sessions[0].SessionID)
+	req.Equal("ny-phone", sessions[1].SessionID)
+	req.Equal(rescoreStart+5*60, sessions[1].Timestamp)
+	req.Greater(sessions[0].Miles, 2000.0)
+
+	// the new york sessions end with a logout and by idling
+	req.Empty(processEvent(12, "1.0.0.1", "ny-phone", models.EventTypeLogout))
+	req.Len(processEvent(20, "2.0.0.0", "la", ""), 1)
+	req.Empty(processEvent(31, "2.0.0.0", "la", ""))
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/repository"
+	"github.com/google/uuid"
+)
+
+// events processed by a single simulation
+const MaxSimulationEvents = 1000
+
+// the username of simulated events that have none
+const SimulationUsername = "simulation"
+
+// runs a sequence of events through the detection with the given parameters against an event store of its own, so
+// nothing is read from or written to the stored events
+type Simulator struct {
+	ipGeoInfoRepository core.IPGeoInfoRepository
+	calculatorService   core.CalculatorService
+}
+
+func NewSimulator(ipGeoInfoRepository core.IPGeoInfoRepository, calculatorService core.CalculatorService) *Simulator {
+	return &Simulator{ipGeoInfoRepository: ipGeoInfoRepository, calculatorService: calculatorService}
+}
+
+// processes the events in the given order. events without a uuid or a username get a random uuid and the
+// simulation username, an event the detection cannot process is reported in its step and the simulation goes on
+func (simulator *Simulator) Simulate(parameters models.DetectionParameters,
+	eventInfos []models.EventInfo) (*models.SimulationResult, error) {
+	if err := parameters.Validate(); err != nil {
+		return nil, err
+	}
+
+	if len(eventInfos) == 0 || len(eventInfos) > MaxSimulationEvents {
+		return nil, models.NewValidationError(fmt.Sprintf("%d events", len(eventInfos)), "events")
+	}
+
+	events := make([]*models.Event, len(eventInfos))
+
+	for i, eventInfo := range eventInfos {
+		if eventInfo.UUID == "" {
+			eventInfo.UUID = uuid.New().String()
+		}
+
+		if eventInfo.Username == "" {
+			eventInfo.Username = SimulationUsername
+		}
+
+		event, err := models.NewEvent(eventInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		events[i] = event
+	}
+
+	detectionService := NewLiveDetectionService(repository.NewMemoryEventsRepository(),
+		simulator.ipGeoInfoRepository, simulator.calculatorService, NewLiveDetectionParameters(parameters))
+	result := &models.SimulationResult{Parameters: parameters, Timeline: make([]*models.SimulationStep, len(events))}
+
+	for i, event := range events {
+		step := &models.SimulationStep{Event: event.ToEventInfo()}
+
+		suspiciousTravelResult, err := detectionService.ProcessEvent(event)
+		if err != nil {
+			step.Error = err.Error()
+		} else {
+			step.Result = suspiciousTravelResult
+		}
+
+		result.Timeline[i] = step
+	}
+
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSimulator() *Simulator {
+	return NewSimulator(MockIPGeoInfoRepository{geoMap: map[string]*models.GeoPoint{
+		"1.0.0.0": {Latitude: 40.7128, Longitude: -74.0060},
+		"2.0.0.0": {Latitude: 34.0522, Longitude: -118.2437},
+	}}, DefaultCalculatorService{})
+}
+
+func TestSimulator_Returns_Verdict_Timeline(t *testing.T) {
+	req := require.New(t)
+
+	// new york, los angeles 3 hours later, then two events between them that arrive late, the first from an
+	// unknown location
+	result, err := newTestSimulator().Simulate(models.DetectionParameters{SuspiciousSpeed: 500}, []models.EventInfo{
+		{Timestamp: 1514764800, IP: "1.0.0.0"},
+		{Timestamp: 1514775600, IP: "2.0.0.0"},
+		{Timestamp: 1514768400, IP: "3.0.0.0"},
+		{Timestamp: 1514772000, IP: "1.0.0.0"},
+	})
+	req.NoError(err)
+	req.Equal(500.0, result.Parameters.SuspiciousSpeed)
+	req.Len(result.Timeline, 4)
+
+	for _, step := range result.Timeline {
+		req.Equal(SimulationUsername, step.Event.Username)
+		req.NotEmpty(step.Event.UUID)
+	}
+
+	first := result.Timeline[0].Result
+	req.Nil(first.PrecedingIPAccess)
+	req.Nil(first.SubsequentIPAccess)
+
+	second := result.Timeline[1].Result
+	req.True(*second.TravelToCurrentGeoSuspicious)
+	req.Equal("1.0.0.0", second.PrecedingIPAccess.IP)
+
+	req.Nil(result.Timeline[2].Result)
+	req.NotEmpty(result.Timeline[2].Error)
+
+	fourth := result.Timeline[3].Result
+	req.Nil(fourth.TravelToCurrentGeoSuspicious)
+	req.True(*fourth.TravelFromCurrentGeoSuspicious)
+	req.Equal("2.0.0.0", fourth.SubsequentIPAccess.IP)
+}
+
+func TestSimulator_Applies_Parameters(t *testing.T) {
+	req := require.New(t)
+	events := []models.EventInfo{
+		{Username: "bob", Timestamp: 1514764800, IP: "1.0.0.0"},
+		{Username: "bob", Timestamp: 1514775600, IP: "2.0.0.0"},
+	}
+
+	result, err := newTestSimulator().Simulate(models.DetectionParameters{SuspiciousSpeed: 1000}, events)
+	req.NoError(err)
+	req.Equal("bob", result.Timeline[1].Event.Username)
+	req.False(*result.Timeline[1].Result.TravelToCurrentGeoSuspicious)
+}
+
+func TestSimulator_Rejects_Invalid_Input(t *testing.T) {
+	req := require.New(t)
+	simulator := newTestSimulator()
+	events := []models.EventInfo{{Timestamp: 1514764800, IP: "1.0.0.0"}}
+
+	_, err := simulator.Simulate(models.DetectionParameters{SuspiciousSpeed: 0}, events)
+	req.Error(err)
+
+	_, err = simulator.Simulate(models.DetectionParameters{SuspiciousSpeed: 500}, nil)
+	req.Error(err)
+
+	_, err = simulator.Simulate(models.DetectionParameters{SuspiciousSpeed: 500},
+		[]models.EventInfo{{Timestamp: 1514764800, IP: "not-an-ip"}})
+	req.Error(err)
+
+	_, err = simulator.Simulate(models.DetectionParameters{SuspiciousSpeed: 500},
+		make([]models.EventInfo, MaxSimulationEvents+1))
+	req.Error(err)
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// number of locks the users are spread over
+const userLockShards = 256
+
+// detection service that processes the events of the same user one at a time through the detection service it
+// wraps, while events of different users run concurrently. wrapped around the whole chain of detection services,
+// each event sees every event of the user processed before it, and the risk, location profile and alerts of the
+// user are updated by one event at a time
+type UserSerializedDetectionService struct {
+	detectionService core.DetectionService
+	userLocks        *support.KeyedMutex
+}
+
+func NewUserSerializedDetectionService(detectionService core.DetectionService) *UserSerializedDetectionService {
+	return &UserSerializedDetectionService{
+		detectionService: detectionService,
+		userLocks:        support.NewKeyedMutex(userLockShards),
+	}
+}
+
+func (service UserSerializedDetectionService) ProcessEvent(
+	currEvent *models.Event) (*models.SuspiciousTravelResult, error) {
+	if currEvent == nil {
+		return nil, support.NewIllegalArgumentError("currEvent cannot be nil")
+	}
+
+	unlock := service.userLocks.Lock(currEvent.ToEventInfo().Username)
+	defer unlock()
+
+	return service.detectionService.ProcessEvent(currEvent)
+}
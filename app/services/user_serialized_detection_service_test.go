@@ -0,0 +1,75 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// detection service that records the most events of a user it processed at the same time
+type ConcurrencyRecordingDetectionService struct {
+	inFlight    map[string]int
+	maxInFlight map[string]int
+	mutex       sync.Mutex
+}
+
+func (service *ConcurrencyRecordingDetectionService) ProcessEvent(
+	currEvent *models.Event) (*models.SuspiciousTravelResult, error) {
+	username := currEvent.ToEventInfo().Username
+
+	service.mutex.Lock()
+	service.inFlight[username]++
+	if service.inFlight[username] > service.maxInFlight[username] {
+		service.maxInFlight[username] = service.inFlight[username]
+	}
+	service.mutex.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	service.mutex.Lock()
+	service.inFlight[username]--
+	service.mutex.Unlock()
+
+	return &models.SuspiciousTravelResult{}, nil
+}
+
+func TestUserSerializedDetectionService_Processes_Events_Of_User_One_At_A_Time(t *testing.T) {
+	req := require.New(t)
+	recordingService := &ConcurrencyRecordingDetectionService{inFlight: map[string]int{},
+		maxInFlight: map[string]int{}}
+	detectionService := NewUserSerializedDetectionService(recordingService)
+	waitGroup := sync.WaitGroup{}
+	errs := make(chan error, 100)
+
+	for i := 0; i < 50; i++ {
+		for _, username := range []string{"bob", "mary"} {
+			event := newEvent(models.EventInfo{UUID: uuid.New().String(), Username: username,
+				Timestamp: int64(1514764800 + i), IP: "1.0.0.0"})
+
+			waitGroup.Add(1)
+
+			go func() {
+				defer waitGroup.Done()
+
+				_, err := detectionService.ProcessEvent(event)
+				errs <- err
+			}()
+		}
+	}
+
+	waitGroup.Wait()
+	close(errs)
+
+	for err := range errs {
+		req.NoError(err)
+	}
+
+	req.Equal(map[string]int{"bob": 1, "mary": 1}, recordingService.maxInFlight)
+
+	_, err := detectionService.ProcessEvent(nil)
+	req.Error(err)
+}
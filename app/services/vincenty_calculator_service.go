@@ -0,0 +1,129 @@
+package services
+
+import (
+	"math"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// the WGS-84 ellipsoid, in meters
+const (
+	wgs84SemiMajorAxis = 6378137.0
+	wgs84Flattening    = 1 / 298.257223563
+	wgs84SemiMinorAxis = wgs84SemiMajorAxis * (1 - wgs84Flattening)
+	// the mean radius of the ellipsoid, for the spherical fallback
+	wgs84MeanRadius = 6371008.8
+	metersInKm      = 1000.0
+	metersInMile    = 1609.344
+)
+
+// the iterations of the inverse formula and the change in longitude on the auxiliary sphere it stops at
+const (
+	vincentyMaxIterations = 200
+	vincentyConvergence   = 1e-12
+)
+
+// calculator service that measures distances along the WGS-84 ellipsoid with the inverse formula of Vincenty,
+// accurate to a millimeter where the default sphere can be off by half a percent. the formula does not converge for
+// nearly antipodal points, their distance is the great circle distance on the mean radius instead
+type VincentyCalculatorService struct {
+	DefaultCalculatorService
+}
+
+// the geodesic distance, despite the name the interface gives it
+func (service VincentyCalculatorService) HaversineDistance(fromPoint,
+	toPoint *models.GeoPoint) (*models.GeoDistance, error) {
+	if fromPoint == nil || toPoint == nil {
+		return nil, support.NewIllegalArgumentError("fromPoint or toPoint cannot be nil")
+	}
+
+	meters, ok := vincentyInverse(fromPoint, toPoint)
+	if !ok {
+		meters = greatCircleDistance(fromPoint, toPoint, wgs84MeanRadius)
+	}
+
+	return models.NewGeoDistance(meters/metersInKm, meters/metersInMile), nil
+}
+
+func (service VincentyCalculatorService) SpeedToTravelDistanceInMPH(
+	eventGeoInfoFrom, eventGeoInfoTo *models.EventGeoInfo) (*float64, error) {
+	return speedToTravelDistanceInMPH(service, eventGeoInfoFrom, eventGeoInfoTo)
+}
+
+// the distance in meters between the points on the ellipsoid, false when the formula does not converge
+func vincentyInverse(fromPoint, toPoint *models.GeoPoint) (float64, bool) {
+	f := wgs84Flattening
+	lonDiff := degreesToRadians(toPoint.Longitude - fromPoint.Longitude)
+	// reduced latitudes, on the auxiliary sphere
+	sinU1, cosU1 := math.Sincos(math.Atan((1 - f) * math.Tan(degreesToRadians(fromPoint.Latitude))))
+	sinU2, cosU2 := math.Sincos(math.Atan((1 - f) * math.Tan(degreesToRadians(toPoint.Latitude))))
+
+	lambda := lonDiff
+
+	var sinSigma, cosSigma, sigma, cosSqAlpha, cos2SigmaM float64
+
+	converged := false
+
+	for i := 0; i < vincentyMaxIterations; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+
+		// the same point
+		if sinSigma == 0 {
+			return 0, true
+		}
+
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+
+		// both points on the equator
+		cos2SigmaM = 0
+		if cosSqAlpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+
+		c := f / 16 * cosSqAlpha * (4 + f*(4-3*cosSqAlpha))
+		previousLambda := lambda
+		lambda = lonDiff + (1-c)*f*sinAlpha*
+			(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+
+		if math.Abs(lambda) > math.Pi {
+			return 0, false
+		}
+
+		if math.Abs(lambda-previousLambda) < vincentyConvergence {
+			converged = true
+			break
+		}
+	}
+
+	if !converged {
+		return 0, false
+	}
+
+	uSq := cosSqAlpha * (wgs84SemiMajorAxis*wgs84SemiMajorAxis - wgs84SemiMinorAxis*wgs84SemiMinorAxis) /
+		(wgs84SemiMinorAxis * wgs84SemiMinorAxis)
+	a := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	b := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+	deltaSigma := b * sinSigma * (cos2SigmaM + b/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+		b/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+
+	return wgs84SemiMinorAxis * a * (sigma - deltaSigma), true
+}
+
+// the haversine distance between the points on a sphere of the radius, in the unit of the radius
+func greatCircleDistance(fromPoint, toPoint *models.GeoPoint, radius float64) float64 {
+	latDiff := degreesToRadians(toPoint.Latitude - fromPoint.Latitude)
+	lonDiff := degreesToRadians(toPoint.Longitude - fromPoint.Longitude)
+	h := math.Sin(latDiff/2)*math.Sin(latDiff/2) + math.Cos(degreesToRadians(fromPoint.Latitude))*
+		math.Cos(degreesToRadians(toPoint.Latitude))*math.Sin(lonDiff/2)*math.Sin(lonDiff/2)
+
+	return 2 * radius * math.Atan2(math.Sqrt(h), math.Sqrt(1-h))
+}
+
+func degreesToRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
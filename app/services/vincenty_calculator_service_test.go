@@ -0,0 +1,111 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/stretchr/testify/require"
+)
+
+// published geodesic distances on the WGS-84 ellipsoid, in meters
+var geodesicTestCases = []struct {
+	name           string
+	from           *models.GeoPoint
+	to             *models.GeoPoint
+	expectedMeters float64
+}{
+	{
+		// the worked example of the geodetic calculations of Geoscience Australia
+		"flinders peak to buninyong",
+		&models.GeoPoint{Latitude: -(37 + 57/60.0 + 3.72030/3600), Longitude: 144 + 25/60.0 + 29.52440/3600},
+		&models.GeoPoint{Latitude: -(37 + 39/60.0 + 10.15610/3600), Longitude: 143 + 55/60.0 + 35.38390/3600},
+		54972.271,
+	},
+	{
+		// a degree of the equator, the semi major axis times the angle
+		"one degree of the equator",
+		&models.GeoPoint{Latitude: 0, Longitude: 0},
+		&models.GeoPoint{Latitude: 0, Longitude: 1},
+		111319.491,
+	},
+	{
+		"quarter meridian",
+		&models.GeoPoint{Latitude: 0, Longitude: 0},
+		&models.GeoPoint{Latitude: 90, Longitude: 0},
+		10001965.729,
+	},
+	{
+		// nearly antipodal, the example of Karney, Algorithms for geodesics, 2013
+		"nearly antipodal",
+		&models.GeoPoint{Latitude: 0, Longitude: 0},
+		&models.GeoPoint{Latitude: 0.5, Longitude: 179.5},
+		19936288.579,
+	},
+}
+
+func TestVincentyCalculatorService_Matches_Reference_Distances(t *testing.T) {
+	req := require.New(t)
+	calculator := VincentyCalculatorService{}
+
+	for _, input := range geodesicTestCases {
+		distance, err := calculator.HaversineDistance(input.from, input.to)
+		req.NoError(err)
+		req.InDelta(input.expectedMeters, distance.Km()*1000, 0.001, input.name)
+		req.InDelta(input.expectedMeters/1609.344, distance.Miles(), 0.001, input.name)
+	}
+
+	distance, err := calculator.HaversineDistance(geodesicTestCases[0].from, geodesicTestCases[0].from)
+	req.NoError(err)
+	req.Equal(0.0, distance.Km())
+
+	_, err = calculator.HaversineDistance(nil, geodesicTestCases[0].to)
+	req.Error(err)
+}
+
+func TestVincentyCalculatorService_Falls_Back_For_Antipodal_Points(t *testing.T) {
+	req := require.New(t)
+	from := &models.GeoPoint{Latitude: 0, Longitude: 0}
+	to := &models.GeoPoint{Latitude: 0, Longitude: 180}
+
+	_, converged := vincentyInverse(from, to)
+	req.False(converged)
+
+	// the shortest path runs over the poles, twice the quarter meridian
+	distance, err := VincentyCalculatorService{}.HaversineDistance(from, to)
+	req.NoError(err)
+	req.False(math.IsNaN(distance.Km()))
+	req.InEpsilon(2*10001965.729, distance.Km()*1000, 0.001)
+}
+
+func TestVincentyCalculatorService_Agrees_With_Haversine_Within_Half_A_Percent(t *testing.T) {
+	req := require.New(t)
+
+	for _, input := range testsCases {
+		haversine, err := DefaultCalculatorService{}.HaversineDistance(input.from, input.to)
+		req.NoError(err)
+
+		geodesic, err := VincentyCalculatorService{}.HaversineDistance(input.from, input.to)
+		req.NoError(err)
+		req.InEpsilon(haversine.Km(), geodesic.Km(), 0.005)
+	}
+}
+
+// run with go test -bench Distance ./app/services/...
+func BenchmarkDefaultCalculatorService_Distance(b *testing.B) {
+	benchmarkDistance(b, DefaultCalculatorService{})
+}
+
+func BenchmarkVincentyCalculatorService_Distance(b *testing.B) {
+	benchmarkDistance(b, VincentyCalculatorService{})
+}
+
+func benchmarkDistance(b *testing.B, calculatorService core.CalculatorService) {
+	for i := 0; i < b.N; i++ {
+		input := testsCases[i%len(testsCases)]
+		if _, err := calculatorService.HaversineDistance(input.from, input.to); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"log"
+	"net"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// detection service that checks the travel of the user over a window of their latest events as a whole, for the
+// event found by the detection service it wraps. the travel from and to the nearest events alone misses a path
+// that is only impossible over several hops, such as one through events whose location is not known or one whose
+// hops each pass only within the accuracy of the locations in between
+type WindowDetectionService struct {
+	detectionService    core.DetectionService
+	eventRepository     core.EventExportRepository
+	ipGeoInfoRepository core.IPGeoInfoRepository
+	calculatorService   core.CalculatorService
+	parameters          *LiveDetectionParameters
+	windowConfig        config.WindowConfig
+}
+
+func NewWindowDetectionService(detectionService core.DetectionService,
+	eventRepository core.EventExportRepository,
+	ipGeoInfoRepository core.IPGeoInfoRepository,
+	calculatorService core.CalculatorService,
+	parameters *LiveDetectionParameters,
+	windowConfig config.WindowConfig) *WindowDetectionService {
+	return &WindowDetectionService{
+		detectionService:    detectionService,
+		eventRepository:     eventRepository,
+		ipGeoInfoRepository: ipGeoInfoRepository,
+		calculatorService:   calculatorService,
+		parameters:          parameters,
+		windowConfig:        windowConfig,
+	}
+}
+
+// a failure to check the window is logged, the result of the detection is still returned without the path
+func (service WindowDetectionService) ProcessEvent(
+	currEvent *models.Event) (*models.SuspiciousTravelResult, error) {
+	result, err := service.detectionService.ProcessEvent(currEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.CurrentGeo == nil {
+		return result, nil
+	}
+
+	path, err := service.FindImpossiblePath(currEvent)
+	if err != nil {
+		log.Printf(support.Error, err)
+		return result, nil
+	}
+
+	result.ImpossiblePath = path
+
+	return result, nil
+}
+
+// the longest impossible path through the located events of the window that ends at the event, nil when there is
+// none. the window is the latest events of the user up to the event, no older than the configured hours
+func (service WindowDetectionService) FindImpossiblePath(currEvent *models.Event) (*models.ImpossiblePath, error) {
+	eventInfo := currEvent.ToEventInfo()
+	query := models.ExportQuery{
+		Username: eventInfo.Username,
+		From:     eventInfo.Timestamp - int64(service.windowConfig.Hours)*3600,
+		To:       eventInfo.Timestamp + 1,
+	}
+
+	var window []*models.Event
+
+	err := service.eventRepository.ExportEvents(query, func(event *models.Event) error {
+		if len(window) == service.windowConfig.Events {
+			copy(window, window[1:])
+			window = window[:len(window)-1]
+		}
+
+		window = append(window, event)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	stops, geoPoints, err := service.pathStops(window)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stops) < 2 {
+		return nil, nil
+	}
+
+	// the distance from each stop to the last one
+	directMiles := make([]float64, len(stops))
+
+	for i, geoPoint := range geoPoints[:len(geoPoints)-1] {
+		distance, err := service.calculatorService.HaversineDistance(geoPoint, geoPoints[len(geoPoints)-1])
+		if err != nil {
+			return nil, err
+		}
+
+		directMiles[i] = distance.Miles()
+	}
+
+	return models.FindImpossiblePath(stops, directMiles, service.parameters.Get()), nil
+}
+
+// the events of the window that have a location, each with the distance from the one before, and their locations
+func (service WindowDetectionService) pathStops(window []*models.Event) ([]*models.PathStop, []*models.GeoPoint,
+	error) {
+	stops := make([]*models.PathStop, 0, len(window))
+	geoPoints := make([]*models.GeoPoint, 0, len(window))
+
+	for _, event := range window {
+		eventInfo := event.ToEventInfo()
+
+		geoPoint, err := service.ipGeoInfoRepository.FindGeoPoint(net.ParseIP(eventInfo.IP))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if geoPoint == nil {
+			continue
+		}
+
+		stop := &models.PathStop{IP: eventInfo.IP, Latitude: geoPoint.Latitude, Longitude: geoPoint.Longitude,
+			Radius: geoPoint.AccuracyRadius, Timestamp: eventInfo.Timestamp}
+
+		if len(geoPoints) > 0 {
+			distance, err := service.calculatorService.HaversineDistance(geoPoints[len(geoPoints)-1], geoPoint)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			stop.Miles = distance.Miles()
+		}
+
+		stops = append(stops, stop)
+		geoPoints = append(geoPoints, geoPoint)
+	}
+
+	return stops, geoPoints, nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowDetectionService_Flags_Path_Through_Unknown_Locations(t *testing.T) {
+	req := require.New(t)
+	eventRepository := repository.NewMemoryEventsRepository()
+	ipGeoInfoRepository := MockIPGeoInfoRepository{geoMap: map[string]*models.GeoPoint{
+		"1.0.0.0": {Latitude: 40.7128, Longitude: -74.0060},
+		"2.0.0.0": {Latitude: 34.0522, Longitude: -118.2437},
+	}}
+	service := NewWindowDetectionService(
+		NewDetectionService(eventRepository, ipGeoInfoRepository, DefaultCalculatorService{}, 500),
+		eventRepository, ipGeoInfoRepository, DefaultCalculatorService{},
+		NewLiveDetectionParameters(models.DetectionParameters{SuspiciousSpeed: 500}),
+		config.WindowConfig{Events: 3, Hours: 24})
+
+	// events without a known location are stored, the detection cannot process them
+	insertEvent := func(username string, hour int64, ip string) {
+		event, err := models.NewEvent(models.EventInfo{UUID: uuid.New().String(), Username: username,
+			Timestamp: rescoreStart + hour*3600, IP: ip})
+		req.NoError(err)
+
+		_, err = eventRepository.InsertEvents([]*models.Event{event})
+		req.NoError(err)
+	}
+
+	processEvent := func(username string, hour int64, ip string) *models.SuspiciousTravelResult {
+		event, err := models.NewEvent(models.EventInfo{UUID: uuid.New().String(), Username: username,
+			Timestamp: rescoreStart + hour*3600, IP: ip})
+		req.NoError(err)
+
+		result, err := service.ProcessEvent(event)
+		req.NoError(err)
+
+		return result
+	}
+
+	// new york to los angeles in 2 hours, with a login of unknown location in between
+	req.Nil(processEvent("bob", 0, "1.0.0.0").ImpossiblePath)
+	insertEvent("bob", 1, "3.0.0.0")
+
+	result := processEvent("bob", 2, "2.0.0.0")
+	req.Nil(result.PrecedingIPAccess)
+	req.NotNil(result.ImpossiblePath)
+	req.Len(result.ImpossiblePath.Stops, 2)
+	req.Equal("1.0.0.0", result.ImpossiblePath.Stops[0].IP)
+	req.Equal("2.0.0.0", result.ImpossiblePath.Stops[1].IP)
+	req.Equal(2.0, result.ImpossiblePath.Hours)
+	req.Greater(*result.ImpossiblePath.Speed, 1000.0)
+
+	// the new york login has left the window of the last 3 events
+	insertEvent("bob", 3, "3.0.0.0")
+	req.Nil(processEvent("bob", 4, "2.0.0.0").ImpossiblePath)
+
+	// new york and los angeles 8 hours apart each way is a plausible pace
+	req.Nil(processEvent("alice", 0, "1.0.0.0").ImpossiblePath)
+	req.Nil(processEvent("alice", 8, "2.0.0.0").ImpossiblePath)
+	req.Nil(processEvent("alice", 16, "1.0.0.0").ImpossiblePath)
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/frankiennamdi/detection-api/app/services"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// rest controller for what-if runs of the detection over a sequence of events
+type SimulationController struct {
+	simulator       *services.Simulator
+	parameters      *services.LiveDetectionParameters
+	maxRequestBytes int64
+}
+
+// the parameters override the ones the server runs with
+type simulationRequest struct {
+	Events     []models.EventInfo                 `json:"events"`
+	Parameters models.DetectionParametersOverride `json:"parameters"`
+}
+
+func (controller SimulationController) SimulationHandler(w http.ResponseWriter, r *http.Request) {
+	maxRequestBytes := controller.maxRequestBytes
+	if maxRequestBytes <= 0 {
+		maxRequestBytes = defaultMaxRequestBytes
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBytes)
+
+	var request simulationRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil && isRequestTooLarge(err) {
+		support.IncrementCounter(support.OversizedRequests)
+		errorResponse(w, http.StatusRequestEntityTooLarge, "request body too large")
+
+		return
+	}
+
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, "can pass request body")
+		return
+	}
+
+	result, err := controller.simulator.Simulate(controller.parameters.Get().WithOverride(request.Parameters),
+		request.Events)
+
+	if _, ok := err.(*models.ValidationError); ok {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		log.Printf(support.Error, err)
+		errorResponse(w, http.StatusInternalServerError, "Unable to run simulation")
+
+		return
+	}
+
+	responseJSON(w, http.StatusOK, result)
+}
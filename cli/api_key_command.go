@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/app"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+func init() {
+	register(&Command{
+		Name:        "apikey",
+		Description: "manage api keys: create, list, revoke",
+		Run:         runAPIKeyCommand,
+	})
+}
+
+func runAPIKeyCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: detection-api apikey <create|list|revoke> [arguments]")
+	}
+
+	serviceContext, err := newServiceContext()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		return createAPIKey(serviceContext, args[1:], out)
+	case "list":
+		return listAPIKeys(serviceContext, out)
+	case "revoke":
+		return revokeAPIKey(serviceContext, args[1:], out)
+	default:
+		return fmt.Errorf("unknown apikey command: %s", args[0])
+	}
+}
+
+func createAPIKey(serviceContext *app.ServiceContext, args []string, out io.Writer) error {
+	flagSet := newFlagSet("apikey create")
+	name := flagSet.String("name", "", "name of the client the key is issued to")
+	scopes := flagSet.String("scopes", models.ScopeEventsWrite,
+		fmt.Sprintf("comma separated scopes, any of %s, %s, %s, %s",
+			models.ScopeEventsWrite, models.ScopeEventsRead, models.ScopeAlertsRead, models.ScopeAdmin))
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	parsedScopes, err := models.ParseScopes(*scopes)
+	if err != nil {
+		return err
+	}
+
+	apiKey, rawKey, err := serviceContext.APIKeyService().CreateAPIKey(*name, parsedScopes)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "id: %s\nname: %s\nscopes: %s\nkey: %s\n\nthe key is only shown once, store it safely\n",
+		apiKey.ID, apiKey.Name, strings.Join(apiKey.Scopes, ","), rawKey)
+
+	return err
+}
+
+func listAPIKeys(serviceContext *app.ServiceContext, out io.Writer) error {
+	apiKeys, err := serviceContext.APIKeyService().ListAPIKeys()
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	if _, err := fmt.Fprintln(writer, "ID\tNAME\tSCOPES\tCREATED\tREVOKED"); err != nil {
+		return err
+	}
+
+	for _, apiKey := range apiKeys {
+		if _, err := fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%t\n", apiKey.ID, apiKey.Name,
+			strings.Join(apiKey.Scopes, ","), time.Unix(apiKey.CreatedAt, 0).UTC().Format(time.RFC3339),
+			apiKey.Revoked); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+func revokeAPIKey(serviceContext *app.ServiceContext, args []string, out io.Writer) error {
+	flagSet := newFlagSet("apikey revoke")
+	id := flagSet.String("id", "", "id of the key to revoke")
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	revoked, err := serviceContext.APIKeyService().RevokeAPIKey(*id)
+	if err != nil {
+		return err
+	}
+
+	if !revoked {
+		return fmt.Errorf("no api key with id: %s", *id)
+	}
+
+	_, err = fmt.Fprintf(out, "revoked api key %s\n", *id)
+
+	return err
+}
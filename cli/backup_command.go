@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/frankiennamdi/detection-api/db"
+)
+
+func init() {
+	register(&Command{
+		Name:        "backup",
+		Description: "write a snapshot of the event db, safe while the server runs",
+		Run:         runBackupCommand,
+	})
+	register(&Command{
+		Name:        "restore",
+		Description: "replace the event db with a backup, the server must be stopped",
+		Run:         runRestoreCommand,
+	})
+}
+
+func runBackupCommand(args []string, out io.Writer) error {
+	flagSet := newFlagSet("backup")
+	path := flagSet.String("out", "", "file the snapshot is written to")
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	if *path == "" {
+		return fmt.Errorf("usage: detection-api backup -out FILE")
+	}
+
+	appConfig, err := readEventDbFileConfig("backup")
+	if err != nil {
+		return err
+	}
+
+	if err := db.NewSqLiteDb(appConfig).Backup(*path); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "backed up %s to %s\n", appConfig.EventDb.File, *path)
+
+	return err
+}
+
+func runRestoreCommand(args []string, out io.Writer) error {
+	flagSet := newFlagSet("restore")
+	path := flagSet.String("in", "", "backup file to restore")
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	if *path == "" {
+		return fmt.Errorf("usage: detection-api restore -in FILE")
+	}
+
+	appConfig, err := readEventDbFileConfig("restore")
+	if err != nil {
+		return err
+	}
+
+	if err := db.RestoreSqLiteDb(appConfig, *path); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "restored %s from %s, the previous db is kept as %s.pre-restore\n",
+		appConfig.EventDb.File, *path, appConfig.EventDb.File)
+
+	return err
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/app/services"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+func init() {
+	register(&Command{
+		Name:        "check-event",
+		Description: "run the detection for a JSON event against the stored events without storing it",
+		Run:         runCheckEventCommand,
+	})
+}
+
+func runCheckEventCommand(args []string, out io.Writer) error {
+	flagSet := newFlagSet("check-event")
+	eventJSON := flagSet.String("event", "", "the event as JSON, read from -file or standard input when not set")
+	path := flagSet.String("file", "", "file holding the event as JSON")
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	if *eventJSON == "" {
+		var data []byte
+		var err error
+
+		if *path != "" {
+			data, err = ioutil.ReadFile(*path)
+		} else {
+			data, err = ioutil.ReadAll(os.Stdin)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		*eventJSON = string(data)
+	}
+
+	event, err := models.EventFromJSON(strings.TrimSpace(*eventJSON))
+	if err != nil {
+		return err
+	}
+
+	serviceContext, err := newServiceContext()
+	if err != nil {
+		return err
+	}
+
+	detectionService := services.NewLiveDetectionService(serviceContext.EventRepository(),
+		serviceContext.IPGeoInfoRepository(), serviceContext.CalculatorService(),
+		serviceContext.DetectionParameters())
+
+	result, err := detectionService.EvaluateEvent(event)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(result)
+}
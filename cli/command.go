@@ -0,0 +1,115 @@
+// sub commands of the detection api binary, used for administration tasks next to serving the api
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/frankiennamdi/detection-api/app"
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/core"
+)
+
+type Command struct {
+	Name        string
+	Description string
+	Run         func(args []string, out io.Writer) error
+}
+
+var commands = map[string]*Command{}
+
+func register(command *Command) {
+	commands[command.Name] = command
+}
+
+// runs the sub command named by the first argument after the options shared by every command
+func Run(args []string, out io.Writer) error {
+	flagSet := newFlagSet("detection-api")
+	configFile := flagSet.String("config", "", "config file, CONFIG_FILE or the embedded default config when not set")
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	if *configFile != "" {
+		if err := os.Setenv("CONFIG_FILE", *configFile); err != nil {
+			return err
+		}
+	}
+
+	args = flagSet.Args()
+	if len(args) == 0 {
+		return usage(out)
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		_ = usage(out)
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+
+	return command.Run(args[1:], out)
+}
+
+func usage(out io.Writer) error {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	if _, err := fmt.Fprintln(out, "usage: detection-api [-config FILE] <command> [arguments]\n\ncommands:"); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, err := fmt.Fprintf(out, "  %-12s %s\n", name, commands[name].Description); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.SetOutput(os.Stderr)
+
+	return flagSet
+}
+
+func readAppConfig() (config.AppConfig, error) {
+	appConfig := config.AppConfig{}
+	err := appConfig.Read()
+
+	return appConfig, err
+}
+
+// reads the application config of a command that works on the SQLite file, which the memory driver does not have
+func readEventDbFileConfig(command string) (config.AppConfig, error) {
+	appConfig, err := readAppConfig()
+	if err == nil && appConfig.EventDb.IsMemory() {
+		return appConfig, fmt.Errorf("%s needs the SQLite file, the %s driver keeps the event db in the process",
+			command, config.MemoryDriver)
+	}
+
+	return appConfig, err
+}
+
+// reads and validates the application config and configures the server the same way serving the api does
+func newServiceContext() (*app.ServiceContext, error) {
+	appConfig, err := readAppConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := appConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	return app.NewServiceContext(core.NewServer(appConfig).Configure()), nil
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/config"
+)
+
+func init() {
+	register(&Command{
+		Name:        "config",
+		Description: "validate and print the effective configuration: check",
+		Run:         runConfigCommand,
+	})
+}
+
+func runConfigCommand(args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "check" {
+		return fmt.Errorf("usage: detection-api config check")
+	}
+
+	if err := newFlagSet("config check").Parse(args[1:]); err != nil {
+		return err
+	}
+
+	appConfig, err := readAppConfig()
+	if err != nil {
+		return err
+	}
+
+	configFile := config.File()
+	if configFile == "" {
+		configFile = "embedded default config"
+	}
+
+	if _, err := fmt.Fprintf(out, "# %s\n", configFile); err != nil {
+		return err
+	}
+
+	if err := writeConfig(out, reflect.ValueOf(appConfig.Redacted()), ""); err != nil {
+		return err
+	}
+
+	if err := appConfig.Validate(); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, "config is valid")
+
+	return err
+}
+
+// writes the values of the config in the layout of config.yml, under the keys of their config tags
+func writeConfig(out io.Writer, value reflect.Value, indent string) error {
+	for i := 0; i < value.NumField(); i++ {
+		key := value.Type().Field(i).Tag.Get("config")
+		field := value.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if _, err := fmt.Fprintf(out, "%s%s:\n", indent, key); err != nil {
+				return err
+			}
+
+			if err := writeConfig(out, field, indent+"  "); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		formatted := fmt.Sprintf("%v", field.Interface())
+		if field.Kind() == reflect.String && (formatted == "" || strings.ContainsAny(formatted, ":#")) {
+			formatted = fmt.Sprintf("%q", formatted)
+		}
+
+		if _, err := fmt.Fprintf(out, "%s%s: %s\n", indent, key, formatted); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/frankiennamdi/detection-api/app/services"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+func init() {
+	register(&Command{
+		Name:        "export",
+		Description: "stream events or alerts as NDJSON or CSV",
+		Run:         runExportCommand,
+	})
+}
+
+func runExportCommand(args []string, out io.Writer) (err error) {
+	if len(args) == 0 || (args[0] != "events" && args[0] != "alerts") {
+		return fmt.Errorf("usage: detection-api export <events|alerts> [arguments]")
+	}
+
+	kind := args[0]
+	availableColumns := services.EventExportColumns
+
+	if kind == "alerts" {
+		availableColumns = services.AlertExportColumns
+	}
+
+	flagSet := newFlagSet("export " + kind)
+	format := flagSet.String("format", services.ExportFormatNDJSON, "ndjson or csv")
+	columns := flagSet.String("columns", "", "comma separated columns, every column when not set")
+	username := flagSet.String("username", "", "only the records of this user")
+	from := flagSet.String("from", "", "first time included, unix seconds or RFC 3339")
+	to := flagSet.String("to", "", "first time excluded, unix seconds or RFC 3339")
+	after := flagSet.String("after", "", "cursor of the last record received, to resume an export")
+	limit := flagSet.Int("limit", 0, "most records written, no limit when 0")
+	path := flagSet.String("out", "", "file the export is written to, standard output when not set")
+	compress := flagSet.Bool("gzip", false, "gzip compress the export")
+
+	if err := flagSet.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if !services.IsExportFormat(*format) {
+		return models.NewValidationError(*format, "format")
+	}
+
+	selectedColumns, err := services.ParseExportColumns(*columns, availableColumns)
+	if err != nil {
+		return err
+	}
+
+	query := models.ExportQuery{Username: *username, After: *after, Limit: *limit}
+
+	if *from != "" {
+		if query.From, err = models.ParseTimestamp(*from); err != nil {
+			return err
+		}
+	}
+
+	if *to != "" {
+		if query.To, err = models.ParseTimestamp(*to); err != nil {
+			return err
+		}
+	}
+
+	serviceContext, err := newServiceContext()
+	if err != nil {
+		return err
+	}
+
+	if *path != "" {
+		file, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if closeErr := file.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}()
+
+		out = file
+	}
+
+	var gzipWriter *gzip.Writer
+
+	if *compress {
+		gzipWriter = gzip.NewWriter(out)
+		out = gzipWriter
+	}
+
+	exporter := services.NewExporter(serviceContext.EventExportRepository(), serviceContext.AlertRepository())
+	if kind == "alerts" {
+		_, err = exporter.ExportAlerts(out, query, *format, selectedColumns)
+	} else {
+		_, err = exporter.ExportEvents(out, query, *format, selectedColumns)
+	}
+
+	if gzipWriter != nil {
+		if closeErr := gzipWriter.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
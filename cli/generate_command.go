@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/support"
+	"github.com/google/uuid"
+)
+
+func init() {
+	register(&Command{
+		Name:        "generate",
+		Description: "post randomized sample events to a running server",
+		Run:         runGenerateCommand,
+	})
+}
+
+func runGenerateCommand(args []string, out io.Writer) error {
+	flagSet := newFlagSet("generate")
+	numEvents := flagSet.Int("num", 3000, "number of events to generate")
+	apiKey := flagSet.String("key", "", "api key with the events:write scope")
+	serverURL := flagSet.String("url", "http://localhost:3000", "url of the server")
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	log.Printf(support.Info, "Generating Events")
+
+	users := []string{"bob", "mark", "johnny", "mary", "kevin", "mike", "case"}
+
+	IPlist := []string{"206.81.252.6", "24.242.71.20", "91.207.175.104"}
+
+	timeChanges := []int{-1, -2, -3, -4, -5, 1, 2, 3, 4, 5}
+
+	startTime := time.Unix(1514764800, 0)
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, len(users))
+
+	for index := range users {
+		wg.Add(1)
+
+		go func(userForEvents string) {
+			defer wg.Done()
+
+			random := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+			for i := 1; i < *numEvents; i++ {
+				timeChange := timeChanges[randomNum(random, 0, len(timeChanges)-1)]
+
+				eventInfo := models.EventInfo{
+					UUID:      uuid.New().String(),
+					Username:  userForEvents,
+					Timestamp: startTime.Add(time.Duration(timeChange*randomNum(random, 1, 20)) * time.Hour).Unix(),
+					IP:        IPlist[randomNum(random, 0, len(IPlist)-1)],
+				}
+
+				if err := postEvent(*serverURL, *apiKey, eventInfo); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(users[index])
+	}
+
+	wg.Wait()
+	close(errs)
+
+	if err, failed := <-errs; failed {
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "posted %d events for each of %d users\n", *numEvents-1, len(users))
+
+	return err
+}
+
+func postEvent(serverURL, apiKey string, eventInfo models.EventInfo) error {
+	log.Printf(support.Info, eventInfo)
+
+	body, err := json.Marshal(eventInfo)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, serverURL+"/api/events", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-API-Key", apiKey)
+
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var result models.SuspiciousTravelResult
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(&result, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	log.Printf(support.Info, string(data))
+
+	return nil
+}
+
+func randomNum(random *rand.Rand, min, max int) int {
+	return random.Intn(max-min) + min
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/app/services"
+)
+
+func init() {
+	register(&Command{
+		Name:        "import",
+		Description: "load historical events from a CSV or NDJSON file",
+		Run:         runImportCommand,
+	})
+}
+
+func runImportCommand(args []string, out io.Writer) error {
+	flagSet := newFlagSet("import")
+	path := flagSet.String("file", "", "CSV or NDJSON file of events")
+	format := flagSet.String("format", "", "csv or ndjson, taken from the file extension when not set")
+	columns := flagSet.String("columns", "",
+		"csv columns of the event fields as field=column pairs, e.g. event_uuid=id,ip_address=client_ip")
+	batchSize := flagSet.Int("batch", 1000, "events inserted per transaction")
+	detect := flagSet.Bool("detect", false, "run the detection over the imported events to back-fill alerts")
+	rejectsPath := flagSet.String("rejects", "", "file for the rejected records, FILE.rejects when not set")
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	if *path == "" {
+		return fmt.Errorf("usage: detection-api import -file FILE [-format csv|ndjson] [-columns MAPPING] [-detect]")
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*path)), ".")
+	}
+
+	csvColumns, err := services.ParseCSVColumns(*columns)
+	if err != nil {
+		return err
+	}
+
+	if *rejectsPath == "" {
+		*rejectsPath = *path + ".rejects"
+	}
+
+	rejects := &lazyFile{path: *rejectsPath}
+
+	serviceContext, err := newServiceContext()
+	if err != nil {
+		return err
+	}
+
+	importer := services.NewEventImporter(serviceContext.EventRepository(), serviceContext.DetectionService())
+	report, importErr := importer.ImportFile(*path, services.ImportOptions{
+		Format:    *format,
+		Columns:   csvColumns,
+		BatchSize: *batchSize,
+		Detect:    *detect,
+		Rejects:   rejects,
+	})
+
+	if err := rejects.Close(); err != nil && importErr == nil {
+		importErr = err
+	}
+
+	if report != nil {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+
+		if report.Rejected > 0 {
+			if _, err := fmt.Fprintf(out, "rejected records written to %s\n", *rejectsPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return importErr
+}
+
+// file that is only created once something is written to it
+type lazyFile struct {
+	path string
+	file *os.File
+}
+
+func (lazy *lazyFile) Write(data []byte) (int, error) {
+	if lazy.file == nil {
+		file, err := os.Create(lazy.path)
+		if err != nil {
+			return 0, err
+		}
+
+		lazy.file = file
+	}
+
+	return lazy.file.Write(data)
+}
+
+func (lazy *lazyFile) Close() error {
+	if lazy.file == nil {
+		return nil
+	}
+
+	return lazy.file.Close()
+}
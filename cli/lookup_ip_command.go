@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/frankiennamdi/detection-api/db"
+	"github.com/frankiennamdi/detection-api/repository"
+)
+
+func init() {
+	register(&Command{
+		Name:        "lookup-ip",
+		Description: "print the location the GeoLite2 database holds for IP addresses",
+		Run:         runLookupIPCommand,
+	})
+}
+
+func runLookupIPCommand(args []string, out io.Writer) error {
+	flagSet := newFlagSet("lookup-ip")
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	if flagSet.NArg() == 0 {
+		return fmt.Errorf("usage: detection-api lookup-ip IP [IP...]")
+	}
+
+	appConfig, err := readAppConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := appConfig.IPGeoDbConfig.Validate(); err != nil {
+		return err
+	}
+
+	ipGeoInfoRepository := repository.NewMaxMindIPGeoInfoRepository(db.NewMaxMindDb(appConfig))
+	encoder := json.NewEncoder(out)
+
+	for _, value := range flagSet.Args() {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return fmt.Errorf("not an IP address: %s", value)
+		}
+
+		geoPoint, err := ipGeoInfoRepository.FindGeoPoint(ip)
+		if err != nil {
+			return err
+		}
+
+		if err := encoder.Encode(map[string]interface{}{"ip": value, "geo": geoPoint}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
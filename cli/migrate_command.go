@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/db"
+)
+
+const (
+	migrateTargetSQLite   = "sqlite"
+	migrateTargetPostgres = "postgres"
+)
+
+const migrateUsage = "usage: detection-api migrate <up|down [N]|goto V|force V|status|version> [-db sqlite|postgres]"
+
+func init() {
+	register(&Command{
+		Name:        "migrate",
+		Description: "manage the schema of the event db: up, down, goto, force, status, version",
+		Run:         runMigrateCommand,
+	})
+}
+
+func runMigrateCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	flagSet := newFlagSet("migrate " + args[0])
+	target := flagSet.String("db", migrateTargetSQLite,
+		"sqlite for the SQLite file, or postgres for the events of the postgres driver")
+
+	if err := flagSet.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	fnx, err := migrateAction(args[0], flagSet.Args(), *target, out)
+	if err != nil {
+		return err
+	}
+
+	appConfig, err := readAppConfig()
+	if err != nil {
+		return err
+	}
+
+	return withMigrations(appConfig, *target, fnx)
+}
+
+// the action of the migrate command with its arguments
+func migrateAction(command string, args []string, target string,
+	out io.Writer) (func(migrations *db.Migrations) error, error) {
+	switch command {
+	case "up":
+		return func(migrations *db.Migrations) error {
+			if err := migrations.Up(); err != nil {
+				return err
+			}
+
+			return printMigrationVersion(out, target, migrations)
+		}, nil
+	case "down":
+		steps := 1
+
+		if len(args) > 0 {
+			parsed, err := strconv.Atoi(args[0])
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("migrate down takes a number of migrations greater than 0, got %s", args[0])
+			}
+
+			steps = parsed
+		}
+
+		return func(migrations *db.Migrations) error {
+			if err := migrations.Down(steps); err != nil {
+				return err
+			}
+
+			return printMigrationVersion(out, target, migrations)
+		}, nil
+	case "goto":
+		version, err := migrateVersionArg(command, args)
+		if err != nil {
+			return nil, err
+		}
+
+		if version < 0 {
+			return nil, fmt.Errorf("migrate goto takes a version of 0 or more, got %d", version)
+		}
+
+		return func(migrations *db.Migrations) error {
+			if err := migrations.Goto(uint(version)); err != nil {
+				return err
+			}
+
+			return printMigrationVersion(out, target, migrations)
+		}, nil
+	case "force":
+		version, err := migrateVersionArg(command, args)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(migrations *db.Migrations) error {
+			if err := migrations.Force(version); err != nil {
+				return err
+			}
+
+			return printMigrationVersion(out, target, migrations)
+		}, nil
+	case "status":
+		return func(migrations *db.Migrations) error {
+			return printMigrationStatus(out, target, migrations)
+		}, nil
+	case "version":
+		return func(migrations *db.Migrations) error {
+			return printMigrationVersion(out, target, migrations)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown migrate command: %s\n%s", command, migrateUsage)
+	}
+}
+
+func migrateVersionArg(command string, args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("usage: detection-api migrate %s [-db sqlite|postgres] V", command)
+	}
+
+	version, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, fmt.Errorf("migrate %s takes a version number, got %s", command, args[0])
+	}
+
+	return version, nil
+}
+
+// runs fnx with the migrations of the target db, without migrating it on open like serving the api does
+func withMigrations(appConfig config.AppConfig, target string, fnx func(migrations *db.Migrations) error) error {
+	switch target {
+	case migrateTargetSQLite:
+		if appConfig.EventDb.IsMemory() {
+			return fmt.Errorf("the %s driver migrates its in-process db when serving the api", config.MemoryDriver)
+		}
+
+		return db.NewSqLiteDb(appConfig).WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+			return db.WithSqLiteMigrations(context, fnx)
+		}, "mode=rwc")
+	case migrateTargetPostgres:
+		postgresDb, err := db.NewPostgresDb(appConfig)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			_ = postgresDb.Close()
+		}()
+
+		return db.WithPostgresMigrations(postgresDb, fnx)
+	default:
+		return fmt.Errorf("unknown db: %s, expected %s or %s", target, migrateTargetSQLite, migrateTargetPostgres)
+	}
+}
+
+func printMigrationVersion(out io.Writer, target string, migrations *db.Migrations) error {
+	version, err := migrations.Version()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "%s schema version: %d, dirty: %t\n", target, version.Version, version.Dirty)
+
+	return err
+}
+
+func printMigrationStatus(out io.Writer, target string, migrations *db.Migrations) error {
+	status, err := migrations.Status()
+	if err != nil {
+		return err
+	}
+
+	pending := make([]string, len(status.Pending))
+	for i, version := range status.Pending {
+		pending[i] = strconv.FormatUint(uint64(version), 10)
+	}
+
+	if len(pending) == 0 {
+		pending = []string{"none"}
+	}
+
+	_, err = fmt.Fprintf(out, "%s schema version: %d, dirty: %t, latest: %d, pending: %s\n", target,
+		status.Version, status.Dirty, status.Latest, strings.Join(pending, ", "))
+
+	return err
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+)
+
+func init() {
+	register(&Command{
+		Name:        "rebuild-locations",
+		Description: "rebuild the location profiles of every user from the stored events",
+		Run:         runRebuildLocationsCommand,
+	})
+}
+
+// the profiles are replaced, so events processed while it runs are lost from them; run it with ingestion stopped
+func runRebuildLocationsCommand(args []string, out io.Writer) error {
+	if err := newFlagSet("rebuild-locations").Parse(args); err != nil {
+		return err
+	}
+
+	serviceContext, err := newServiceContext()
+	if err != nil {
+		return err
+	}
+
+	report, err := serviceContext.LocationProfiler().Rebuild()
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
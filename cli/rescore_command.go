@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+func init() {
+	register(&Command{
+		Name:        "rescore",
+		Description: "report how the alerts of the stored events change with a candidate suspicious speed",
+		Run:         runRescoreCommand,
+	})
+}
+
+func runRescoreCommand(args []string, out io.Writer) error {
+	flagSet := newFlagSet("rescore")
+	speed := flagSet.Float64("speed", 0, "candidate suspicious speed in miles per hour")
+	username := flagSet.String("username", "", "only rescore the events of this user")
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	if *speed == 0 {
+		return fmt.Errorf("usage: detection-api rescore -speed MPH [-username USER]")
+	}
+
+	serviceContext, err := newServiceContext()
+	if err != nil {
+		return err
+	}
+
+	baseline := serviceContext.DetectionParameters().Get()
+	candidate := baseline
+	candidate.SuspiciousSpeed = *speed
+
+	report, err := serviceContext.Rescorer().Rescore(baseline, candidate, *username)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
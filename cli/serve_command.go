@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+func init() {
+	register(&Command{
+		Name:        "serve",
+		Description: "serve the api, the default when no command is given",
+		Run:         runServeCommand,
+	})
+}
+
+func runServeCommand(args []string, out io.Writer) error {
+	if err := newFlagSet("serve").Parse(args); err != nil {
+		return err
+	}
+
+	log.Printf(support.Info, "starting")
+
+	sigc := make(chan os.Signal, 1)
+	// SIGHUP is not a shutdown signal, it reloads the tls certificates and the config
+	signal.Notify(sigc,
+		syscall.SIGINT,
+		syscall.SIGTERM,
+		syscall.SIGQUIT)
+
+	go func() {
+		sig := <-sigc
+		log.Printf(support.Info, sig)
+		log.Printf(support.Info, "goodbye")
+		os.Exit(0)
+	}()
+
+	serviceContext, err := newServiceContext()
+	if err != nil {
+		return err
+	}
+
+	serviceContext.Listen()
+
+	return nil
+}
@@ -2,18 +2,54 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
-	"log"
+	"time"
 
+	"github.com/frankiennamdi/detection-api/resources"
 	"github.com/frankiennamdi/detection-api/support"
 	"github.com/frankiennamdi/go-configuration/configuration"
 )
 
+// event db drivers. the api keys, the ingestion queue and the derived data live in the SQLite file, the driver only
+// selects where events are stored. memory keeps the events and an in-memory SQLite db in the process, touches no file
+// and loses everything on restart
+const (
+	SQLiteDriver   = "sqlite3"
+	PostgresDriver = "postgres"
+	MemoryDriver   = "memory"
+)
+
+// distance calculators. haversine measures on a sphere, vincenty along the WGS-84 ellipsoid
+const (
+	HaversineCalculator = "haversine"
+	VincentyCalculator  = "vincenty"
+)
+
+// the migration location of the migrations embedded in the binary, also used when no location is set
+const EmbeddedMigrationLoc = "embedded"
+
 type EventDbConfig struct {
+	Driver        string `config:"driver"`
 	File          string `config:"file"`
+	DSN           string `config:"dsn"`
 	Name          string `config:"name"`
 	MigrationLoc  string `config:"migrationLoc"`
 	MaxConnection int    `config:"maxConnection"`
+	AutoMigrate   bool   `config:"autoMigrate"`
+}
+
+// an empty driver is treated as SQLite
+func (eventDbConfig EventDbConfig) IsPostgres() bool {
+	return eventDbConfig.Driver == PostgresDriver
+}
+
+func (eventDbConfig EventDbConfig) IsMemory() bool {
+	return eventDbConfig.Driver == MemoryDriver
+}
+
+func (eventDbConfig EventDbConfig) IsEmbeddedMigrations() bool {
+	return eventDbConfig.MigrationLoc == "" || eventDbConfig.MigrationLoc == EmbeddedMigrationLoc
 }
 
 type IPGeoDbConfig struct {
@@ -21,25 +57,165 @@ type IPGeoDbConfig struct {
 	MaxConnection int    `config:"maxConnection"`
 }
 
+// requestsPerSecond and burst limit the ingestion of each client. addressRequestsPerSecond and addressBurst limit
+// every request of a remote address before the api key is checked, and are set looser since many clients can share
+// an address behind a NAT or proxy
+type RateLimitConfig struct {
+	Enabled                  bool    `config:"enabled"`
+	RequestsPerSecond        float64 `config:"requestsPerSecond"`
+	Burst                    int     `config:"burst"`
+	AddressRequestsPerSecond float64 `config:"addressRequestsPerSecond"`
+	AddressBurst             int     `config:"addressBurst"`
+}
+
+// minVersion is one of 1.0, 1.1, 1.2, 1.3 and clientAuth one of none, request, require, verify-if-given,
+// require-and-verify. the client CA file is only read when client certificates are verified
+type TLSConfig struct {
+	Enabled      bool   `config:"enabled"`
+	CertFile     string `config:"certFile"`
+	KeyFile      string `config:"keyFile"`
+	ClientCAFile string `config:"clientCaFile"`
+	MinVersion   string `config:"minVersion"`
+	ClientAuth   string `config:"clientAuth"`
+}
+
 type ServerConfig struct {
-	Port int `config:"port"`
+	Port            int             `config:"port"`
+	AuthEnabled     bool            `config:"authEnabled"`
+	MaxRequestBytes int             `config:"maxRequestBytes"`
+	RateLimit       RateLimitConfig `config:"rateLimit"`
+	TLS             TLSConfig       `config:"tls"`
+}
+
+// a failed event is tried again by its worker until it failed maxAttempts times, then it is left failed. done and
+// failed events are deleted resultTtlHours after they were processed
+type AsyncConfig struct {
+	Enabled            bool `config:"enabled"`
+	Workers            int  `config:"workers"`
+	BatchSize          int  `config:"batchSize"`
+	PollIntervalMillis int  `config:"pollIntervalMillis"`
+	MaxAttempts        int  `config:"maxAttempts"`
+	ResultTTLHours     int  `config:"resultTtlHours"`
+}
+
+// days of zero or less keep events forever. tenantDays holds comma separated tenant:days pairs, where the tenant is
+// the domain of the username, or none. archived events are written to gzip compressed NDJSON files in archiveDir
+type RetentionConfig struct {
+	Enabled         bool   `config:"enabled"`
+	Days            int    `config:"days"`
+	TenantDays      string `config:"tenantDays"`
+	BatchSize       int    `config:"batchSize"`
+	IntervalMinutes int    `config:"intervalMinutes"`
+	Archive         bool   `config:"archive"`
+	ArchiveDir      string `config:"archiveDir"`
+	Vacuum          bool   `config:"vacuum"`
+}
+
+type BackupConfig struct {
+	Dir string `config:"dir"`
+}
+
+// the detection parameters, suspiciousSpeed and speedBands, are reloaded from the config file when it changes,
+// checked every intervalSeconds, or on SIGHUP. without a config file there is nothing to reload
+type ReloadConfig struct {
+	Enabled         bool `config:"enabled"`
+	IntervalSeconds int  `config:"intervalSeconds"`
+}
+
+// the risk of a user is halved every halfLifeHours. leaderboardSize is the number of users on the leaderboard unless
+// a request asks for fewer or more, up to maxLeaderboardSize
+type RiskConfig struct {
+	Enabled            bool `config:"enabled"`
+	HalfLifeHours      int  `config:"halfLifeHours"`
+	LeaderboardSize    int  `config:"leaderboardSize"`
+	MaxLeaderboardSize int  `config:"maxLeaderboardSize"`
+}
+
+// the half life of the risk of a user
+func (riskConfig RiskConfig) HalfLife() time.Duration {
+	return time.Duration(riskConfig.HalfLifeHours) * time.Hour
+}
+
+// locations are geohash cells of geohashPrecision characters and countries. nothing is flagged for a user with fewer
+// than minEvents events, and a location seen in less than rareShare of the events of the user is rare
+type NoveltyConfig struct {
+	Enabled          bool    `config:"enabled"`
+	GeohashPrecision int     `config:"geohashPrecision"`
+	MinEvents        int     `config:"minEvents"`
+	RareShare        float64 `config:"rareShare"`
+}
+
+// the travel of a user over a window of at most the last events events within the last hours hours is checked as a
+// whole, in addition to the travel from and to the nearest events
+type WindowConfig struct {
+	Enabled bool `config:"enabled"`
+	Events  int  `config:"events"`
+	Hours   int  `config:"hours"`
+}
+
+// a session is open from its latest event until an event that ends it or until it was idle for idleMinutes. another
+// open session of the user from at least minMiles away is flagged
+type SessionConfig struct {
+	Enabled     bool    `config:"enabled"`
+	IdleMinutes int     `config:"idleMinutes"`
+	MinMiles    float64 `config:"minMiles"`
+}
+
+// travels shorter than groundMaxMiles are by ground and suspicious from groundSpeed, longer ones are flights that
+// take flightOverheadHours on top of the time in the air at the suspicious speed
+type SpeedBandsConfig struct {
+	Enabled             bool    `config:"enabled"`
+	GroundMaxMiles      float64 `config:"groundMaxMiles"`
+	GroundSpeed         float64 `config:"groundSpeed"`
+	FlightOverheadHours float64 `config:"flightOverheadHours"`
 }
 
 type AppConfig struct {
-	EventDb         EventDbConfig `config:"eventDb"`
-	Server          ServerConfig  `config:"server"`
-	IPGeoDbConfig   IPGeoDbConfig `config:"ipGeoDbConfig"`
-	SuspiciousSpeed float64       `config:"suspiciousSpeed"`
+	EventDb         EventDbConfig    `config:"eventDb"`
+	Server          ServerConfig     `config:"server"`
+	IPGeoDbConfig   IPGeoDbConfig    `config:"ipGeoDbConfig"`
+	SuspiciousSpeed float64          `config:"suspiciousSpeed"`
+	SpeedBands      SpeedBandsConfig `config:"speedBands"`
+	Calculator      string           `config:"calculator"`
+	Async           AsyncConfig      `config:"async"`
+	Retention       RetentionConfig  `config:"retention"`
+	Backup          BackupConfig     `config:"backup"`
+	Reload          ReloadConfig     `config:"reload"`
+	Risk            RiskConfig       `config:"risk"`
+	Novelty         NoveltyConfig    `config:"novelty"`
+	Window          WindowConfig     `config:"window"`
+	Session         SessionConfig    `config:"session"`
+}
+
+// the config file read by Read, CONFIG_FILE or empty when the default config embedded in the binary is read
+func File() string {
+	configFile := support.GetEnvironmentValue("CONFIG_FILE", "")
+	if configFile == "" {
+		return ""
+	}
+
+	return support.Resolve(configFile)
 }
 
+// reads the config from File(), or the embedded default config without one. Validate checks the values read
 func (appConfig *AppConfig) Read() error {
-	configFile := support.GetEnvironmentValue("CONFIG_FILE", "resources/config.yml")
-	yamlData, err := ioutil.ReadFile(support.Resolve(configFile))
+	if configFile := File(); configFile != "" {
+		return appConfig.ReadFile(configFile)
+	}
+
+	return appConfig.bind(resources.DefaultConfig)
+}
 
+func (appConfig *AppConfig) ReadFile(configFile string) error {
+	yamlData, err := ioutil.ReadFile(configFile)
 	if err != nil {
-		log.Panicf(support.Fatal, err)
+		return fmt.Errorf("cannot read config file: %v", err)
 	}
 
+	return appConfig.bind(yamlData)
+}
+
+func (appConfig *AppConfig) bind(yamlData []byte) error {
 	binder := configuration.New()
 	if err := binder.InitializeConfigFromYaml(yamlData, appConfig); err != nil {
 		return err
@@ -21,7 +21,26 @@ func TestConfigWithEnvironment(t *testing.T) {
 	req.NoError(err)
 	req.Equal("test_db", appConfig.EventDb.Name)
 	req.Equal("resources/event-db/event_db.db", appConfig.EventDb.File)
-	req.Equal("migrations", appConfig.EventDb.MigrationLoc)
+	req.Equal(EmbeddedMigrationLoc, appConfig.EventDb.MigrationLoc)
+	req.True(appConfig.EventDb.IsEmbeddedMigrations())
+	req.True(appConfig.EventDb.AutoMigrate)
+}
+
+func TestConfig_Read_From_File_Like_Embedded(t *testing.T) {
+	req := require.New(t)
+
+	embedded := AppConfig{}
+	req.NoError(embedded.Read())
+
+	setEnv("CONFIG_FILE", "resources/config.yml")
+
+	defer unsetEnv("CONFIG_FILE")
+
+	req.Equal(support.Resolve("resources/config.yml"), File())
+
+	fromFile := AppConfig{}
+	req.NoError(fromFile.Read())
+	req.Equal(embedded, fromFile)
 }
 
 func unsetEnv(key string) {
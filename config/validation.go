@@ -0,0 +1,356 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/support"
+)
+
+// accepted values of tls.minVersion and tls.clientAuth
+var (
+	tlsMinVersions     = []string{"1.0", "1.1", "1.2", "1.3"}
+	tlsClientAuthTypes = []string{"none", "request", "require", "verify-if-given", "require-and-verify"}
+)
+
+// every problem found in a config, each naming the key it is about
+type ValidationErrors []string
+
+func (errs ValidationErrors) Error() string {
+	return fmt.Sprintf("invalid config:\n  %s", strings.Join(errs, "\n  "))
+}
+
+// collects the problems of a config
+type problems struct {
+	errs ValidationErrors
+}
+
+func (problems *problems) add(key, format string, args ...interface{}) {
+	problems.errs = append(problems.errs, fmt.Sprintf("%s: %s", key, fmt.Sprintf(format, args...)))
+}
+
+func (problems *problems) err() error {
+	if len(problems.errs) == 0 {
+		return nil
+	}
+
+	return problems.errs
+}
+
+// checks the whole config and returns every problem found as ValidationErrors, nil when there are none
+func (appConfig AppConfig) Validate() error {
+	problems := &problems{}
+
+	appConfig.EventDb.validate(problems)
+	appConfig.IPGeoDbConfig.validate(problems)
+	appConfig.Server.validate(problems)
+
+	if appConfig.SuspiciousSpeed <= 0 {
+		problems.add("suspiciousSpeed", "must be greater than 0, got %v", appConfig.SuspiciousSpeed)
+	}
+
+	switch appConfig.Calculator {
+	case "", HaversineCalculator, VincentyCalculator:
+	default:
+		problems.add("calculator", "must be one of %s or %s, got %q", HaversineCalculator, VincentyCalculator,
+			appConfig.Calculator)
+	}
+
+	if speedBands := appConfig.SpeedBands; speedBands.Enabled {
+		if speedBands.GroundMaxMiles <= 0 {
+			problems.add("speedBands.groundMaxMiles", "must be greater than 0, got %v", speedBands.GroundMaxMiles)
+		}
+
+		if speedBands.GroundSpeed <= 0 {
+			problems.add("speedBands.groundSpeed", "must be greater than 0, got %v", speedBands.GroundSpeed)
+		}
+
+		if speedBands.FlightOverheadHours < 0 {
+			problems.add("speedBands.flightOverheadHours", "must not be negative, got %v",
+				speedBands.FlightOverheadHours)
+		}
+	}
+
+	if appConfig.Async.Enabled {
+		checkPositive(problems, "async.workers", appConfig.Async.Workers)
+		checkPositive(problems, "async.batchSize", appConfig.Async.BatchSize)
+		checkPositive(problems, "async.pollIntervalMillis", appConfig.Async.PollIntervalMillis)
+		checkPositive(problems, "async.maxAttempts", appConfig.Async.MaxAttempts)
+		checkPositive(problems, "async.resultTtlHours", appConfig.Async.ResultTTLHours)
+	}
+
+	if appConfig.Retention.Enabled {
+		checkPositive(problems, "retention.batchSize", appConfig.Retention.BatchSize)
+		checkPositive(problems, "retention.intervalMinutes", appConfig.Retention.IntervalMinutes)
+
+		if appConfig.Retention.Archive {
+			checkWritableDir(problems, "retention.archiveDir", support.Resolve(appConfig.Retention.ArchiveDir),
+				true)
+		}
+	}
+
+	checkWritableDir(problems, "backup.dir", support.Resolve(appConfig.Backup.Dir), true)
+
+	if appConfig.Reload.Enabled && appConfig.Reload.IntervalSeconds < 0 {
+		problems.add("reload.intervalSeconds", "must not be negative, got %d", appConfig.Reload.IntervalSeconds)
+	}
+
+	if appConfig.Risk.Enabled {
+		checkPositive(problems, "risk.halfLifeHours", appConfig.Risk.HalfLifeHours)
+		checkPositive(problems, "risk.leaderboardSize", appConfig.Risk.LeaderboardSize)
+
+		if appConfig.Risk.MaxLeaderboardSize < appConfig.Risk.LeaderboardSize {
+			problems.add("risk.maxLeaderboardSize", "must be at least risk.leaderboardSize %d, got %d",
+				appConfig.Risk.LeaderboardSize, appConfig.Risk.MaxLeaderboardSize)
+		}
+	}
+
+	if appConfig.Novelty.Enabled {
+		if appConfig.Novelty.GeohashPrecision < 1 || appConfig.Novelty.GeohashPrecision > 12 {
+			problems.add("novelty.geohashPrecision", "must be between 1 and 12, got %d",
+				appConfig.Novelty.GeohashPrecision)
+		}
+
+		checkPositive(problems, "novelty.minEvents", appConfig.Novelty.MinEvents)
+
+		if appConfig.Novelty.RareShare <= 0 || appConfig.Novelty.RareShare >= 1 {
+			problems.add("novelty.rareShare", "must be between 0 and 1, got %v", appConfig.Novelty.RareShare)
+		}
+	}
+
+	if appConfig.Window.Enabled {
+		if appConfig.Window.Events < 2 {
+			problems.add("window.events", "must be at least 2, got %d", appConfig.Window.Events)
+		}
+
+		checkPositive(problems, "window.hours", appConfig.Window.Hours)
+	}
+
+	if appConfig.Session.Enabled {
+		checkPositive(problems, "session.idleMinutes", appConfig.Session.IdleMinutes)
+
+		if appConfig.Session.MinMiles <= 0 {
+			problems.add("session.minMiles", "must be greater than 0, got %v", appConfig.Session.MinMiles)
+		}
+	}
+
+	return problems.err()
+}
+
+func (eventDbConfig EventDbConfig) Validate() error {
+	problems := &problems{}
+	eventDbConfig.validate(problems)
+
+	return problems.err()
+}
+
+func (eventDbConfig EventDbConfig) validate(problems *problems) {
+	switch eventDbConfig.Driver {
+	case "", SQLiteDriver, MemoryDriver:
+	case PostgresDriver:
+		if strings.TrimSpace(eventDbConfig.DSN) == "" {
+			problems.add("eventDb.dsn", "is required by the %s driver", PostgresDriver)
+		}
+	default:
+		problems.add("eventDb.driver", "must be one of %s, %s or %s, got %q", SQLiteDriver, PostgresDriver,
+			MemoryDriver, eventDbConfig.Driver)
+	}
+
+	// the api keys, the queue and the alerts are kept in the SQLite file, or in the process with the memory driver
+	switch {
+	case eventDbConfig.IsMemory():
+	case strings.TrimSpace(eventDbConfig.File) == "":
+		problems.add("eventDb.file", "is required")
+	default:
+		checkWritableDir(problems, "eventDb.file", filepath.Dir(eventDbConfig.File), false)
+	}
+
+	if strings.TrimSpace(eventDbConfig.Name) == "" {
+		problems.add("eventDb.name", "is required")
+	}
+
+	// without a migration location the migrations embedded in the binary are used
+	if !eventDbConfig.IsEmbeddedMigrations() {
+		checkDir(problems, "eventDb.migrationLoc", support.Resolve(eventDbConfig.MigrationLoc))
+	}
+	checkPositive(problems, "eventDb.maxConnection", eventDbConfig.MaxConnection)
+}
+
+func (ipGeoDbConfig IPGeoDbConfig) Validate() error {
+	problems := &problems{}
+	ipGeoDbConfig.validate(problems)
+
+	return problems.err()
+}
+
+func (ipGeoDbConfig IPGeoDbConfig) validate(problems *problems) {
+	checkFile(problems, "ipGeoDbConfig.location", support.Resolve(ipGeoDbConfig.Location))
+	checkPositive(problems, "ipGeoDbConfig.maxConnection", ipGeoDbConfig.MaxConnection)
+}
+
+func (serverConfig ServerConfig) Validate() error {
+	problems := &problems{}
+	serverConfig.validate(problems)
+
+	return problems.err()
+}
+
+func (serverConfig ServerConfig) validate(problems *problems) {
+	if serverConfig.Port < 1 || serverConfig.Port > 65535 {
+		problems.add("server.port", "must be between 1 and 65535, got %d", serverConfig.Port)
+	}
+
+	checkPositive(problems, "server.maxRequestBytes", serverConfig.MaxRequestBytes)
+
+	if serverConfig.RateLimit.Enabled {
+		if serverConfig.RateLimit.RequestsPerSecond <= 0 {
+			problems.add("server.rateLimit.requestsPerSecond", "must be greater than 0, got %v",
+				serverConfig.RateLimit.RequestsPerSecond)
+		}
+
+		checkPositive(problems, "server.rateLimit.burst", serverConfig.RateLimit.Burst)
+
+		if serverConfig.RateLimit.AddressRequestsPerSecond <= 0 {
+			problems.add("server.rateLimit.addressRequestsPerSecond", "must be greater than 0, got %v",
+				serverConfig.RateLimit.AddressRequestsPerSecond)
+		}
+
+		checkPositive(problems, "server.rateLimit.addressBurst", serverConfig.RateLimit.AddressBurst)
+	}
+
+	tlsConfig := serverConfig.TLS
+	if !tlsConfig.Enabled {
+		return
+	}
+
+	checkFile(problems, "server.tls.certFile", support.Resolve(tlsConfig.CertFile))
+	checkFile(problems, "server.tls.keyFile", support.Resolve(tlsConfig.KeyFile))
+
+	if !contains(tlsMinVersions, tlsConfig.MinVersion) {
+		problems.add("server.tls.minVersion", "must be one of %s, got %q", strings.Join(tlsMinVersions, ", "),
+			tlsConfig.MinVersion)
+	}
+
+	if !contains(tlsClientAuthTypes, tlsConfig.ClientAuth) {
+		problems.add("server.tls.clientAuth", "must be one of %s, got %q", strings.Join(tlsClientAuthTypes, ", "),
+			tlsConfig.ClientAuth)
+	}
+
+	if tlsConfig.ClientAuth == "verify-if-given" || tlsConfig.ClientAuth == "require-and-verify" {
+		checkFile(problems, "server.tls.clientCaFile", support.Resolve(tlsConfig.ClientCAFile))
+	}
+}
+
+// the config with the password of the postgres dsn masked, for printing
+func (appConfig AppConfig) Redacted() AppConfig {
+	appConfig.EventDb.DSN = redactDSN(appConfig.EventDb.DSN)
+	return appConfig
+}
+
+// masks the password of a url dsn, or of a password=... pair of a key value dsn
+func redactDSN(dsn string) string {
+	if parsed, err := url.Parse(dsn); err == nil && parsed.User != nil {
+		if _, ok := parsed.User.Password(); ok {
+			parsed.User = url.UserPassword(parsed.User.Username(), "redacted")
+			return parsed.String()
+		}
+
+		return dsn
+	}
+
+	fields := strings.Fields(dsn)
+	for i, field := range fields {
+		if strings.HasPrefix(field, "password=") {
+			fields[i] = "password=redacted"
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func checkPositive(problems *problems, key string, value int) {
+	if value <= 0 {
+		problems.add(key, "must be greater than 0, got %d", value)
+	}
+}
+
+func checkFile(problems *problems, key, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		addStatProblem(problems, key, path, err)
+		return
+	}
+
+	if info.IsDir() {
+		problems.add(key, "%s is a directory, expected a file", path)
+	}
+}
+
+func checkDir(problems *problems, key, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		addStatProblem(problems, key, path, err)
+		return
+	}
+
+	if !info.IsDir() {
+		problems.add(key, "%s is not a directory", path)
+	}
+}
+
+// a directory that is created when missing only needs its closest existing parent to be writable
+func checkWritableDir(problems *problems, key, path string, createdWhenMissing bool) {
+	dir := path
+
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				problems.add(key, "%s is not a directory", dir)
+				return
+			}
+
+			break
+		}
+
+		parent := filepath.Dir(dir)
+		if !createdWhenMissing || !os.IsNotExist(err) || parent == dir {
+			addStatProblem(problems, key, dir, err)
+			return
+		}
+
+		dir = parent
+	}
+
+	file, err := ioutil.TempFile(dir, ".write-check-")
+	if err != nil {
+		problems.add(key, "%s is not writable: %v", dir, err)
+		return
+	}
+
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+}
+
+func addStatProblem(problems *problems, key, path string, err error) {
+	if os.IsNotExist(err) {
+		problems.add(key, "%s does not exist", path)
+		return
+	}
+
+	problems.add(key, "%v", err)
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
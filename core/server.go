@@ -1,6 +1,7 @@
 package core
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/frankiennamdi/detection-api/config"
@@ -22,15 +23,21 @@ func NewServer(config config.AppConfig) *Server {
 
 // represents the initialized and configures core components of the system like database, and application configuration
 type ServerContext struct {
-	sqLitDb   *db.SqLiteDb
-	maxMindDb *db.MaxMindDb
-	config    config.AppConfig
+	sqLitDb    *db.SqLiteDb
+	postgresDb *db.PostgresDb
+	maxMindDb  *db.MaxMindDb
+	config     config.AppConfig
 }
 
 func (serverContext *ServerContext) EventDb() *db.SqLiteDb {
 	return serverContext.sqLitDb
 }
 
+// nil unless the event db driver is postgres
+func (serverContext *ServerContext) PostgresDb() *db.PostgresDb {
+	return serverContext.postgresDb
+}
+
 func (serverContext *ServerContext) GeoIPDb() *db.MaxMindDb {
 	return serverContext.maxMindDb
 }
@@ -47,24 +54,66 @@ func (server *Server) Configure() *ServerContext {
 		log.Panicf(support.Fatal, err)
 	}
 	log.Printf(support.Info, "configuring EventDb Complete")
+
+	postgresDb, err := server.configurePostgresDb()
+	if err != nil {
+		log.Panicf(support.Fatal, err)
+	}
+
 	maxMindDb := db.NewMaxMindDb(server.config)
 
 	return &ServerContext{
-		sqLitDb:   sqLiteDb,
-		maxMindDb: maxMindDb,
-		config:    server.config,
+		sqLitDb:    sqLiteDb,
+		postgresDb: postgresDb,
+		maxMindDb:  maxMindDb,
+		config:     server.config,
+	}
+}
+
+func (server *Server) configurePostgresDb() (*db.PostgresDb, error) {
+	switch server.config.EventDb.Driver {
+	case "", config.SQLiteDriver, config.MemoryDriver:
+		return nil, nil
+	case config.PostgresDriver:
+	default:
+		return nil, fmt.Errorf("unsupported event db driver : %s", server.config.EventDb.Driver)
+	}
+
+	log.Printf(support.Info, "configuring Postgres EventDb")
+
+	postgresDb, err := db.NewPostgresDb(server.config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.WithPostgresMigrations(postgresDb, server.prepareSchema); err != nil {
+		_ = postgresDb.Close()
+		return nil, err
 	}
+
+	log.Printf(support.Info, "configuring Postgres EventDb Complete")
+
+	return postgresDb, nil
 }
 
 func (server *Server) configureEventDb(sqLiteDb *db.SqLiteDb) (err error) {
 	log.Printf(support.Info, "configuring EventDb")
 
 	fnxErr := sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
-		migrationErr := db.MigrateUp(context)
-		if migrationErr != nil {
-			return migrationErr
-		}
-		return nil
+		return db.WithSqLiteMigrations(context, server.prepareSchema)
 	}, "mode=rwc")
 	return fnxErr
 }
+
+// migrates the schema up, or when auto migration is off refuses to start unless it is already current
+func (server *Server) prepareSchema(migrations *db.Migrations) error {
+	if server.config.EventDb.AutoMigrate {
+		return migrations.Up()
+	}
+
+	if err := migrations.RequireCurrent(); err != nil {
+		return fmt.Errorf("eventDb.autoMigrate is off: %v", err)
+	}
+
+	return nil
+}
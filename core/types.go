@@ -3,6 +3,7 @@ package core
 import (
 	"database/sql"
 	"net"
+	"time"
 
 	"github.com/frankiennamdi/detection-api/models"
 )
@@ -13,10 +14,29 @@ type EventRepository interface {
 	InsertAndFindRelatedEvents(event *models.Event, filter EventFilter) error
 }
 
+// receives the stored events of the user closest in time to the event being looked up, at least the nearest
+// earlier and the nearest later one when they exist
 type EventFilter interface {
 	Filter(event *models.Event)
 }
 
+// removal of events past their retention. Vacuum reclaims the space freed by deleted events where the store needs it
+type EventRetentionRepository interface {
+	FindExpiredEvents(rule models.RetentionRule, limit int) ([]*models.Event, error)
+	DeleteEvents(uuids []string) (int64, error)
+	Vacuum() error
+}
+
+// the data derived from events. PurgeDerivedData deletes what was derived from the events a retention rule expires
+type DerivedRetentionRepository interface {
+	PurgeDerivedData(rule models.RetentionRule) (int64, error)
+}
+
+// streams the events of an export to fnx in the order of their cursors
+type EventExportRepository interface {
+	ExportEvents(query models.ExportQuery, fnx func(event *models.Event) error) error
+}
+
 type IPGeoInfoRepository interface {
 	FindGeoPoint(IP net.IP) (*models.GeoPoint, error)
 }
@@ -30,3 +50,54 @@ type CalculatorService interface {
 	TimeDifferenceInHours(currentTimeStamp, previousTimeStamp int64) float64
 	SpeedToTravelDistanceInMPH(eventGeoInfoFrom, eventGeoInfoTo *models.EventGeoInfo) (*float64, error)
 }
+
+type AlertRepository interface {
+	InsertAlerts(alerts []*models.Alert) (int64, error)
+	FindAlerts(username string) ([]*models.Alert, error)
+	ExportAlerts(query models.ExportQuery, fnx func(alert *models.Alert) error) error
+}
+
+// the rolling risk of users. AddUserRisk adds the risk of the travels of an event to the stored risk of its user
+// in one step, each travel only once, and takes out the travel the event superseded
+type UserRiskRepository interface {
+	AddUserRisk(username string, travelRisks []models.TravelRisk, superseded *models.TravelRisk,
+		halfLife time.Duration) (*models.UserRisk, error)
+	FindUserRisk(username string) (*models.UserRisk, error)
+	FindTopUserRisks(limit int) ([]*models.UserRisk, error)
+}
+
+// the profiles of the locations users logged in from. ObserveLocation adds an event to the profile of its user once
+// and returns what the profile held about its location before
+type LocationProfileRepository interface {
+	ObserveLocation(username, geohash, country string, timestamp int64) (*models.LocationHistory, error)
+	FindUserLocations(username string) ([]*models.UserLocation, error)
+	ReplaceUserLocations(userLocations []*models.UserLocation, observations []*models.LocationObservation) error
+}
+
+type APIKeyRepository interface {
+	InsertAPIKey(apiKey *models.APIKey, secretHash string) error
+	FindAPIKey(id string) (*models.APIKey, string, error)
+	FindAPIKeys() ([]*models.APIKey, error)
+	RevokeAPIKey(id string) (bool, error)
+}
+
+type APIKeyService interface {
+	CreateAPIKey(name string, scopes []string) (*models.APIKey, string, error)
+	Authenticate(rawKey string) (*models.APIKey, error)
+	ListAPIKeys() ([]*models.APIKey, error)
+	RevokeAPIKey(id string) (bool, error)
+}
+
+type EventQueueRepository interface {
+	Enqueue(event *models.Event) (bool, error)
+	ClaimPending(limit int) ([]*models.QueuedEvent, error)
+	Complete(uuid string, result *models.SuspiciousTravelResult, processErr error, attempts int) error
+	PruneFinished(before int64) (int64, error)
+	ResetProcessing() (int64, error)
+	FindQueuedEvent(uuid string) (*models.QueuedEvent, error)
+}
+
+type AsyncDetectionService interface {
+	Submit(event *models.Event) error
+	FindResult(uuid string) (*models.QueuedEvent, error)
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	appConfig "github.com/frankiennamdi/detection-api/config"
+	embeddedMigrations "github.com/frankiennamdi/detection-api/migrations"
+	"github.com/frankiennamdi/detection-api/support"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/httpfs"
+)
+
+// the schema version of a db, 0 when no migration has been applied. dirty is set when a migration failed half way
+type MigrationVersion struct {
+	Version uint `json:"version"`
+	Dirty   bool `json:"dirty"`
+}
+
+// the schema version of a db next to the migrations available to it
+type MigrationStatus struct {
+	MigrationVersion
+	Latest  uint   `json:"latest"`
+	Pending []uint `json:"pending"`
+}
+
+// the migrations of a migration source over one db
+type Migrations struct {
+	migrate *migrate.Migrate
+	source  migrationSource
+}
+
+// opens the migrations of one folder, from the migration location when one is configured or else the ones embedded
+// in the binary
+type migrationSource struct {
+	config appConfig.EventDbConfig
+	dir    string
+}
+
+func sqLiteMigrationSource(config appConfig.AppConfig) migrationSource {
+	return migrationSource{config: config.EventDb, dir: "."}
+}
+
+func postgresMigrationSource(config appConfig.AppConfig) migrationSource {
+	return migrationSource{config: config.EventDb, dir: "postgres"}
+}
+
+func (migrationSource migrationSource) open() (source.Driver, error) {
+	if migrationSource.config.IsEmbeddedMigrations() {
+		return httpfs.New(http.FS(embeddedMigrations.FS), migrationSource.dir)
+	}
+
+	return source.Open(fmt.Sprintf("file://%s",
+		filepath.Join(support.Resolve(migrationSource.config.MigrationLoc), migrationSource.dir)))
+}
+
+func newMigrations(migrationSource migrationSource, databaseName string,
+	databaseDriver database.Driver) (*Migrations, error) {
+	sourceDriver, err := migrationSource.open()
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := migrate.NewWithInstance("migrations", sourceDriver, databaseName, databaseDriver)
+	if err != nil {
+		_ = sourceDriver.Close()
+		return nil, err
+	}
+
+	return &Migrations{migrate: migrations, source: migrationSource}, nil
+}
+
+// applies every pending migration
+func (migrations *Migrations) Up() error {
+	if err := migrations.migrate.Up(); err != nil && err != migrate.ErrNoChange {
+		return err
+	}
+
+	return nil
+}
+
+// rolls back the latest steps applied migrations
+func (migrations *Migrations) Down(steps int) error {
+	if steps <= 0 {
+		return support.NewIllegalArgumentError("steps must be greater than 0")
+	}
+
+	return migrations.migrate.Steps(-steps)
+}
+
+// migrates up or down to the version
+func (migrations *Migrations) Goto(version uint) error {
+	if err := migrations.migrate.Migrate(version); err != nil && err != migrate.ErrNoChange {
+		return err
+	}
+
+	return nil
+}
+
+// sets the version without running any migration and clears the dirty flag, to recover from a failed migration
+// once the schema was repaired by hand. -1 marks the db as having no migration applied
+func (migrations *Migrations) Force(version int) error {
+	return migrations.migrate.Force(version)
+}
+
+func (migrations *Migrations) Version() (MigrationVersion, error) {
+	version, dirty, err := migrations.migrate.Version()
+	if err == migrate.ErrNilVersion {
+		return MigrationVersion{}, nil
+	}
+
+	return MigrationVersion{Version: version, Dirty: dirty}, err
+}
+
+func (migrations *Migrations) Status() (*MigrationStatus, error) {
+	version, err := migrations.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := availableMigrations(migrations.source)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MigrationStatus{MigrationVersion: version, Pending: []uint{}}
+
+	for _, migration := range available {
+		status.Latest = migration
+
+		if migration > version.Version {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+
+	return status, nil
+}
+
+// fails unless every available migration is applied cleanly, for a server that does not migrate on start
+func (migrations *Migrations) RequireCurrent() error {
+	status, err := migrations.Status()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case status.Dirty:
+		return fmt.Errorf("schema version %d is dirty, a migration failed; repair it and run migrate force",
+			status.Version)
+	case status.Version < status.Latest:
+		return fmt.Errorf("schema version %d is behind the latest migration %d; run migrate up",
+			status.Version, status.Latest)
+	case status.Version > status.Latest:
+		return fmt.Errorf("schema version %d is newer than the latest migration %d of this build",
+			status.Version, status.Latest)
+	}
+
+	return nil
+}
+
+// the versions of the migrations at the source in ascending order
+func availableMigrations(migrationSource migrationSource) (versions []uint, err error) {
+	sourceDriver, err := migrationSource.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if closeErr := sourceDriver.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	version, err := sourceDriver.First()
+	for err == nil {
+		versions = append(versions, version)
+		version, err = sourceDriver.Next(version)
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return versions, nil
+}
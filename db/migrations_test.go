@@ -0,0 +1,207 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/support"
+	"github.com/stretchr/testify/require"
+)
+
+func newUnmigratedSqLiteDb(dir string) *SqLiteDb {
+	return NewSqLiteDb(config.AppConfig{
+		EventDb: config.EventDbConfig{
+			File:         filepath.Join(dir, "sqlite3.db"),
+			Name:         "event_db",
+			MigrationLoc: "migrations",
+		},
+	})
+}
+
+func sqLiteTables(t *testing.T, context *SqLiteDbContext) []string {
+	rows, err := context.Database().Query(
+		"SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, rows.Close())
+	}()
+
+	tables := []string{}
+
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+
+		tables = append(tables, name)
+	}
+
+	require.NoError(t, rows.Err())
+
+	return tables
+}
+
+func TestMigrations_Round_Trip_Up_And_Down(t *testing.T) {
+	temporaryDir := support.NewTemporaryDir("", "sqlite3-migrations-test")
+	defer temporaryDir.Clean()
+
+	req := require.New(t)
+	allTables := []string{"alerts", "api_keys", "event_queue", "events", "schema_migrations", "user_location_events",
+		"user_locations", "user_risk", "user_risk_travels"}
+
+	err := newUnmigratedSqLiteDb(temporaryDir.Path()).WithSqLiteDbContext(func(context *SqLiteDbContext) error {
+		return WithSqLiteMigrations(context, func(migrations *Migrations) error {
+			status, err := migrations.Status()
+			req.NoError(err)
+			req.Equal(uint(0), status.Version)
+			req.Equal(uint(11), status.Latest)
+			req.Equal([]uint{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, status.Pending)
+
+			for round := 0; round < 2; round++ {
+				req.NoError(migrations.Up())
+				req.Equal(allTables, sqLiteTables(t, context))
+
+				status, err = migrations.Status()
+				req.NoError(err)
+				req.Equal(uint(11), status.Version)
+				req.False(status.Dirty)
+				req.Empty(status.Pending)
+
+				// one step at a time, so every down migration runs on its own
+				for version := status.Latest; version > 0; version-- {
+					req.NoError(migrations.Down(1))
+
+					current, err := migrations.Version()
+					req.NoError(err)
+					req.Equal(version-1, current.Version)
+				}
+
+				req.Equal([]string{"schema_migrations"}, sqLiteTables(t, context))
+			}
+
+			req.NoError(migrations.Goto(3))
+			req.Equal([]string{"api_keys", "event_queue", "events", "schema_migrations"}, sqLiteTables(t, context))
+			req.NoError(migrations.Goto(11))
+			req.Equal(allTables, sqLiteTables(t, context))
+			req.Error(migrations.Down(0))
+
+			return nil
+		})
+	}, "mode=rwc")
+	req.NoError(err)
+}
+
+func TestMigrations_RequireCurrent(t *testing.T) {
+	temporaryDir := support.NewTemporaryDir("", "sqlite3-migrations-test")
+	defer temporaryDir.Clean()
+
+	req := require.New(t)
+
+	err := newUnmigratedSqLiteDb(temporaryDir.Path()).WithSqLiteDbContext(func(context *SqLiteDbContext) error {
+		return WithSqLiteMigrations(context, func(migrations *Migrations) error {
+			req.Contains(migrations.RequireCurrent().Error(), "run migrate up")
+
+			req.NoError(migrations.Goto(4))
+			req.Contains(migrations.RequireCurrent().Error(), "schema version 4 is behind the latest migration 11")
+
+			req.NoError(migrations.Up())
+			req.NoError(migrations.RequireCurrent())
+
+			_, err := context.Database().Exec("UPDATE schema_migrations SET dirty = 1")
+			req.NoError(err)
+			req.Contains(migrations.RequireCurrent().Error(), "is dirty")
+
+			req.NoError(migrations.Force(11))
+			req.NoError(migrations.RequireCurrent())
+
+			return nil
+		})
+	}, "mode=rwc")
+	req.NoError(err)
+}
+
+func TestMigrations_Embedded_Match_Migration_Location(t *testing.T) {
+	req := require.New(t)
+	embedded := config.AppConfig{EventDb: config.EventDbConfig{MigrationLoc: config.EmbeddedMigrationLoc}}
+	location := config.AppConfig{EventDb: config.EventDbConfig{MigrationLoc: "migrations"}}
+
+	for _, newSource := range []func(config config.AppConfig) migrationSource{
+		sqLiteMigrationSource, postgresMigrationSource} {
+		embeddedVersions, err := availableMigrations(newSource(embedded))
+		req.NoError(err)
+		req.NotEmpty(embeddedVersions)
+
+		locationVersions, err := availableMigrations(newSource(location))
+		req.NoError(err)
+		req.Equal(locationVersions, embeddedVersions)
+	}
+}
+
+func TestMigrations_Up_From_Embedded(t *testing.T) {
+	temporaryDir := support.NewTemporaryDir("", "sqlite3-migrations-test")
+	defer temporaryDir.Clean()
+
+	req := require.New(t)
+	db := NewSqLiteDb(config.AppConfig{
+		EventDb: config.EventDbConfig{
+			File: filepath.Join(temporaryDir.Path(), "sqlite3.db"),
+			Name: "event_db",
+		},
+	})
+
+	err := db.WithSqLiteDbContext(func(context *SqLiteDbContext) error {
+		return WithSqLiteMigrations(context, func(migrations *Migrations) error {
+			req.NoError(migrations.Up())
+			req.NoError(migrations.RequireCurrent())
+			req.Equal([]string{"alerts", "api_keys", "event_queue", "events", "schema_migrations",
+				"user_location_events", "user_locations", "user_risk", "user_risk_travels"}, sqLiteTables(t, context))
+
+			return nil
+		})
+	}, "mode=rwc")
+	req.NoError(err)
+}
+
+func TestMigrations_Down_From_Session_Columns_Keeps_Events(t *testing.T) {
+	temporaryDir := support.NewTemporaryDir("", "sqlite3-migrations-test")
+	defer temporaryDir.Clean()
+
+	req := require.New(t)
+
+	err := newUnmigratedSqLiteDb(temporaryDir.Path()).WithSqLiteDbContext(func(context *SqLiteDbContext) error {
+		return WithSqLiteMigrations(context, func(migrations *Migrations) error {
+			req.NoError(migrations.Goto(8))
+
+			_, err := context.Database().Exec("INSERT INTO events(uuid, username, timestamp, ip, session_id, " +
+				"event_type) VALUES('u1', 'bob', 1514764800, '1.0.0.0', 's1', 'logout')")
+			req.NoError(err)
+
+			req.NoError(migrations.Goto(7))
+
+			var username, ip string
+			req.NoError(context.Database().QueryRow("SELECT username, ip FROM events WHERE uuid = 'u1'").Scan(
+				&username, &ip))
+			req.Equal("bob", username)
+			req.Equal("1.0.0.0", ip)
+
+			_, err = context.Database().Exec("SELECT session_id FROM events")
+			req.Error(err)
+
+			// the indexes of the events table are rebuilt with it
+			var indexes int
+			req.NoError(context.Database().QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'index' AND " +
+				"name IN ('events_username_timestamp_unq', 'events_timestamp_uuid')").Scan(&indexes))
+			req.Equal(2, indexes)
+
+			_, err = context.Database().Exec("INSERT INTO events(uuid, username, timestamp, ip) " +
+				"VALUES('u2', 'bob', 1514764800, '2.0.0.0')")
+			req.Error(err)
+
+			req.NoError(migrations.Up())
+
+			return nil
+		})
+	}, "mode=rwc")
+	req.NoError(err)
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	appConfig "github.com/frankiennamdi/detection-api/config"
+	_ "github.com/lib/pq" // indirect
+)
+
+// provide services for PostgreSQL db. unlike SQLite the connection pool stays open for the life of the server
+// and postgres manages concurrent access, so no connection limiting is done here
+type PostgresDb struct {
+	config appConfig.AppConfig
+	db     *sql.DB
+}
+
+func NewPostgresDb(config appConfig.AppConfig) (*PostgresDb, error) {
+	db, err := sql.Open("postgres", config.EventDb.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	maxConnection := 20
+	if config.EventDb.MaxConnection > 0 {
+		maxConnection = config.EventDb.MaxConnection
+	}
+
+	db.SetMaxOpenConns(maxConnection)
+	db.SetMaxIdleConns(maxConnection)
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &PostgresDb{config: config, db: db}, nil
+}
+
+func (postgresDb *PostgresDb) WithTransaction(fnx TransactionEnabled) error {
+	tx, beginErr := postgresDb.db.Begin()
+	if beginErr != nil {
+		return beginErr
+	}
+
+	if fnxErr := fnx(tx); fnxErr != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return rollbackErr
+		}
+
+		return fnxErr
+	}
+
+	return tx.Commit()
+}
+
+func (postgresDb *PostgresDb) Database() *sql.DB {
+	return postgresDb.db
+}
+
+func (postgresDb *PostgresDb) Close() error {
+	return postgresDb.db.Close()
+}
+
+// applies the migrations in the postgres folder of the migration location
+func MigratePostgresUp(postgresDb *PostgresDb) error {
+	return WithPostgresMigrations(postgresDb, func(migrations *Migrations) error {
+		return migrations.Up()
+	})
+}
+
+// runs fnx with the migrations in the postgres folder of the migration location
+func WithPostgresMigrations(postgresDb *PostgresDb, fnx func(migrations *Migrations) error) (err error) {
+	conn, err := postgresDb.db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+
+	driver, err := newPostgresMigrationDriver(conn)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	migrations, err := newMigrations(postgresMigrationSource(postgresDb.config), postgresDb.config.EventDb.Name,
+		driver)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	defer func() {
+		if sourceErr, closeErr := migrations.migrate.Close(); sourceErr != nil || closeErr != nil {
+			if err == nil && sourceErr != nil {
+				err = sourceErr
+			} else if err == nil {
+				err = closeErr
+			}
+		}
+	}()
+
+	return fnx(migrations)
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang-migrate/migrate/v4/database"
+)
+
+// key of the advisory lock that keeps two servers from migrating the same database at once
+const postgresMigrationLockID = int64(7469540937)
+
+// golang-migrate database driver for postgres. it runs on a single connection because advisory locks are held
+// by the session that takes them
+type postgresMigrationDriver struct {
+	conn     *sql.Conn
+	isLocked bool
+}
+
+func newPostgresMigrationDriver(conn *sql.Conn) (*postgresMigrationDriver, error) {
+	_, err := conn.ExecContext(context.Background(), "CREATE TABLE IF NOT EXISTS schema_migrations "+
+		"(version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgresMigrationDriver{conn: conn}, nil
+}
+
+func (driver *postgresMigrationDriver) Open(url string) (database.Driver, error) {
+	return nil, fmt.Errorf("open is not supported, the driver is created from an existing connection")
+}
+
+func (driver *postgresMigrationDriver) Close() error {
+	return driver.conn.Close()
+}
+
+func (driver *postgresMigrationDriver) Lock() error {
+	if driver.isLocked {
+		return database.ErrLocked
+	}
+
+	if _, err := driver.conn.ExecContext(context.Background(), "SELECT pg_advisory_lock($1)",
+		postgresMigrationLockID); err != nil {
+		return err
+	}
+
+	driver.isLocked = true
+
+	return nil
+}
+
+func (driver *postgresMigrationDriver) Unlock() error {
+	if !driver.isLocked {
+		return nil
+	}
+
+	if _, err := driver.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)",
+		postgresMigrationLockID); err != nil {
+		return err
+	}
+
+	driver.isLocked = false
+
+	return nil
+}
+
+func (driver *postgresMigrationDriver) Run(migration io.Reader) error {
+	statements, err := ioutil.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+
+	_, err = driver.conn.ExecContext(context.Background(), string(statements))
+
+	return err
+}
+
+func (driver *postgresMigrationDriver) SetVersion(version int, dirty bool) error {
+	tx, err := driver.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("TRUNCATE schema_migrations"); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if version >= 0 {
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)",
+			version, dirty); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (driver *postgresMigrationDriver) Version() (int, bool, error) {
+	var version int
+
+	var dirty bool
+
+	err := driver.conn.QueryRowContext(context.Background(),
+		"SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return database.NilVersion, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	return version, dirty, nil
+}
+
+func (driver *postgresMigrationDriver) Drop() (err error) {
+	rows, err := driver.conn.QueryContext(context.Background(),
+		"SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema()")
+	if err != nil {
+		return err
+	}
+
+	var tables []string
+
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			_ = rows.Close()
+			return err
+		}
+
+		tables = append(tables, table)
+	}
+
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if _, err := driver.conn.ExecContext(context.Background(),
+			fmt.Sprintf("DROP TABLE IF EXISTS %q CASCADE", table)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
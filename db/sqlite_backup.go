@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	appConfig "github.com/frankiennamdi/detection-api/config"
+	"github.com/mattn/go-sqlite3"
+)
+
+// pause between attempts when the event db is locked by a writer during a backup
+const backupRetryInterval = 50 * time.Millisecond
+
+// copies a consistent snapshot of the event db to the file at path with the SQLite online backup api, while the
+// server keeps using the db. the snapshot is written next to path and only renamed into place once complete
+func (sqLiteDb *SqLiteDb) Backup(path string) error {
+	tempPath := path + ".tmp"
+	_ = os.Remove(tempPath)
+
+	err := sqLiteDb.WithSqLiteDbContext(func(context *SqLiteDbContext) error {
+		return backupSqLiteDb(context.Database(), tempPath)
+	}, "mode=ro")
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	return os.Rename(tempPath, path)
+}
+
+func backupSqLiteDb(sourceDb *sql.DB, path string) (err error) {
+	destinationDb, err := sql.Open("sqlite3", fmt.Sprintf("%s?mode=rwc", path))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if closeErr := destinationDb.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	sourceConn, err := sourceDb.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer sourceConn.Close()
+
+	destinationConn, err := destinationDb.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer destinationConn.Close()
+
+	return destinationConn.Raw(func(destinationDriverConn interface{}) error {
+		return sourceConn.Raw(func(sourceDriverConn interface{}) error {
+			destination, ok := destinationDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("backup requires a sqlite3 connection, got %T", destinationDriverConn)
+			}
+
+			backup, err := destination.Backup("main", sourceDriverConn.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+
+			// copying every page in one step keeps the snapshot consistent, a busy or locked source is retried
+			for {
+				done, err := backup.Step(-1)
+				if err != nil {
+					_ = backup.Finish()
+					return err
+				}
+
+				if done {
+					return backup.Finish()
+				}
+
+				time.Sleep(backupRetryInterval)
+			}
+		})
+	})
+}
+
+// version of the migrations applied to the SQLite database file at path
+func SqLiteMigrationVersion(path string) (version uint, dirty bool, err error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, false, err
+	}
+
+	database, err := sql.Open("sqlite3", fmt.Sprintf("%s?mode=ro", path))
+	if err != nil {
+		return 0, false, err
+	}
+
+	defer func() {
+		if closeErr := database.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	var integrity string
+	if err := database.QueryRow("PRAGMA integrity_check").Scan(&integrity); err != nil {
+		return 0, false, err
+	}
+
+	if integrity != "ok" {
+		return 0, false, fmt.Errorf("integrity check of %s failed: %s", path, integrity)
+	}
+
+	if err := database.QueryRow("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version,
+		&dirty); err != nil {
+		return 0, false, fmt.Errorf("%s is not a migrated event db: %v", path, err)
+	}
+
+	return version, dirty, nil
+}
+
+// the highest version among the migrations of the configured migration location
+func LatestMigrationVersion(config appConfig.AppConfig) (uint, error) {
+	versions, err := availableMigrations(sqLiteMigrationSource(config))
+	if err != nil || len(versions) == 0 {
+		return 0, err
+	}
+
+	return versions[len(versions)-1], nil
+}
+
+// replaces the event db file with the backup at path. the backup must pass an integrity check and carry no
+// migration newer than this build knows, older ones are migrated on the next start when eventDb.autoMigrate is on
+// or else with the migrate command. the server must be stopped, and the replaced db is kept with a .pre-restore
+// suffix
+func RestoreSqLiteDb(config appConfig.AppConfig, path string) error {
+	version, dirty, err := SqLiteMigrationVersion(path)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("backup %s has a failed migration at version %d", path, version)
+	}
+
+	latest, err := LatestMigrationVersion(config)
+	if err != nil {
+		return err
+	}
+
+	if version > latest {
+		return fmt.Errorf("backup %s is at migration version %d, newer than the latest known version %d",
+			path, version, latest)
+	}
+
+	dbFile := config.EventDb.File
+	tempPath := dbFile + ".restore"
+
+	if err := copyFile(path, tempPath); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	if _, err := os.Stat(dbFile); err == nil {
+		if err := os.Rename(dbFile, dbFile+".pre-restore"); err != nil {
+			return err
+		}
+	}
+
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(dbFile + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(tempPath, dbFile)
+}
+
+func copyFile(from, to string) (err error) {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+
+	defer source.Close()
+
+	if err := os.MkdirAll(filepath.Dir(to), 0750); err != nil {
+		return err
+	}
+
+	destination, err := os.OpenFile(to, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if closeErr := destination.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	if _, err := io.Copy(destination, source); err != nil {
+		return err
+	}
+
+	return destination.Sync()
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/support"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackup_Under_Concurrent_Writes(t *testing.T) {
+	temporaryDir := support.NewTemporaryDir("", "sqlite3-backup-test")
+	defer temporaryDir.Clean()
+
+	req := require.New(t)
+	db := newMigratedSqLiteDb(t, temporaryDir.Path())
+
+	var inserted int64
+
+	writeErrs := make(chan error, 4)
+	stop := make(chan struct{})
+	waitGroup := sync.WaitGroup{}
+
+	for writer := 0; writer < 4; writer++ {
+		waitGroup.Add(1)
+
+		go func(writer int) {
+			defer waitGroup.Done()
+
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				if err := insertBackupTestEvent(db, fmt.Sprintf("user-%d", writer), int64(i)); err != nil {
+					writeErrs <- err
+					return
+				}
+
+				atomic.AddInt64(&inserted, 1)
+			}
+		}(writer)
+	}
+
+	for i := 0; i < 5; i++ {
+		before := atomic.LoadInt64(&inserted)
+		path := filepath.Join(temporaryDir.Path(), fmt.Sprintf("backup-%d.db", i))
+		req.NoError(db.Backup(path))
+
+		after := atomic.LoadInt64(&inserted)
+
+		version, dirty, err := SqLiteMigrationVersion(path)
+		req.NoError(err)
+		req.False(dirty)
+		req.NotZero(version)
+
+		// each writer may have committed an insert it has not counted yet
+		count := countEvents(t, path)
+		req.True(count >= before && count <= after+4, "backup has %d events, expected %d to %d", count,
+			before, after)
+	}
+
+	close(stop)
+	waitGroup.Wait()
+	close(writeErrs)
+
+	for err := range writeErrs {
+		req.NoError(err)
+	}
+}
+
+func TestRestoreSqLiteDb(t *testing.T) {
+	temporaryDir := support.NewTemporaryDir("", "sqlite3-restore-test")
+	defer temporaryDir.Clean()
+
+	req := require.New(t)
+	db := newMigratedSqLiteDb(t, temporaryDir.Path())
+	backupPath := filepath.Join(temporaryDir.Path(), "backup.db")
+
+	req.NoError(insertBackupTestEvent(db, "john", 1))
+	req.NoError(db.Backup(backupPath))
+	req.NoError(insertBackupTestEvent(db, "john", 2))
+	req.NoError(RestoreSqLiteDb(db.config, backupPath))
+
+	req.Equal(int64(1), countEvents(t, db.config.EventDb.File))
+	req.Equal(int64(2), countEvents(t, db.config.EventDb.File+".pre-restore"))
+}
+
+func TestRestoreSqLiteDb_Rejects_Invalid_Backups(t *testing.T) {
+	temporaryDir := support.NewTemporaryDir("", "sqlite3-restore-test")
+	defer temporaryDir.Clean()
+
+	req := require.New(t)
+	db := newMigratedSqLiteDb(t, temporaryDir.Path())
+	backupPath := filepath.Join(temporaryDir.Path(), "backup.db")
+
+	req.Error(RestoreSqLiteDb(db.config, backupPath))
+
+	req.NoError(db.Backup(backupPath))
+	execOnFile(t, backupPath, "UPDATE schema_migrations SET version = 999")
+	req.Error(RestoreSqLiteDb(db.config, backupPath))
+
+	req.NoError(db.Backup(backupPath))
+	execOnFile(t, backupPath, "UPDATE schema_migrations SET dirty = 1")
+	req.Error(RestoreSqLiteDb(db.config, backupPath))
+
+	notADb := filepath.Join(temporaryDir.Path(), "not-a-db.db")
+	req.NoError(ioutil.WriteFile(notADb, []byte("not a database"), 0600))
+	req.Error(RestoreSqLiteDb(db.config, notADb))
+
+	_, err := os.Stat(db.config.EventDb.File + ".pre-restore")
+	req.True(os.IsNotExist(err))
+}
+
+func newMigratedSqLiteDb(t *testing.T, dir string) *SqLiteDb {
+	db := NewSqLiteDb(config.AppConfig{
+		EventDb: config.EventDbConfig{
+			File:         filepath.Join(dir, "sqlite3.db"),
+			Name:         "event_db",
+			MigrationLoc: "migrations",
+		},
+	})
+
+	require.NoError(t, db.WithSqLiteDbContext(func(context *SqLiteDbContext) error {
+		return MigrateUp(context)
+	}, "mode=rwc"))
+
+	return db
+}
+
+func insertBackupTestEvent(db *SqLiteDb, username string, timestamp int64) error {
+	return db.WithSqLiteDbContext(func(context *SqLiteDbContext) error {
+		_, err := context.Database().Exec("INSERT INTO events(uuid, username, timestamp, ip) VALUES(?, ?, ?, ?)",
+			fmt.Sprintf("%s-%d", username, timestamp), username, timestamp, "1.0.0.0")
+		return err
+	}, "mode=rw")
+}
+
+func countEvents(t *testing.T, path string) int64 {
+	var count int64
+
+	execOnFile(t, path, "", func(database *sql.DB) error {
+		return database.QueryRow("SELECT COUNT(*) FROM events").Scan(&count)
+	})
+
+	return count
+}
+
+func execOnFile(t *testing.T, path, statement string, queries ...func(database *sql.DB) error) {
+	req := require.New(t)
+	database, err := sql.Open("sqlite3", path)
+	req.NoError(err)
+
+	defer database.Close()
+
+	if statement != "" {
+		_, err = database.Exec(statement)
+		req.NoError(err)
+	}
+
+	for _, query := range queries {
+		req.NoError(query(database))
+	}
+}
@@ -4,22 +4,28 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"sync"
 
 	appConfig "github.com/frankiennamdi/detection-api/config"
 	"github.com/frankiennamdi/detection-api/support"
-	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file" // indirect
 )
 
 // provide services for SQLite db
 type SqLiteDb struct {
 	config                  appConfig.AppConfig
 	sqLiteDbConnectionLimit chan int
+	memoryDb                *sql.DB
+	memoryDbOnce            sync.Once
+	memoryDbErr             error
 }
 
 type SqLiteDbContext struct {
-	db     *sql.DB
-	config appConfig.AppConfig
+	db            *sql.DB
+	config        appConfig.AppConfig
+	memory        bool
+	inTransaction bool
 }
 
 type TransactionEnabled func(tx *sql.Tx) error
@@ -35,6 +41,10 @@ func NewSqLiteDb(config appConfig.AppConfig) *SqLiteDb {
 }
 
 func (sqLiteDb *SqLiteDb) WithSqLiteDbContext(fnx SQLiteDbRequired, options string) (err error) {
+	if sqLiteDb.config.EventDb.IsMemory() {
+		return sqLiteDb.withMemoryDbContext(fnx)
+	}
+
 	sqLiteDb.sqLiteDbConnectionLimit <- 1
 	resolvedPath := fmt.Sprintf("%s?%s", sqLiteDb.config.EventDb.File, options)
 	db, err := sql.Open("sqlite3", resolvedPath)
@@ -64,13 +74,48 @@ func (sqLiteDb *SqLiteDb) WithSqLiteDbContext(fnx SQLiteDbRequired, options stri
 	return nil
 }
 
+// with the memory driver the db is held in the process on a single connection that stays open until Close, there
+// is no file to open per call and the options do not apply. the calls share that connection, so fnx must not use
+// the db while it holds the connection: no Database() inside WithTransaction, which panics rather than waiting
+// forever, no query while the rows of another are still open, and no WithSqLiteDbContext nested in fnx
+func (sqLiteDb *SqLiteDb) withMemoryDbContext(fnx SQLiteDbRequired) error {
+	sqLiteDb.memoryDbOnce.Do(func() {
+		sqLiteDb.memoryDb, sqLiteDb.memoryDbErr = sql.Open("sqlite3", ":memory:")
+		if sqLiteDb.memoryDbErr == nil {
+			// every connection to :memory: opens a db of its own
+			sqLiteDb.memoryDb.SetMaxOpenConns(1)
+			sqLiteDb.memoryDb.SetMaxIdleConns(1)
+		}
+	})
+
+	if sqLiteDb.memoryDbErr != nil {
+		return sqLiteDb.memoryDbErr
+	}
+
+	return fnx(&SqLiteDbContext{db: sqLiteDb.memoryDb,
+		config: sqLiteDb.config,
+		memory: true,
+	})
+}
+
+// releases the db held in the process by the memory driver, a no-op for a db file
+func (sqLiteDb *SqLiteDb) Close() error {
+	if sqLiteDb.memoryDb == nil {
+		return nil
+	}
+
+	return sqLiteDb.memoryDb.Close()
+}
+
 func (sqLiteDbContext *SqLiteDbContext) WithTransaction(fnx TransactionEnabled) error {
 	tx, beginErr := sqLiteDbContext.db.Begin()
 	if beginErr != nil {
 		return beginErr
 	}
 
+	sqLiteDbContext.inTransaction = true
 	fnxErr := fnx(tx)
+	sqLiteDbContext.inTransaction = false
 
 	if fnxErr != nil {
 		rollbackErr := tx.Rollback()
@@ -90,6 +135,13 @@ func (sqLiteDbContext *SqLiteDbContext) WithTransaction(fnx TransactionEnabled)
 }
 
 func MigrateUp(dbContext *SqLiteDbContext) error {
+	return WithSqLiteMigrations(dbContext, func(migrations *Migrations) error {
+		return migrations.Up()
+	})
+}
+
+// runs fnx with the migrations of the migration location over the SQLite db of the context
+func WithSqLiteMigrations(dbContext *SqLiteDbContext, fnx func(migrations *Migrations) error) error {
 	driver, err := sqlite3.WithInstance(dbContext.db, &sqlite3.Config{
 		DatabaseName: dbContext.config.EventDb.Name,
 	})
@@ -97,26 +149,26 @@ func MigrateUp(dbContext *SqLiteDbContext) error {
 		return err
 	}
 
-	migrations, migrationInitErr := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", support.Resolve(dbContext.config.EventDb.MigrationLoc)),
+	migrations, migrationInitErr := newMigrations(sqLiteMigrationSource(dbContext.config),
 		dbContext.config.EventDb.Name, driver)
 
 	if migrationInitErr != nil {
 		return migrationInitErr
 	}
 
-	upgradeErr := migrations.Up()
-	if upgradeErr != nil {
-		return upgradeErr
-	}
-
-	return nil
+	return fnx(migrations)
 }
 
 func (sqLiteDbContext *SqLiteDbContext) AppConfig() appConfig.AppConfig {
 	return sqLiteDbContext.config
 }
 
+// the db of the context. it panics inside a transaction of the memory driver, whose only connection the
+// transaction holds
 func (sqLiteDbContext *SqLiteDbContext) Database() *sql.DB {
+	if sqLiteDbContext.memory && sqLiteDbContext.inTransaction {
+		log.Panicf(support.Fatal, "the db of the memory driver is used inside a transaction holding its only connection")
+	}
+
 	return sqLiteDbContext.db
 }
@@ -1,18 +1,21 @@
 package db
 
 import (
+	"database/sql"
 	"github.com/frankiennamdi/detection-api/config"
 	"github.com/frankiennamdi/detection-api/support"
 	"github.com/stretchr/testify/require"
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
 func TestWithSqLiteDbContext_When_Config_Is_Incomplete(t *testing.T) {
 	db := NewSqLiteDb(config.AppConfig{
 		EventDb:         config.EventDbConfig{
-			File: "bad",
+			File:         "bad",
+			MigrationLoc: "bad",
 		},
 		Server:          config.ServerConfig{},
 		IPGeoDbConfig:   config.IPGeoDbConfig{},
@@ -36,3 +39,90 @@ func TestWithSqLiteDbContext_When_Config_Is_Incomplete(t *testing.T) {
 	req := require.New(t)
 	req.Error(err)
 }
+
+func TestMigrateUp_When_Schema_Is_Current(t *testing.T) {
+	temporaryDir := support.NewTemporaryDir("", "sqlite3-migrate-test")
+	defer temporaryDir.Clean()
+
+	db := NewSqLiteDb(config.AppConfig{
+		EventDb: config.EventDbConfig{
+			File:         filepath.Join(temporaryDir.Path(), "sqlite3.db"),
+			Name:         "event_db",
+			MigrationLoc: "migrations",
+		},
+	})
+
+	req := require.New(t)
+
+	for i := 0; i < 2; i++ {
+		err := db.WithSqLiteDbContext(func(context *SqLiteDbContext) error {
+			return MigrateUp(context)
+		}, "mode=rwc")
+		req.NoError(err)
+	}
+}
+
+func TestWithSqLiteDbContext_When_Driver_Is_Memory(t *testing.T) {
+	temporaryDir := support.NewTemporaryDir("", "sqlite3-memory-test")
+	defer temporaryDir.Clean()
+
+	db := NewSqLiteDb(config.AppConfig{
+		EventDb: config.EventDbConfig{
+			Driver:       config.MemoryDriver,
+			File:         filepath.Join(temporaryDir.Path(), "sqlite3.db"),
+			Name:         "event_db",
+			MigrationLoc: "migrations",
+		},
+	})
+
+	req := require.New(t)
+
+	req.NoError(db.WithSqLiteDbContext(func(context *SqLiteDbContext) error {
+		if err := MigrateUp(context); err != nil {
+			return err
+		}
+
+		_, err := context.db.Exec("INSERT INTO api_keys (id, name, secret_hash, scopes, created_at) " +
+			"VALUES ('key', 'collector', 'hash', 'events:write', 0)")
+
+		return err
+	}, "mode=rwc"))
+
+	// the db outlives the call, and no file is written
+	var count int
+	req.NoError(db.WithSqLiteDbContext(func(context *SqLiteDbContext) error {
+		return context.db.QueryRow("SELECT COUNT(*) FROM api_keys").Scan(&count)
+	}, "mode=rw"))
+	req.Equal(1, count)
+
+	_, err := os.Stat(filepath.Join(temporaryDir.Path(), "sqlite3.db"))
+	req.True(os.IsNotExist(err))
+
+	req.NoError(db.Close())
+}
+
+func TestDatabase_Panics_In_Transaction_When_Driver_Is_Memory(t *testing.T) {
+	db := NewSqLiteDb(config.AppConfig{
+		EventDb: config.EventDbConfig{Driver: config.MemoryDriver, Name: "event_db", MigrationLoc: "migrations"},
+	})
+
+	req := require.New(t)
+
+	req.NoError(db.WithSqLiteDbContext(func(context *SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) error {
+			// the transaction holds the only connection, waiting for another would never return
+			req.Panics(func() {
+				context.Database()
+			})
+
+			return nil
+		})
+	}, "mode=rw"))
+
+	// outside of the transaction the connection is free again
+	req.NoError(db.WithSqLiteDbContext(func(context *SqLiteDbContext) error {
+		return context.Database().Ping()
+	}, "mode=rw"))
+
+	req.NoError(db.Close())
+}
@@ -5,8 +5,10 @@ require (
 	github.com/golang-migrate/migrate/v4 v4.9.1
 	github.com/google/uuid v1.1.1
 	github.com/gorilla/mux v1.7.4
+	github.com/lib/pq v1.3.0
+	github.com/mattn/go-sqlite3 v1.10.0
 	github.com/oschwald/geoip2-golang v1.4.0
 	github.com/stretchr/testify v1.5.1
 )
 
-go 1.13
+go 1.16
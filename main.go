@@ -1,44 +1,23 @@
 package main
 
 import (
-	"github.com/frankiennamdi/detection-api/core"
 	"log"
 	"os"
-	"os/signal"
-	"syscall"
 
-	"github.com/frankiennamdi/detection-api/app"
-	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/cli"
 	"github.com/frankiennamdi/detection-api/support"
 )
 
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	log.Printf(support.Info, "starting")
 
-	sigc := make(chan os.Signal, 1)
-	signal.Notify(sigc,
-		syscall.SIGHUP,
-		syscall.SIGINT,
-		syscall.SIGTERM,
-		syscall.SIGQUIT)
-
-	go func() {
-		sig := <-sigc
-		log.Printf(support.Info, sig)
-		log.Printf(support.Info, "goodbye")
-		os.Exit(0)
-	}()
-
-	appConfig := &config.AppConfig{}
-	err := appConfig.Read()
-
-	if err != nil {
-		log.Panicf(support.Fatal, err)
+	args := os.Args[1:]
+	if len(args) == 0 {
+		args = []string{"serve"}
 	}
 
-	server := core.NewServer(*appConfig)
-	serverContext := server.Configure()
-	serviceContext := app.NewServiceContext(serverContext)
-	serviceContext.Listen()
+	if err := cli.Run(args, os.Stdout); err != nil {
+		log.Printf(support.Error, err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,11 @@
+CREATE TABLE user_location_events (
+    username TEXT NOT NULL,
+    timestamp INTEGER NOT NULL,
+    geohash TEXT NOT NULL,
+    country TEXT NOT NULL,
+    profile_events INTEGER NOT NULL,
+    location_count INTEGER NOT NULL,
+    location_last_seen INTEGER NOT NULL,
+    country_count INTEGER NOT NULL,
+    PRIMARY KEY (username, timestamp)
+);
@@ -0,0 +1,20 @@
+CREATE TABLE event_queue_old (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid TEXT NOT NULL UNIQUE,
+    username TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL,
+    result TEXT,
+    error TEXT,
+    enqueued_at NUMERIC NOT NULL,
+    processed_at NUMERIC
+);
+
+INSERT INTO event_queue_old(seq, uuid, username, payload, status, result, error, enqueued_at, processed_at)
+SELECT seq, uuid, username, payload, status, result, error, enqueued_at, processed_at FROM event_queue;
+
+DROP TABLE event_queue;
+
+ALTER TABLE event_queue_old RENAME TO event_queue;
+
+CREATE INDEX event_queue_status_seq ON event_queue(status, seq);
@@ -0,0 +1 @@
+ALTER TABLE event_queue ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
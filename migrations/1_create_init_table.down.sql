@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS events_username_timestamp_unq;
+
+DROP TABLE IF EXISTS events;
@@ -0,0 +1,8 @@
+CREATE TABLE api_keys (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    secret_hash TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    created_at NUMERIC NOT NULL,
+    revoked INTEGER NOT NULL DEFAULT 0
+);
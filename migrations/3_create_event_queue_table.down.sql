@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS event_queue_status_seq;
+
+DROP TABLE IF EXISTS event_queue;
@@ -0,0 +1,13 @@
+CREATE TABLE event_queue (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid TEXT NOT NULL UNIQUE,
+    username TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL,
+    result TEXT,
+    error TEXT,
+    enqueued_at NUMERIC NOT NULL,
+    processed_at NUMERIC
+);
+
+CREATE INDEX event_queue_status_seq ON event_queue(status, seq);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS alerts_username_from_to_unq;
+
+DROP TABLE IF EXISTS alerts;
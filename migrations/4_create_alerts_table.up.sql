@@ -0,0 +1,17 @@
+CREATE TABLE alerts (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_uuid TEXT NOT NULL,
+    username TEXT NOT NULL,
+    from_timestamp INTEGER NOT NULL,
+    from_ip TEXT NOT NULL,
+    from_lat REAL NOT NULL,
+    from_lon REAL NOT NULL,
+    to_timestamp INTEGER NOT NULL,
+    to_ip TEXT NOT NULL,
+    to_lat REAL NOT NULL,
+    to_lon REAL NOT NULL,
+    speed REAL NOT NULL,
+    created_at NUMERIC NOT NULL
+);
+
+CREATE UNIQUE INDEX alerts_username_from_to_unq ON alerts(username, from_timestamp, to_timestamp);
@@ -0,0 +1 @@
+CREATE INDEX events_timestamp_uuid ON events(timestamp, uuid);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS user_risk_rank_key;
+
+DROP TABLE IF EXISTS user_risk;
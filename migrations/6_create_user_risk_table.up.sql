@@ -0,0 +1,11 @@
+CREATE TABLE user_risk (
+    username TEXT PRIMARY KEY,
+    score REAL NOT NULL,
+    as_of INTEGER NOT NULL,
+    rank_key REAL NOT NULL,
+    rule_hits INTEGER NOT NULL,
+    travels INTEGER NOT NULL,
+    updated_at NUMERIC NOT NULL
+);
+
+CREATE INDEX user_risk_rank_key ON user_risk(rank_key);
@@ -0,0 +1,15 @@
+CREATE TABLE events_old (
+    uuid TEXT PRIMARY KEY,
+    username TEXT NOT NULL,
+    timestamp NUMERIC NOT NULL,
+    ip  TEXT NOT NULL
+);
+
+INSERT INTO events_old(uuid, username, timestamp, ip) SELECT uuid, username, timestamp, ip FROM events;
+
+DROP TABLE events;
+
+ALTER TABLE events_old RENAME TO events;
+
+CREATE UNIQUE INDEX events_username_timestamp_unq ON events(username, timestamp);
+CREATE INDEX events_timestamp_uuid ON events(timestamp, uuid);
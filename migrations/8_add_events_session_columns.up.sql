@@ -0,0 +1,2 @@
+ALTER TABLE events ADD COLUMN session_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN event_type TEXT NOT NULL DEFAULT '';
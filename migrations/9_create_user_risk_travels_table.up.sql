@@ -0,0 +1,8 @@
+CREATE TABLE user_risk_travels (
+    username TEXT NOT NULL,
+    from_timestamp INTEGER NOT NULL,
+    to_timestamp INTEGER NOT NULL,
+    score REAL NOT NULL,
+    rule_hits INTEGER NOT NULL,
+    PRIMARY KEY (username, from_timestamp, to_timestamp)
+);
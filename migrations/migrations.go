@@ -0,0 +1,9 @@
+// the schema migrations of the event db, embedded so the binary runs without the migrations folder
+package migrations
+
+import "embed"
+
+// the SQLite migrations at the root and the migrations of the PostgreSQL event store in postgres
+//
+//go:embed *.sql postgres/*.sql
+var FS embed.FS
@@ -0,0 +1,2 @@
+ALTER TABLE events DROP COLUMN event_type;
+ALTER TABLE events DROP COLUMN session_id;
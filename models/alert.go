@@ -0,0 +1,69 @@
+package models
+
+// suspicious travel between two consecutive logins of a user, from the earlier to the later one. the event uuid is
+// the event whose processing raised the alert
+type Alert struct {
+	Seq           int64   `json:"seq"`
+	EventUUID     string  `json:"event_uuid"`
+	Username      string  `json:"username"`
+	FromTimestamp int64   `json:"from_timestamp"`
+	FromIP        string  `json:"from_ip"`
+	FromLatitude  float64 `json:"from_lat"`
+	FromLongitude float64 `json:"from_lon"`
+	ToTimestamp   int64   `json:"to_timestamp"`
+	ToIP          string  `json:"to_ip"`
+	ToLatitude    float64 `json:"to_lat"`
+	ToLongitude   float64 `json:"to_lon"`
+	Speed         float64 `json:"speed"`
+	CreatedAt     int64   `json:"created_at"`
+}
+
+// the alerts of the suspicious travels in the result of the event
+func NewAlerts(event *Event, result *SuspiciousTravelResult, createdAt int64) []*Alert {
+	var alerts []*Alert
+
+	if result == nil || result.CurrentGeo == nil {
+		return alerts
+	}
+
+	eventInfo := event.ToEventInfo()
+	current := RelatedAccessInfo{
+		IP:        eventInfo.IP,
+		Latitude:  result.CurrentGeo.Latitude,
+		Longitude: result.CurrentGeo.Longitude,
+		Timestamp: eventInfo.Timestamp,
+	}
+
+	if isTrue(result.TravelToCurrentGeoSuspicious) && result.PrecedingIPAccess != nil {
+		alerts = append(alerts, newAlert(eventInfo, *result.PrecedingIPAccess, current,
+			result.PrecedingIPAccess.Speed, createdAt))
+	}
+
+	if isTrue(result.TravelFromCurrentGeoSuspicious) && result.SubsequentIPAccess != nil {
+		alerts = append(alerts, newAlert(eventInfo, current, *result.SubsequentIPAccess,
+			result.SubsequentIPAccess.Speed, createdAt))
+	}
+
+	return alerts
+}
+
+func newAlert(eventInfo EventInfo, from, to RelatedAccessInfo, speed float64, createdAt int64) *Alert {
+	return &Alert{
+		EventUUID:     eventInfo.UUID,
+		Username:      eventInfo.Username,
+		FromTimestamp: from.Timestamp,
+		FromIP:        from.IP,
+		FromLatitude:  from.Latitude,
+		FromLongitude: from.Longitude,
+		ToTimestamp:   to.Timestamp,
+		ToIP:          to.IP,
+		ToLatitude:    to.Latitude,
+		ToLongitude:   to.Longitude,
+		Speed:         speed,
+		CreatedAt:     createdAt,
+	}
+}
+
+func isTrue(value *bool) bool {
+	return value != nil && *value
+}
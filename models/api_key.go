@@ -0,0 +1,65 @@
+package models
+
+import "strings"
+
+const (
+	ScopeEventsWrite = "events:write"
+	ScopeEventsRead  = "events:read"
+	ScopeAlertsRead  = "alerts:read"
+	ScopeAdmin       = "admin"
+)
+
+var knownScopes = []string{ScopeEventsWrite, ScopeEventsRead, ScopeAlertsRead, ScopeAdmin}
+
+// api key issued to a client. only the hash of the secret is stored, so the key itself can never be read back
+type APIKey struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt int64    `json:"created_at"`
+	Revoked   bool     `json:"revoked"`
+}
+
+// admin implicitly grants every other scope
+func (apiKey APIKey) HasScope(scope string) bool {
+	for _, granted := range apiKey.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+func IsValidScope(scope string) bool {
+	for _, known := range knownScopes {
+		if known == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ParseScopes(scopes string) ([]string, error) {
+	var result []string
+
+	for _, scope := range strings.Split(scopes, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+
+		if !IsValidScope(scope) {
+			return nil, NewValidationError(scope, "scope")
+		}
+
+		result = append(result, scope)
+	}
+
+	if len(result) == 0 {
+		return nil, NewValidationError(scopes, "scopes")
+	}
+
+	return result, nil
+}
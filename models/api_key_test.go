@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var parseScopesTestCases = []struct {
+	scopes         string
+	expectedScopes []string
+	expectedError  error
+}{
+	{scopes: "events:write", expectedScopes: []string{ScopeEventsWrite}, expectedError: nil},
+	{scopes: " events:write , alerts:read", expectedScopes: []string{ScopeEventsWrite, ScopeAlertsRead},
+		expectedError: nil},
+	{scopes: "events:write,root", expectedScopes: nil, expectedError: NewValidationError("root", "scope")},
+	{scopes: "", expectedScopes: nil, expectedError: NewValidationError("", "scopes")},
+}
+
+func TestParseScopes(t *testing.T) {
+	for _, input := range parseScopesTestCases {
+		req := require.New(t)
+		scopes, err := ParseScopes(input.scopes)
+		req.Equal(input.expectedError, err)
+		req.Equal(input.expectedScopes, scopes)
+	}
+}
+
+func TestAPIKeyHasScope(t *testing.T) {
+	req := require.New(t)
+
+	writer := APIKey{Scopes: []string{ScopeEventsWrite}}
+	req.True(writer.HasScope(ScopeEventsWrite))
+	req.False(writer.HasScope(ScopeAlertsRead))
+	req.False(writer.HasScope(ScopeAdmin))
+
+	admin := APIKey{Scopes: []string{ScopeAdmin}}
+	req.True(admin.HasScope(ScopeEventsWrite))
+	req.True(admin.HasScope(ScopeAlertsRead))
+	req.True(admin.HasScope(ScopeAdmin))
+}
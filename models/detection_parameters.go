@@ -0,0 +1,123 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// the bands of travel distance a speed threshold is applied to
+const (
+	SpeedBandGround = "ground"
+	SpeedBandFlight = "flight"
+)
+
+// a travel shorter than GroundMaxMiles is by ground and suspicious from GroundSpeed. a longer one is a flight,
+// suspicious when it is faster than the suspicious speed once FlightOverheadHours are added to the time in the air.
+// the zero value applies the suspicious speed to every travel
+type SpeedBands struct {
+	GroundMaxMiles      float64 `json:"groundMaxMiles"`
+	GroundSpeed         float64 `json:"groundSpeed"`
+	FlightOverheadHours float64 `json:"flightOverheadHours"`
+}
+
+// tunable parameters of the detection rules
+type DetectionParameters struct {
+	SuspiciousSpeed float64    `json:"suspiciousSpeed"`
+	SpeedBands      SpeedBands `json:"speedBands"`
+}
+
+// parameters to change, the ones left out keep their value
+type DetectionParametersOverride struct {
+	SuspiciousSpeed *float64    `json:"suspiciousSpeed,omitempty"`
+	SpeedBands      *SpeedBands `json:"speedBands,omitempty"`
+}
+
+func (parameters DetectionParameters) WithOverride(override DetectionParametersOverride) DetectionParameters {
+	if override.SuspiciousSpeed != nil {
+		parameters.SuspiciousSpeed = *override.SuspiciousSpeed
+	}
+
+	if override.SpeedBands != nil {
+		parameters.SpeedBands = *override.SpeedBands
+	}
+
+	return parameters
+}
+
+func (parameters DetectionParameters) Validate() error {
+	if parameters.SuspiciousSpeed <= 0 {
+		return NewValidationError(strconv.FormatFloat(parameters.SuspiciousSpeed, 'f', -1, 64), "suspiciousSpeed")
+	}
+
+	bands := parameters.SpeedBands
+	if bands.GroundMaxMiles < 0 {
+		return NewValidationError(strconv.FormatFloat(bands.GroundMaxMiles, 'f', -1, 64), "speedBands.groundMaxMiles")
+	}
+
+	if bands.GroundMaxMiles > 0 && bands.GroundSpeed <= 0 {
+		return NewValidationError(strconv.FormatFloat(bands.GroundSpeed, 'f', -1, 64), "speedBands.groundSpeed")
+	}
+
+	if bands.FlightOverheadHours < 0 {
+		return NewValidationError(strconv.FormatFloat(bands.FlightOverheadHours, 'f', -1, 64),
+			"speedBands.flightOverheadHours")
+	}
+
+	return nil
+}
+
+// whether travelling at the speed, in miles per hour, is suspicious
+func (parameters DetectionParameters) IsSuspiciousSpeed(speed float64) bool {
+	return speed >= parameters.SuspiciousSpeed
+}
+
+// whether travelling miles at the speed, in miles per hour, is suspicious under the threshold of its band
+func (parameters DetectionParameters) IsSuspiciousTravel(speed, miles float64) bool {
+	_, threshold := parameters.SpeedThreshold(miles)
+	return speed >= threshold
+}
+
+// the band of a travel over miles and the speed over the whole travel from which it is suspicious. the band is
+// empty when no bands are configured
+func (parameters DetectionParameters) SpeedThreshold(miles float64) (string, float64) {
+	bands := parameters.SpeedBands
+	if bands == (SpeedBands{}) {
+		return "", parameters.SuspiciousSpeed
+	}
+
+	if miles < bands.GroundMaxMiles {
+		return SpeedBandGround, bands.GroundSpeed
+	}
+
+	if bands.FlightOverheadHours <= 0 || miles <= 0 {
+		return SpeedBandFlight, parameters.SuspiciousSpeed
+	}
+
+	// the quickest the flight can be is the time in the air at the suspicious speed and the overhead
+	threshold := miles / (miles/parameters.SuspiciousSpeed + bands.FlightOverheadHours)
+
+	return SpeedBandFlight, math.Round(threshold*100) / 100
+}
+
+// the parameters that differ from the previous ones, as name: previous -> current
+func (parameters DetectionParameters) Diff(previous DetectionParameters) []string {
+	var changes []string
+
+	currentValue := reflect.ValueOf(parameters)
+	previousValue := reflect.ValueOf(previous)
+
+	for i := 0; i < currentValue.NumField(); i++ {
+		current := currentValue.Field(i).Interface()
+		before := previousValue.Field(i).Interface()
+
+		if !reflect.DeepEqual(current, before) {
+			name := strings.Split(currentValue.Type().Field(i).Tag.Get("json"), ",")[0]
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", name, before, current))
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectionParameters_Override_And_Diff(t *testing.T) {
+	req := require.New(t)
+	baseline := DetectionParameters{SuspiciousSpeed: 500}
+	speed := 300.0
+
+	req.Equal(baseline, baseline.WithOverride(DetectionParametersOverride{}))
+
+	candidate := baseline.WithOverride(DetectionParametersOverride{SuspiciousSpeed: &speed})
+	req.Equal(300.0, candidate.SuspiciousSpeed)
+	req.Equal([]string{"suspiciousSpeed: 500 -> 300"}, candidate.Diff(baseline))
+	req.Empty(baseline.Diff(baseline))
+
+	req.NoError(candidate.Validate())
+	req.Error(DetectionParameters{}.Validate())
+}
+
+func TestDetectionParameters_SpeedThreshold_Of_Distance_Bands(t *testing.T) {
+	req := require.New(t)
+	flat := DetectionParameters{SuspiciousSpeed: 500}
+	banded := DetectionParameters{SuspiciousSpeed: 500,
+		SpeedBands: SpeedBands{GroundMaxMiles: 300, GroundSpeed: 100, FlightOverheadHours: 2}}
+
+	band, threshold := flat.SpeedThreshold(200)
+	req.Equal("", band)
+	req.Equal(500.0, threshold)
+
+	band, threshold = banded.SpeedThreshold(200)
+	req.Equal(SpeedBandGround, band)
+	req.Equal(100.0, threshold)
+
+	// 1000 miles take 2 hours in the air and 2 hours of overhead
+	band, threshold = banded.SpeedThreshold(1000)
+	req.Equal(SpeedBandFlight, band)
+	req.Equal(250.0, threshold)
+
+	req.True(banded.IsSuspiciousTravel(150, 200))
+	req.False(flat.IsSuspiciousTravel(150, 200))
+	req.True(banded.IsSuspiciousTravel(300, 1000))
+
+	req.NoError(banded.Validate())
+
+	bands := SpeedBands{GroundMaxMiles: 300}
+	req.Error(flat.WithOverride(DetectionParametersOverride{SpeedBands: &bands}).Validate())
+	req.Equal([]string{"speedBands: {0 0 0} -> {300 100 2}"}, banded.Diff(flat))
+}
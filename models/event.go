@@ -8,20 +8,31 @@ import (
 	"github.com/google/uuid"
 )
 
+// types of an event, an event without a type is a login. a logout or a session end closes the session of the event
+const (
+	EventTypeLogin      = "login"
+	EventTypeLogout     = "logout"
+	EventTypeSessionEnd = "session_end"
+)
+
 // immutable event
 type Event struct {
 	uuid      string
 	username  string
 	timestamp int64
 	ip        string
+	sessionID string
+	eventType string
 }
 
-// mutable event info
+// mutable event info. the session id and the type are optional
 type EventInfo struct {
 	UUID      string `json:"event_uuid"`
 	Username  string `json:"username"`
 	Timestamp int64  `json:"unix_timestamp"`
 	IP        string `json:"ip_address"`
+	SessionID string `json:"session_id,omitempty"`
+	Type      string `json:"event_type,omitempty"`
 }
 
 type ValidationError struct {
@@ -52,9 +63,16 @@ func (event *Event) ToEventInfo() EventInfo {
 		event.username,
 		event.timestamp,
 		event.ip,
+		event.sessionID,
+		event.eventType,
 	}
 }
 
+// whether the event closes its session
+func (eventInfo EventInfo) EndsSession() bool {
+	return eventInfo.Type == EventTypeLogout || eventInfo.Type == EventTypeSessionEnd
+}
+
 func (event *Event) MarshalJSON() ([]byte, error) {
 	return json.Marshal(event.ToEventInfo())
 }
@@ -76,11 +94,19 @@ func NewEvent(eventInfo EventInfo) (*Event, error) {
 		return nil, NewValidationError(eventInfo.IP, "IP")
 	}
 
+	switch eventInfo.Type {
+	case "", EventTypeLogin, EventTypeLogout, EventTypeSessionEnd:
+	default:
+		return nil, NewValidationError(eventInfo.Type, "event_type")
+	}
+
 	return &Event{
 		uuid:      eventInfo.UUID,
 		username:  eventInfo.Username,
 		timestamp: eventInfo.Timestamp,
 		ip:        eventInfo.IP,
+		sessionID: eventInfo.SessionID,
+		eventType: eventInfo.Type,
 	}, nil
 }
 
@@ -40,6 +40,21 @@ var newEventTestsCases = []struct {
 		Timestamp: 1514764800,
 		IP:        "1.0.0.0",
 	}, expectedError: NewValidationError("85ad929a", "UUID")},
+	{eventInfo: EventInfo{
+		UUID:      "85ad929a-db03-4bf4-9541-8f728fa12e42",
+		Username:  "john",
+		Timestamp: 1514764800,
+		IP:        "1.0.0.0",
+		SessionID: "s1",
+		Type:      EventTypeLogout,
+	}, expectedError: nil},
+	{eventInfo: EventInfo{
+		UUID:      "85ad929a-db03-4bf4-9541-8f728fa12e42",
+		Username:  "john",
+		Timestamp: 1514764800,
+		IP:        "1.0.0.0",
+		Type:      "signup",
+	}, expectedError: NewValidationError("signup", "event_type")},
 }
 
 var newEventFromJSONTestCases = []struct {
@@ -70,6 +85,7 @@ func TestNewEvent(t *testing.T) {
 			req.Equal(input.eventInfo.IP, event.ip)
 			req.Equal(input.eventInfo.UUID, event.uuid)
 			req.Equal(input.eventInfo.Username, event.username)
+			req.Equal(input.eventInfo, event.ToEventInfo())
 		}
 	}
 }
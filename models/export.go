@@ -0,0 +1,62 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// filters and resumes an export. From is inclusive and To exclusive, zero leaves them open. After is the cursor of
+// the last record already received, the export continues with the record that follows it
+type ExportQuery struct {
+	Username string
+	From     int64
+	To       int64
+	After    string
+	Limit    int
+}
+
+// exported events are ordered by timestamp and uuid, the cursor of an event is timestamp:uuid
+func EventCursor(eventInfo EventInfo) string {
+	return fmt.Sprintf("%d:%s", eventInfo.Timestamp, eventInfo.UUID)
+}
+
+func ParseEventCursor(cursor string) (int64, string, error) {
+	parts := strings.SplitN(cursor, ":", 2)
+	if len(parts) != 2 {
+		return 0, "", NewValidationError(cursor, "cursor")
+	}
+
+	timestamp, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", NewValidationError(cursor, "cursor")
+	}
+
+	return timestamp, parts[1], nil
+}
+
+// exported alerts are ordered by seq, which is their cursor
+func ParseAlertCursor(cursor string) (int64, error) {
+	seq, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil {
+		return 0, NewValidationError(cursor, "cursor")
+	}
+
+	return seq, nil
+}
+
+// unix seconds or RFC 3339
+func ParseTimestamp(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if timestamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return timestamp, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, NewValidationError(value, "timestamp")
+	}
+
+	return parsed.Unix(), nil
+}
@@ -0,0 +1,54 @@
+package models
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// longest geohash, about 4 cm wide
+const MaxGeohashPrecision = 12
+
+// the geohash cell of precision characters that contains the point. a precision of 4 is about 39 by 20 km, 5 about
+// 5 by 5 km
+func Geohash(latitude, longitude float64, precision int) string {
+	if precision <= 0 {
+		return ""
+	}
+
+	if precision > MaxGeohashPrecision {
+		precision = MaxGeohashPrecision
+	}
+
+	latitudeRange := [2]float64{-90, 90}
+	longitudeRange := [2]float64{-180, 180}
+	hash := make([]byte, 0, precision)
+	even := true
+	bit, index := 0, 0
+
+	for len(hash) < precision {
+		if even {
+			index = bisect(&longitudeRange, longitude, index)
+		} else {
+			index = bisect(&latitudeRange, latitude, index)
+		}
+
+		even = !even
+
+		if bit++; bit == 5 {
+			hash = append(hash, geohashAlphabet[index])
+			bit, index = 0, 0
+		}
+	}
+
+	return string(hash)
+}
+
+// halves the range towards value and appends the half taken to index
+func bisect(valueRange *[2]float64, value float64, index int) int {
+	middle := (valueRange[0] + valueRange[1]) / 2
+	if value >= middle {
+		valueRange[0] = middle
+		return index<<1 | 1
+	}
+
+	valueRange[1] = middle
+
+	return index << 1
+}
@@ -0,0 +1,95 @@
+package models
+
+import "math"
+
+// kinds of locations in the profile of a user
+const (
+	LocationKindCell    = "cell"
+	LocationKindCountry = "country"
+)
+
+// a location the user logged in from, a geohash cell or a country, with the number of events from it and the
+// timestamps of the first and the latest one
+type UserLocation struct {
+	Username  string `json:"username"`
+	Kind      string `json:"kind"`
+	Location  string `json:"location"`
+	Count     int64  `json:"count"`
+	FirstSeen int64  `json:"first_seen"`
+	LastSeen  int64  `json:"last_seen"`
+}
+
+// what the profile of a user held about the location of an event before the event was added to it. the country
+// is empty when the location has none
+type LocationHistory struct {
+	Geohash          string
+	Country          string
+	ProfileEvents    int64
+	LocationCount    int64
+	LocationLastSeen int64
+	CountryCount     int64
+}
+
+// an event added to the profile of its user, with what the profile held about its location before it. kept so each
+// event is added once and an event processed again gets the same history
+type LocationObservation struct {
+	Username  string
+	Timestamp int64
+	LocationHistory
+}
+
+// how new the location of an event is to its user. nothing is flagged until the profile holds minEvents events, and
+// the confidence grows with the size of the profile and, for a rare location, with how rare it is
+type LocationNovelty struct {
+	Geohash           string  `json:"geohash"`
+	Country           string  `json:"country,omitempty"`
+	FirstSeenLocation bool    `json:"firstSeenLocation"`
+	FirstSeenCountry  bool    `json:"firstSeenCountry"`
+	RareLocation      bool    `json:"rareLocation"`
+	Confidence        float64 `json:"confidence"`
+	LocationCount     int64   `json:"locationCount"`
+	LocationLastSeen  int64   `json:"locationLastSeen,omitempty"`
+	ProfileEvents     int64   `json:"profileEvents"`
+}
+
+// a location seen in less than rareShare of the events of the profile is rare
+func NewLocationNovelty(history LocationHistory, minEvents int64, rareShare float64) *LocationNovelty {
+	novelty := &LocationNovelty{
+		Geohash:          history.Geohash,
+		Country:          history.Country,
+		LocationCount:    history.LocationCount,
+		LocationLastSeen: history.LocationLastSeen,
+		ProfileEvents:    history.ProfileEvents,
+	}
+
+	if history.ProfileEvents <= 0 || history.ProfileEvents < minEvents {
+		return novelty
+	}
+
+	profileEvents := float64(history.ProfileEvents)
+	profileConfidence := profileEvents / (profileEvents + float64(minEvents))
+	share := float64(history.LocationCount) / profileEvents
+
+	novelty.FirstSeenLocation = history.LocationCount == 0
+	novelty.FirstSeenCountry = history.Country != "" && history.CountryCount == 0
+	novelty.RareLocation = !novelty.FirstSeenLocation && share < rareShare
+
+	switch {
+	case novelty.FirstSeenLocation || novelty.FirstSeenCountry:
+		novelty.Confidence = profileConfidence
+	case novelty.RareLocation:
+		novelty.Confidence = profileConfidence * (1 - share/rareShare)
+	}
+
+	novelty.Confidence = math.Round(novelty.Confidence*100) / 100
+
+	return novelty
+}
+
+// the numbers of a rebuild of the location profiles from the stored events
+type LocationProfileReport struct {
+	Users            int64 `json:"users"`
+	Events           int64 `json:"events"`
+	UnknownLocations int64 `json:"unknown_locations"`
+	Locations        int64 `json:"locations"`
+}
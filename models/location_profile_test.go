@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeohash(t *testing.T) {
+	req := require.New(t)
+
+	req.Equal("u4pruydqqvj", Geohash(57.64911, 10.40744, 11))
+	req.Equal("dr5r", Geohash(40.7128, -74.0060, 4))
+	req.Equal("9q5c", Geohash(34.0522, -118.2437, 4))
+	req.Equal("", Geohash(40.7128, -74.0060, 0))
+	req.Len(Geohash(40.7128, -74.0060, 20), MaxGeohashPrecision)
+}
+
+func TestLocationNovelty(t *testing.T) {
+	req := require.New(t)
+
+	learning := NewLocationNovelty(LocationHistory{Geohash: "dr5r", ProfileEvents: 4}, 5, 0.05)
+	req.Equal(&LocationNovelty{Geohash: "dr5r", ProfileEvents: 4}, learning)
+
+	firstSeen := NewLocationNovelty(LocationHistory{Geohash: "dr5r", Country: "US", ProfileEvents: 15,
+		CountryCount: 0}, 5, 0.05)
+	req.True(firstSeen.FirstSeenLocation)
+	req.True(firstSeen.FirstSeenCountry)
+	req.False(firstSeen.RareLocation)
+	req.Equal(0.75, firstSeen.Confidence)
+
+	rare := NewLocationNovelty(LocationHistory{Geohash: "dr5r", Country: "US", ProfileEvents: 95,
+		LocationCount: 1, LocationLastSeen: 1514764800, CountryCount: 90}, 5, 0.05)
+	req.False(rare.FirstSeenLocation)
+	req.False(rare.FirstSeenCountry)
+	req.True(rare.RareLocation)
+	req.Equal(0.75, rare.Confidence)
+	req.Equal(int64(1514764800), rare.LocationLastSeen)
+
+	usual := NewLocationNovelty(LocationHistory{Geohash: "dr5r", Country: "US", ProfileEvents: 95,
+		LocationCount: 60, CountryCount: 90}, 5, 0.05)
+	req.False(usual.FirstSeenLocation || usual.FirstSeenCountry || usual.RareLocation)
+	req.Equal(0.0, usual.Confidence)
+}
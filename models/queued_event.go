@@ -0,0 +1,22 @@
+package models
+
+const (
+	QueueStatusPending    = "pending"
+	QueueStatusProcessing = "processing"
+	QueueStatusDone       = "done"
+	QueueStatusFailed     = "failed"
+)
+
+// an event submitted for asynchronous detection, the result is set once it is processed. Error is the error of the
+// latest failed attempt
+type QueuedEvent struct {
+	Seq         int64                   `json:"-"`
+	Event       *Event                  `json:"-"`
+	UUID        string                  `json:"event_uuid"`
+	Status      string                  `json:"status"`
+	Attempts    int                     `json:"attempts,omitempty"`
+	Result      *SuspiciousTravelResult `json:"result,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+	EnqueuedAt  int64                   `json:"enqueued_at"`
+	ProcessedAt int64                   `json:"processed_at,omitempty"`
+}
@@ -0,0 +1,45 @@
+package models
+
+const (
+	RescoreStatusRunning = "running"
+	RescoreStatusDone    = "done"
+	RescoreStatusFailed  = "failed"
+)
+
+// alerts of a user under the baseline and the candidate parameters, only users whose alerts changed are reported
+type RescoreUserCounts struct {
+	Username        string `json:"username"`
+	BaselineAlerts  int64  `json:"baseline_alerts"`
+	CandidateAlerts int64  `json:"candidate_alerts"`
+	NewAlerts       int64  `json:"new_alerts"`
+	RemovedAlerts   int64  `json:"removed_alerts"`
+}
+
+// the difference between the alerts raised over the stored history by the baseline and the candidate parameters.
+// new alerts are raised only by the candidate and removed alerts only by the baseline. the lists are capped, the
+// counts are not
+type RescoreReport struct {
+	Users            int64                `json:"users"`
+	Events           int64                `json:"events"`
+	Pairs            int64                `json:"pairs"`
+	UnknownLocations int64                `json:"unknown_locations"`
+	BaselineAlerts   int64                `json:"baseline_alerts"`
+	CandidateAlerts  int64                `json:"candidate_alerts"`
+	NewAlerts        []*Alert             `json:"new_alerts"`
+	RemovedAlerts    []*Alert             `json:"removed_alerts"`
+	Truncated        bool                 `json:"truncated"`
+	ChangedUsers     []*RescoreUserCounts `json:"changed_users"`
+}
+
+// a rescore run in the background, the report is set once it is done
+type RescoreJob struct {
+	ID         string              `json:"id"`
+	Status     string              `json:"status"`
+	Username   string              `json:"username,omitempty"`
+	Baseline   DetectionParameters `json:"baseline"`
+	Candidate  DetectionParameters `json:"candidate"`
+	Report     *RescoreReport      `json:"report,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	StartedAt  int64               `json:"started_at"`
+	FinishedAt int64               `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// value of the tenant retention setting when no tenant has its own window
+const NoTenantRetention = "none"
+
+// the tenant of a user is the domain of the username, users without a domain belong to no tenant
+func TenantOf(username string) string {
+	if index := strings.LastIndex(username, "@"); index >= 0 {
+		return strings.ToLower(username[index+1:])
+	}
+
+	return ""
+}
+
+// how long events are kept, in days. a window of zero or less keeps the events forever
+type RetentionPolicy struct {
+	Days       int
+	TenantDays map[string]int
+}
+
+// selects the events a retention policy expires. an empty tenant applies to the users of every tenant
+// that is not excluded, as they follow the default window
+type RetentionRule struct {
+	Tenant          string
+	ExcludedTenants []string
+	Before          int64
+}
+
+// parses comma separated tenant:days pairs, e.g. example.com:30,example.org:365
+func ParseTenantRetention(value string) (map[string]int, error) {
+	tenantDays := make(map[string]int)
+	if strings.TrimSpace(value) == NoTenantRetention {
+		return tenantDays, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, NewValidationError(entry, "tenant retention")
+		}
+
+		days, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, NewValidationError(entry, "tenant retention")
+		}
+
+		tenantDays[strings.ToLower(strings.TrimSpace(parts[0]))] = days
+	}
+
+	return tenantDays, nil
+}
+
+// the rules that expire events older than the windows of the policy at the given time
+func (policy RetentionPolicy) Rules(now time.Time) []RetentionRule {
+	var rules []RetentionRule
+
+	tenants := make([]string, 0, len(policy.TenantDays))
+	for tenant := range policy.TenantDays {
+		tenants = append(tenants, tenant)
+	}
+
+	sort.Strings(tenants)
+
+	for _, tenant := range tenants {
+		if days := policy.TenantDays[tenant]; days > 0 {
+			rules = append(rules, RetentionRule{Tenant: tenant, Before: retentionCutoff(now, days)})
+		}
+	}
+
+	if policy.Days > 0 {
+		rules = append(rules, RetentionRule{ExcludedTenants: tenants, Before: retentionCutoff(now, policy.Days)})
+	}
+
+	return rules
+}
+
+// whether the rule applies to the events of the user
+func (rule RetentionRule) Matches(username string) bool {
+	tenant := TenantOf(username)
+	if rule.Tenant != "" {
+		return tenant == rule.Tenant
+	}
+
+	for _, excluded := range rule.ExcludedTenants {
+		if tenant == excluded {
+			return false
+		}
+	}
+
+	return true
+}
+
+func retentionCutoff(now time.Time, days int) int64 {
+	return now.Add(-time.Duration(days) * 24 * time.Hour).Unix()
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var parseTenantRetentionTestCases = []struct {
+	value              string
+	expectedTenantDays map[string]int
+	expectedError      error
+}{
+	{value: "none", expectedTenantDays: map[string]int{}, expectedError: nil},
+	{value: "Example.com:30, example.org:365", expectedTenantDays: map[string]int{"example.com": 30,
+		"example.org": 365}, expectedError: nil},
+	{value: "example.com", expectedTenantDays: nil, expectedError: NewValidationError("example.com",
+		"tenant retention")},
+	{value: "example.com:thirty", expectedTenantDays: nil, expectedError: NewValidationError("example.com:thirty",
+		"tenant retention")},
+}
+
+func TestParseTenantRetention(t *testing.T) {
+	req := require.New(t)
+
+	for _, testCase := range parseTenantRetentionTestCases {
+		tenantDays, err := ParseTenantRetention(testCase.value)
+		req.Equal(testCase.expectedError, err, testCase.value)
+		req.Equal(testCase.expectedTenantDays, tenantDays, testCase.value)
+	}
+}
+
+func TestTenantOf(t *testing.T) {
+	req := require.New(t)
+	req.Equal("example.com", TenantOf("john@Example.com"))
+	req.Equal("", TenantOf("john"))
+}
+
+func TestRetentionPolicyRules(t *testing.T) {
+	req := require.New(t)
+	now := time.Unix(1514764800, 0)
+	policy := RetentionPolicy{Days: 10, TenantDays: map[string]int{"example.com": 1, "example.org": 0}}
+
+	rules := policy.Rules(now)
+	req.Len(rules, 2)
+	req.Equal(RetentionRule{Tenant: "example.com", Before: now.Add(-24 * time.Hour).Unix()}, rules[0])
+	req.Equal(RetentionRule{ExcludedTenants: []string{"example.com", "example.org"},
+		Before: now.Add(-240 * time.Hour).Unix()}, rules[1])
+
+	req.True(rules[0].Matches("john@example.com"))
+	req.False(rules[0].Matches("john@example.org"))
+	req.False(rules[1].Matches("john@example.org"))
+	req.True(rules[1].Matches("john"))
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// weights of the risk of a travel between two logins
+const (
+	// travel below this share of the suspicious speed adds no risk
+	RiskBorderlineRatio = 0.5
+	// the share of the suspicious speed counted at most, so one absurd hop does not outweigh a series of them
+	RiskMaxSpeedRatio = 10.0
+	// added for a travel that hits the suspicious speed rule
+	RiskRuleHitWeight = 5.0
+	// shorter travels count in proportion to their distance, their speed is mostly the inaccuracy of the locations
+	RiskFullWeightMiles = 100.0
+)
+
+// the risk of the travels of one event
+type EventRisk struct {
+	Score    float64
+	RuleHits int64
+	Travels  int64
+}
+
+// adds the risk of a travel of speed over miles, suspicious when it hit the suspicious speed rule
+func (eventRisk *EventRisk) AddTravel(speed, miles, suspiciousSpeed float64, suspicious bool) {
+	eventRisk.Travels++
+
+	if suspicious {
+		eventRisk.RuleHits++
+	}
+
+	if suspiciousSpeed <= 0 || speed <= 0 || miles <= 0 {
+		return
+	}
+
+	ratio := math.Min(speed/suspiciousSpeed, RiskMaxSpeedRatio)
+	if ratio < RiskBorderlineRatio {
+		return
+	}
+
+	score := ratio
+	if suspicious {
+		score += RiskRuleHitWeight
+	}
+
+	eventRisk.Score += math.Round(score*math.Min(miles/RiskFullWeightMiles, 1)*100) / 100
+}
+
+// the risk of the travel between two logins of a user, known by their timestamps. it is added to the risk of the
+// user at the later login, once however often the logins are processed
+type TravelRisk struct {
+	From     int64
+	To       int64
+	Score    float64
+	RuleHits int64
+}
+
+// the rolling risk of a user, the risk of their travels halved every half life. the score is as of the latest
+// scored event, ScoreAt decays it to another time
+type UserRisk struct {
+	Username  string  `json:"username"`
+	Score     float64 `json:"score"`
+	AsOf      int64   `json:"as_of"`
+	RuleHits  int64   `json:"rule_hits"`
+	Travels   int64   `json:"travels"`
+	UpdatedAt int64   `json:"updated_at"`
+}
+
+// adds the risk of an event at timestamp. the risk of an event older than the score is decayed to the score
+func (userRisk *UserRisk) Add(eventRisk EventRisk, timestamp int64, halfLife time.Duration) {
+	if timestamp >= userRisk.AsOf {
+		userRisk.Score = userRisk.ScoreAt(timestamp, halfLife) + eventRisk.Score
+		userRisk.AsOf = timestamp
+	} else {
+		userRisk.Score += eventRisk.Score * decay(userRisk.AsOf-timestamp, halfLife)
+	}
+
+	userRisk.Score = math.Round(userRisk.Score*100) / 100
+	userRisk.RuleHits += eventRisk.RuleHits
+	userRisk.Travels += eventRisk.Travels
+}
+
+// takes out the risk of a travel added before, such as one between logins that are no longer next to each other.
+// the score and the counts do not go below zero
+func (userRisk *UserRisk) Remove(travelRisk TravelRisk, halfLife time.Duration) {
+	userRisk.Add(EventRisk{Score: -travelRisk.Score, RuleHits: -travelRisk.RuleHits, Travels: -1}, travelRisk.To,
+		halfLife)
+	userRisk.Score = math.Max(userRisk.Score, 0)
+	userRisk.RuleHits = max64(userRisk.RuleHits, 0)
+	userRisk.Travels = max64(userRisk.Travels, 0)
+}
+
+// the score decayed to timestamp, a timestamp before the score leaves it as it is
+func (userRisk UserRisk) ScoreAt(timestamp int64, halfLife time.Duration) float64 {
+	if timestamp <= userRisk.AsOf {
+		return userRisk.Score
+	}
+
+	return userRisk.Score * decay(timestamp-userRisk.AsOf, halfLife)
+}
+
+// orders users like their scores decayed to any one time, log2 of the score plus the half lives passed since epoch
+func (userRisk UserRisk) RankKey(halfLife time.Duration) float64 {
+	if userRisk.Score <= 0 || halfLife <= 0 {
+		return -math.MaxFloat64
+	}
+
+	return math.Log2(userRisk.Score) + float64(userRisk.AsOf)/halfLife.Seconds()
+}
+
+// the user risk with the score decayed to now, as returned by the risk endpoints
+type CurrentUserRisk struct {
+	UserRisk
+	CurrentScore float64 `json:"current_score"`
+}
+
+func NewCurrentUserRisk(userRisk *UserRisk, now int64, halfLife time.Duration) *CurrentUserRisk {
+	return &CurrentUserRisk{
+		UserRisk:     *userRisk,
+		CurrentScore: math.Round(userRisk.ScoreAt(now, halfLife)*100) / 100,
+	}
+}
+
+// the risk of the event and of its user after it, returned with the result of the event
+type RiskResult struct {
+	EventScore   float64 `json:"eventScore"`
+	UserScore    float64 `json:"userScore"`
+	UserRuleHits int64   `json:"userRuleHits"`
+}
+
+func decay(seconds int64, halfLife time.Duration) float64 {
+	if halfLife <= 0 {
+		return 1
+	}
+
+	return math.Pow(0.5, float64(seconds)/halfLife.Seconds())
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventRisk_Weighs_Speed_Distance_And_Rule_Hits(t *testing.T) {
+	req := require.New(t)
+
+	eventRisk := EventRisk{}
+	eventRisk.AddTravel(200, 1000, 500, false)
+	req.Equal(EventRisk{Travels: 1}, eventRisk)
+
+	eventRisk.AddTravel(400, 1000, 500, false)
+	req.Equal(EventRisk{Score: 0.8, Travels: 2}, eventRisk)
+
+	borderline := EventRisk{}
+	borderline.AddTravel(450, 2000, 500, false)
+
+	impossible := EventRisk{}
+	impossible.AddTravel(2000, 2000, 500, true)
+	req.Equal(EventRisk{Score: 9, RuleHits: 1, Travels: 1}, impossible)
+	req.True(impossible.Score > 5*borderline.Score)
+
+	capped := EventRisk{}
+	capped.AddTravel(500000, 2000, 500, true)
+	req.Equal(RiskMaxSpeedRatio+RiskRuleHitWeight, capped.Score)
+
+	short := EventRisk{}
+	short.AddTravel(2000, 25, 500, true)
+	req.Equal(2.25, short.Score)
+}
+
+func TestUserRisk_Decays_Over_Half_Lives(t *testing.T) {
+	req := require.New(t)
+	halfLife := 24 * time.Hour
+	day := int64(24 * 3600)
+
+	userRisk := UserRisk{Username: "bob"}
+	userRisk.Add(EventRisk{Score: 8, RuleHits: 1, Travels: 1}, day, halfLife)
+	req.Equal(8.0, userRisk.Score)
+	req.Equal(day, userRisk.AsOf)
+
+	userRisk.Add(EventRisk{Score: 2, Travels: 1}, 3*day, halfLife)
+	req.Equal(4.0, userRisk.Score)
+	req.Equal(3*day, userRisk.AsOf)
+
+	// an event older than the score adds its risk as decayed to the score
+	userRisk.Add(EventRisk{Score: 8, RuleHits: 1, Travels: 1}, 2*day, halfLife)
+	req.Equal(8.0, userRisk.Score)
+	req.Equal(3*day, userRisk.AsOf)
+	req.Equal(int64(2), userRisk.RuleHits)
+	req.Equal(int64(3), userRisk.Travels)
+
+	req.Equal(4.0, userRisk.ScoreAt(4*day, halfLife))
+	req.Equal(8.0, userRisk.ScoreAt(day, halfLife))
+	req.Equal(4.0, NewCurrentUserRisk(&userRisk, 4*day, halfLife).CurrentScore)
+}
+
+func TestUserRisk_RankKey_Orders_Like_Current_Score(t *testing.T) {
+	req := require.New(t)
+	halfLife := 24 * time.Hour
+	day := int64(24 * 3600)
+
+	old := UserRisk{Score: 16, AsOf: day}
+	recent := UserRisk{Score: 5, AsOf: 3 * day}
+
+	req.True(old.ScoreAt(3*day, halfLife) < recent.ScoreAt(3*day, halfLife))
+	req.True(old.RankKey(halfLife) < recent.RankKey(halfLife))
+	req.True(UserRisk{}.RankKey(halfLife) < old.RankKey(halfLife))
+}
+
+func TestUserRisk_Remove_Takes_Out_Decayed_Travel(t *testing.T) {
+	req := require.New(t)
+	halfLife := 24 * time.Hour
+	day := int64(24 * 3600)
+
+	userRisk := UserRisk{}
+	userRisk.Add(EventRisk{Score: 8, RuleHits: 1, Travels: 1}, day, halfLife)
+	userRisk.Add(EventRisk{Score: 2, Travels: 1}, 2*day, halfLife)
+
+	userRisk.Remove(TravelRisk{From: 0, To: day, Score: 8, RuleHits: 1}, halfLife)
+	req.Equal(UserRisk{Score: 2, AsOf: 2 * day, Travels: 1}, userRisk)
+
+	userRisk.Remove(TravelRisk{From: day, To: 2 * day, Score: 5, RuleHits: 1}, halfLife)
+	req.Equal(UserRisk{AsOf: 2 * day}, userRisk)
+}
@@ -0,0 +1,45 @@
+package models
+
+import "sort"
+
+// another session of a user that was open at the time of an event, as of its latest event, Miles from the location
+// of the event
+type ConcurrentSession struct {
+	SessionID string  `json:"sessionId"`
+	IP        string  `json:"ip"`
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lon"`
+	Timestamp int64   `json:"timestamp"`
+	Miles     float64 `json:"miles"`
+}
+
+// the latest event of every session but sessionID among the events, when it does not end its session, in timestamp
+// order. events without a session are left out
+func OpenSessions(events []*Event, sessionID string) []*Event {
+	latest := make(map[string]*Event)
+
+	for _, event := range events {
+		eventInfo := event.ToEventInfo()
+		if eventInfo.SessionID == "" || eventInfo.SessionID == sessionID {
+			continue
+		}
+
+		if previous, ok := latest[eventInfo.SessionID]; !ok || previous.ToEventInfo().Timestamp <= eventInfo.Timestamp {
+			latest[eventInfo.SessionID] = event
+		}
+	}
+
+	open := make([]*Event, 0, len(latest))
+
+	for _, event := range latest {
+		if !event.ToEventInfo().EndsSession() {
+			open = append(open, event)
+		}
+	}
+
+	sort.Slice(open, func(i, j int) bool {
+		return open[i].ToEventInfo().Timestamp < open[j].ToEventInfo().Timestamp
+	})
+
+	return open
+}
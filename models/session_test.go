@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSessions_Leaves_Out_Ended_And_Own_Sessions(t *testing.T) {
+	req := require.New(t)
+
+	var events []*Event
+
+	for i, eventInfo := range []EventInfo{
+		{SessionID: "a"},
+		{SessionID: "b"},
+		{},
+		{SessionID: "c"},
+		{SessionID: "a", Type: EventTypeLogin},
+		{SessionID: "c", Type: EventTypeSessionEnd},
+		{SessionID: "d", Type: EventTypeLogout},
+		{SessionID: "e"},
+	} {
+		eventInfo.UUID = uuid.New().String()
+		eventInfo.Username = "bob"
+		eventInfo.Timestamp = int64(i)
+		eventInfo.IP = "1.0.0.0"
+
+		event, err := NewEvent(eventInfo)
+		req.NoError(err)
+
+		events = append(events, event)
+	}
+
+	var sessions []string
+
+	for _, event := range OpenSessions(events, "e") {
+		sessions = append(sessions, event.ToEventInfo().SessionID)
+	}
+
+	req.Equal([]string{"b", "a"}, sessions)
+	req.Equal(int64(4), OpenSessions(events, "b")[0].ToEventInfo().Timestamp)
+}
@@ -0,0 +1,14 @@
+package models
+
+// the verdict of one event of a simulation, the error is set when the event could not be processed
+type SimulationStep struct {
+	Event  EventInfo               `json:"event"`
+	Result *SuspiciousTravelResult `json:"result,omitempty"`
+	Error  string                  `json:"error,omitempty"`
+}
+
+// the verdicts of the events of a simulation in the order they were processed
+type SimulationResult struct {
+	Parameters DetectionParameters `json:"parameters"`
+	Timeline   []*SimulationStep   `json:"timeline"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import "math"
+
+// the miles in a kilometer, for the accuracy radius of a location
+const MilesPerKm = 0.621371
+
+// a located event of a user on the way through a window of their events. Miles is the distance from the stop before
+// and Radius the accuracy radius of the location in km
+type PathStop struct {
+	IP        string  `json:"ip"`
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lon"`
+	Radius    uint16  `json:"radius"`
+	Timestamp int64   `json:"timestamp"`
+	Miles     float64 `json:"miles"`
+}
+
+// a sequence of stops that cannot be travelled in the time between its first and last stop, wherever within their
+// accuracy radius the locations are. Miles is the least distance of the itinerary through the stops, Speed is left
+// out when the first and the last stop share a timestamp
+type ImpossiblePath struct {
+	Stops []*PathStop `json:"stops"`
+	Miles float64     `json:"miles"`
+	Hours float64     `json:"hours"`
+	Speed *float64    `json:"speed,omitempty"`
+}
+
+// the longest sequence of the stops, in timestamp order, that ends at the last stop and whose itinerary is only
+// travelled at a suspicious speed, nil when there is none. directMiles holds the distance from each stop to the
+// last one. the least distance of the itinerary is the larger of two lower bounds: the distance from its first stop
+// straight to the last, and the sum of its hops, each less the radius of the stops at both of its ends
+func FindImpossiblePath(stops []*PathStop, directMiles []float64, parameters DetectionParameters) *ImpossiblePath {
+	if len(stops) < 2 || len(directMiles) != len(stops) {
+		return nil
+	}
+
+	last := stops[len(stops)-1]
+
+	// the least distance of the hops from each stop on to the last one
+	hopMiles := make([]float64, len(stops))
+	for i := len(stops) - 2; i >= 0; i-- {
+		hopMiles[i] = hopMiles[i+1] + withinRadius(stops[i+1].Miles, stops[i], stops[i+1])
+	}
+
+	for first := 0; first < len(stops)-1; first++ {
+		miles := math.Max(withinRadius(directMiles[first], stops[first], last), hopMiles[first])
+		miles = math.Round(miles*100) / 100
+		hours := float64(last.Timestamp-stops[first].Timestamp) / 3600
+
+		var path *ImpossiblePath
+
+		if hours > 0 {
+			speed := math.Round(miles / hours)
+			if parameters.IsSuspiciousTravel(speed, miles) {
+				path = &ImpossiblePath{Miles: miles, Hours: hours, Speed: &speed}
+			}
+		} else if miles > 0 {
+			path = &ImpossiblePath{Miles: miles}
+		}
+
+		if path != nil {
+			// the path starts at its first stop, the distance to it is not part of the path
+			start := *stops[first]
+			start.Miles = 0
+			path.Stops = append([]*PathStop{&start}, stops[first+1:]...)
+
+			return path
+		}
+	}
+
+	return nil
+}
+
+// the least distance between two stops the miles apart, when each can be anywhere within its radius
+func withinRadius(miles float64, from, to *PathStop) float64 {
+	return math.Max(miles-(float64(from.Radius)+float64(to.Radius))*MilesPerKm, 0)
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindImpossiblePath_Flags_Path_Whose_Hops_Pass(t *testing.T) {
+	req := require.New(t)
+	parameters := DetectionParameters{SuspiciousSpeed: 500}
+	// the third stop is known to 400 km, about 249 miles
+	stops := []*PathStop{
+		{IP: "1.0.0.0", Timestamp: 0},
+		{IP: "2.0.0.0", Timestamp: 10 * 3600, Miles: 400},
+		{IP: "3.0.0.0", Timestamp: 11 * 3600, Radius: 400, Miles: 550},
+		{IP: "4.0.0.0", Timestamp: 12 * 3600, Miles: 550},
+	}
+
+	// every hop is under the suspicious speed for a location within the radius of the third stop
+	for i := 1; i < len(stops); i++ {
+		req.Nil(FindImpossiblePath(stops[i-1:i+1], []float64{stops[i].Miles, 0}, parameters))
+	}
+
+	// but the third stop cannot be near both the second and the fourth, they are 1080 miles in 2 hours apart
+	path := FindImpossiblePath(stops, []float64{1400, 1080, 550, 0}, parameters)
+	req.NotNil(path)
+	req.Len(path.Stops, 3)
+	req.Equal("2.0.0.0", path.Stops[0].IP)
+	req.Equal(0.0, path.Stops[0].Miles)
+	req.Equal(400.0, stops[1].Miles)
+	req.Equal(1080.0, path.Miles)
+	req.Equal(2.0, path.Hours)
+	req.Equal(540.0, *path.Speed)
+
+	// 900 miles in 2 hours is a plausible pace
+	req.Nil(FindImpossiblePath(stops, []float64{1400, 900, 550, 0}, parameters))
+}
+
+func TestFindImpossiblePath_Flags_Path_Whose_Direct_Travel_Passes(t *testing.T) {
+	req := require.New(t)
+	parameters := DetectionParameters{SuspiciousSpeed: 500}
+	// the user is back where they started, but went 1000 miles away and back in between
+	stops := []*PathStop{
+		{IP: "1.0.0.0", Timestamp: 0, Radius: 10},
+		{IP: "2.0.0.0", Timestamp: 3600, Radius: 10, Miles: 1000},
+		{IP: "3.0.0.0", Timestamp: 2 * 3600, Radius: 10, Miles: 1000},
+	}
+
+	req.Nil(FindImpossiblePath([]*PathStop{stops[0], stops[2]}, []float64{0, 0}, parameters))
+
+	// each hop is allowed the accuracy of both of its stops, 1000 miles less 20 km
+	path := FindImpossiblePath(stops, []float64{0, 1000, 0}, parameters)
+	req.NotNil(path)
+	req.Len(path.Stops, 3)
+	req.Equal("1.0.0.0", path.Stops[0].IP)
+	req.Equal(1975.15, path.Miles)
+	req.Equal(2.0, path.Hours)
+	req.Equal(988.0, *path.Speed)
+
+	// over more time the same itinerary is plausible
+	stops[2].Timestamp = 5 * 3600
+	req.Nil(FindImpossiblePath(stops, []float64{0, 1000, 0}, parameters))
+}
+
+func TestFindImpossiblePath_Allows_Accuracy_Of_Both_Stops(t *testing.T) {
+	req := require.New(t)
+	parameters := DetectionParameters{SuspiciousSpeed: 500}
+	stops := []*PathStop{{Timestamp: 0, Radius: 100}, {Timestamp: 3600, Radius: 100, Miles: 600}}
+
+	// 600 miles less 200 km of accuracy is about 476 miles
+	req.Nil(FindImpossiblePath(stops, []float64{600, 0}, parameters))
+
+	stops[1].Radius = 50
+	path := FindImpossiblePath(stops, []float64{600, 0}, parameters)
+	req.NotNil(path)
+	req.Equal(506.79, path.Miles)
+	req.Equal(507.0, *path.Speed)
+}
+
+func TestFindImpossiblePath_Flags_Distant_Stops_Of_One_Timestamp(t *testing.T) {
+	req := require.New(t)
+	parameters := DetectionParameters{SuspiciousSpeed: 500}
+
+	req.Nil(FindImpossiblePath([]*PathStop{{Timestamp: 3600}}, []float64{0}, parameters))
+	req.Nil(FindImpossiblePath([]*PathStop{{Timestamp: 3600}, {Timestamp: 3600}}, []float64{0, 0}, parameters))
+
+	path := FindImpossiblePath([]*PathStop{{Timestamp: 3600}, {Timestamp: 3600, Miles: 10}}, []float64{10, 0},
+		parameters)
+	req.NotNil(path)
+	req.Nil(path.Speed)
+	req.Equal(10.0, path.Miles)
+
+	// within the accuracy of the locations
+	req.Nil(FindImpossiblePath([]*PathStop{{Timestamp: 3600, Radius: 10}, {Timestamp: 3600, Radius: 10, Miles: 10}},
+		[]float64{10, 0}, parameters))
+}
@@ -50,14 +50,19 @@ type GeoPoint struct {
 	Latitude       float64 `json:"lat"`
 	Longitude      float64 `json:"lon"`
 	AccuracyRadius uint16  `json:"radius"`
+	Country        string  `json:"country,omitempty"`
 }
 
 type SuspiciousTravelResult struct {
-	CurrentGeo                     *GeoPoint          `json:"currentGeo,omitempty"`
-	TravelToCurrentGeoSuspicious   *bool              `json:"travelToCurrentGeoSuspicious,omitempty"`
-	TravelFromCurrentGeoSuspicious *bool              `json:"travelFromCurrentGeoSuspicious,omitempty"`
-	PrecedingIPAccess              *RelatedAccessInfo `json:"precedingIpAccess,omitempty"`
-	SubsequentIPAccess             *RelatedAccessInfo `json:"subsequentIpAccess,omitempty"`
+	CurrentGeo                     *GeoPoint            `json:"currentGeo,omitempty"`
+	TravelToCurrentGeoSuspicious   *bool                `json:"travelToCurrentGeoSuspicious,omitempty"`
+	TravelFromCurrentGeoSuspicious *bool                `json:"travelFromCurrentGeoSuspicious,omitempty"`
+	PrecedingIPAccess              *RelatedAccessInfo   `json:"precedingIpAccess,omitempty"`
+	SubsequentIPAccess             *RelatedAccessInfo   `json:"subsequentIpAccess,omitempty"`
+	Risk                           *RiskResult          `json:"risk,omitempty"`
+	Novelty                        *LocationNovelty     `json:"novelty,omitempty"`
+	ImpossiblePath                 *ImpossiblePath      `json:"impossiblePath,omitempty"`
+	ConcurrentSessions             []*ConcurrentSession `json:"concurrentSessions,omitempty"`
 }
 
 type RelatedAccessInfo struct {
@@ -67,4 +72,6 @@ type RelatedAccessInfo struct {
 	Longitude      float64 `json:"lon"`
 	AccuracyRadius uint16  `json:"radius"`
 	Timestamp      int64   `json:"timestamp"`
+	Band           string  `json:"band,omitempty"`
+	Threshold      float64 `json:"threshold"`
 }
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+// builds the query of the events of an export, in the order of their cursors. placeholder returns the parameter
+// marker of the dialect for the 1 based position
+func exportEventsQuery(exportQuery models.ExportQuery, placeholder func(position int) string) (string,
+	[]interface{}, error) {
+	var conditions []string
+
+	var args []interface{}
+
+	addCondition := func(format string, values ...interface{}) {
+		markers := make([]interface{}, 0, len(values))
+
+		for _, value := range values {
+			args = append(args, value)
+			markers = append(markers, placeholder(len(args)))
+		}
+
+		conditions = append(conditions, fmt.Sprintf(format, markers...))
+	}
+
+	if exportQuery.Username != "" {
+		addCondition("username = %s", exportQuery.Username)
+	}
+
+	if exportQuery.From != 0 {
+		addCondition("timestamp >= %s", exportQuery.From)
+	}
+
+	if exportQuery.To != 0 {
+		addCondition("timestamp < %s", exportQuery.To)
+	}
+
+	if exportQuery.After != "" {
+		timestamp, uuid, err := models.ParseEventCursor(exportQuery.After)
+		if err != nil {
+			return "", nil, err
+		}
+
+		addCondition("(timestamp > %s OR (timestamp = %s AND uuid > %s))", timestamp, timestamp, uuid)
+	}
+
+	query := strings.Builder{}
+	query.WriteString("SELECT uuid, username, timestamp, ip, session_id, event_type FROM events")
+
+	if len(conditions) > 0 {
+		query.WriteString(" WHERE ")
+		query.WriteString(strings.Join(conditions, " AND "))
+	}
+
+	query.WriteString(" ORDER BY timestamp ASC, uuid ASC")
+
+	if exportQuery.Limit > 0 {
+		args = append(args, exportQuery.Limit)
+		query.WriteString(" LIMIT ")
+		query.WriteString(placeholder(len(args)))
+	}
+
+	return query.String(), args, nil
+}
+
+// passes every event of the export to fnx while the rows are read, an error of fnx ends the export
+func exportEvents(db queryer, exportQuery models.ExportQuery, placeholder func(position int) string,
+	fnx func(event *models.Event) error) error {
+	query, args, err := exportEventsQuery(exportQuery, placeholder)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+
+	return forEachEventRow(rows, fnx)
+}
+
+func forEachEventRow(rows *sql.Rows, fnx func(event *models.Event) error) (err error) {
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		var eventInfo models.EventInfo
+		if err := rows.Scan(&eventInfo.UUID, &eventInfo.Username, &eventInfo.Timestamp, &eventInfo.IP,
+			&eventInfo.SessionID, &eventInfo.Type); err != nil {
+			return err
+		}
+
+		event, err := models.NewEvent(eventInfo)
+		if err != nil {
+			return err
+		}
+
+		if err := fnx(event); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/config"
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// postgres contract tests only run when this points at a database the tests may truncate
+const postgresTestDSNEnv = "EVENT_DB_TEST_POSTGRES_DSN"
+
+// the repositories under contract
+type contractEventRepository interface {
+	core.EventRepository
+	core.EventRetentionRepository
+	core.EventExportRepository
+}
+
+// creates an empty repository and returns it with its clean up
+type eventRepositoryFactory func(t *testing.T) (contractEventRepository, func())
+
+func TestSQLiteEventsRepository_Contract(t *testing.T) {
+	runEventRepositoryContract(t, func(t *testing.T) (contractEventRepository, func()) {
+		testSetup := test.SetUpWithFileDb()
+		return NewSQLLiteEventsRepository(testSetup.AppServerContext().EventDb()), testSetup.CleanUp
+	})
+}
+
+func TestSQLiteEventsRepository_In_Memory_Db_Contract(t *testing.T) {
+	runEventRepositoryContract(t, func(t *testing.T) (contractEventRepository, func()) {
+		testSetup := test.SetUp()
+		return NewSQLLiteEventsRepository(testSetup.AppServerContext().EventDb()), testSetup.CleanUp
+	})
+}
+
+func TestMemoryEventsRepository_Contract(t *testing.T) {
+	runEventRepositoryContract(t, func(t *testing.T) (contractEventRepository, func()) {
+		return NewMemoryEventsRepository(), func() {}
+	})
+}
+
+func TestPostgresEventsRepository_Contract(t *testing.T) {
+	dsn := os.Getenv(postgresTestDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresTestDSNEnv)
+	}
+
+	runEventRepositoryContract(t, func(t *testing.T) (contractEventRepository, func()) {
+		testSetup := test.SetUpWithConfig(func(appConfig *config.AppConfig) {
+			appConfig.EventDb.Driver = config.PostgresDriver
+			appConfig.EventDb.DSN = dsn
+		})
+		postgresDb := testSetup.AppServerContext().PostgresDb()
+		_, err := postgresDb.Database().Exec("TRUNCATE events")
+		require.NoError(t, err)
+
+		return NewPostgresEventsRepository(postgresDb), func() {
+			_ = postgresDb.Close()
+			testSetup.CleanUp()
+		}
+	})
+}
+
+// behaviour every event store must share so the detection service works the same on each of them
+func runEventRepositoryContract(t *testing.T, newRepository eventRepositoryFactory) {
+	initialTime := int64(1514764800)
+
+	t.Run("insert reports affected rows", func(t *testing.T) {
+		eventRepository, cleanUp := newRepository(t)
+		defer cleanUp()
+
+		req := require.New(t)
+		event := newTestEvent(models.EventInfo{UUID: uuid.New().String(), Username: "john",
+			Timestamp: initialTime, IP: "1.0.0.0"})
+		results, err := eventRepository.InsertEvents([]*models.Event{event, event})
+		req.NoError(err)
+		req.Len(results, 2)
+
+		rows, err := results[0].RowsAffected()
+		req.NoError(err)
+		req.Equal(int64(1), rows)
+
+		rows, err = results[1].RowsAffected()
+		req.NoError(err)
+		req.Equal(int64(0), rows)
+	})
+
+	t.Run("duplicate uuid keeps the original", func(t *testing.T) {
+		eventRepository, cleanUp := newRepository(t)
+		defer cleanUp()
+
+		req := require.New(t)
+		eventUUID := uuid.New().String()
+		events := []*models.Event{
+			newTestEvent(models.EventInfo{UUID: eventUUID, Username: "john", Timestamp: initialTime, IP: "1.0.0.0"}),
+			newTestEvent(models.EventInfo{UUID: eventUUID, Username: "john",
+				Timestamp: test.AddTime(initialTime, 1, time.Hour), IP: "1.0.0.0"}),
+		}
+		_, err := eventRepository.InsertEvents(events)
+		req.NoError(err)
+
+		filter := NewRelatedEventsFilter(events[0])
+		req.NoError(eventRepository.FindRelatedEvents(events[0], filter))
+		req.Nil(filter.GetRelatedEvents().SubsequentEvent)
+		req.Equal(events[0].ToEventInfo(), filter.GetRelatedEvents().CurrentEvent.ToEventInfo())
+	})
+
+	t.Run("duplicate user and timestamp keeps the original", func(t *testing.T) {
+		eventRepository, cleanUp := newRepository(t)
+		defer cleanUp()
+
+		req := require.New(t)
+		events := []*models.Event{
+			newTestEvent(models.EventInfo{UUID: uuid.New().String(), Username: "john", Timestamp: initialTime,
+				IP: "1.0.0.0"}),
+			newTestEvent(models.EventInfo{UUID: uuid.New().String(), Username: "john", Timestamp: initialTime,
+				IP: "2.0.0.0"}),
+		}
+		_, err := eventRepository.InsertEvents(events)
+		req.NoError(err)
+
+		filter := NewRelatedEventsFilter(events[1])
+		req.NoError(eventRepository.FindRelatedEvents(events[1], filter))
+		req.Nil(filter.GetRelatedEvents().PreviousEvent)
+		req.Nil(filter.GetRelatedEvents().SubsequentEvent)
+	})
+
+	t.Run("neighbours are found in timestamp order for the same user only", func(t *testing.T) {
+		eventRepository, cleanUp := newRepository(t)
+		defer cleanUp()
+
+		req := require.New(t)
+		events := []*models.Event{
+			newTestEvent(models.EventInfo{UUID: uuid.New().String(), Username: "john",
+				Timestamp: test.AddTime(initialTime, 10, time.Hour), IP: "1.0.0.0"}),
+			newTestEvent(models.EventInfo{UUID: uuid.New().String(), Username: "john",
+				Timestamp: test.AddTime(initialTime, -10, time.Hour), IP: "1.0.0.0"}),
+			newTestEvent(models.EventInfo{UUID: uuid.New().String(), Username: "jane",
+				Timestamp: test.AddTime(initialTime, -1, time.Hour), IP: "1.0.0.0"}),
+		}
+		_, err := eventRepository.InsertEvents(events)
+		req.NoError(err)
+
+		current := newTestEvent(models.EventInfo{UUID: uuid.New().String(), Username: "john",
+			Timestamp: initialTime, IP: "1.0.0.0"})
+		filter := NewRelatedEventsFilter(current)
+		req.NoError(eventRepository.InsertAndFindRelatedEvents(current, filter))
+		req.Equal(events[1].ToEventInfo(), filter.GetRelatedEvents().PreviousEvent.ToEventInfo())
+		req.Equal(events[0].ToEventInfo(), filter.GetRelatedEvents().SubsequentEvent.ToEventInfo())
+		req.Equal(current.ToEventInfo(), filter.GetRelatedEvents().CurrentEvent.ToEventInfo())
+	})
+
+	t.Run("neighbours match a scan of the whole history", func(t *testing.T) {
+		eventRepository, cleanUp := newRepository(t)
+		defer cleanUp()
+
+		req := require.New(t)
+
+		var events []*models.Event
+
+		for _, offset := range rand.Perm(200) {
+			events = append(events, newTestEvent(models.EventInfo{UUID: uuid.New().String(), Username: "john",
+				Timestamp: test.AddTime(initialTime, offset*2, time.Minute), IP: "1.0.0.0"}))
+		}
+
+		_, err := eventRepository.InsertEvents(events)
+		req.NoError(err)
+
+		for _, offset := range []int{-1, 0, 1, 2, 199, 398, 399, 400} {
+			current := newTestEvent(models.EventInfo{UUID: uuid.New().String(), Username: "john",
+				Timestamp: test.AddTime(initialTime, offset, time.Minute), IP: "1.0.0.0"})
+
+			expected := NewRelatedEventsFilter(current)
+			for _, event := range events {
+				expected.Filter(event)
+			}
+
+			actual := NewRelatedEventsFilter(current)
+			req.NoError(eventRepository.FindRelatedEvents(current, actual))
+			req.Equal(expected.GetRelatedEvents(), actual.GetRelatedEvents(), "offset %d", offset)
+		}
+	})
+
+	t.Run("expired events keep the latest event of each user", func(t *testing.T) {
+		eventRepository, cleanUp := newRepository(t)
+		defer cleanUp()
+
+		req := require.New(t)
+
+		var events []*models.Event
+
+		for _, username := range []string{"john@example.com", "jane@example.org", "bob", "tom_50%@example.com"} {
+			for hours := 0; hours < 3; hours++ {
+				events = append(events, newTestEvent(models.EventInfo{UUID: uuid.New().String(), Username: username,
+					Timestamp: test.AddTime(initialTime, hours, time.Hour), IP: "1.0.0.0"}))
+			}
+		}
+
+		_, err := eventRepository.InsertEvents(events)
+		req.NoError(err)
+
+		expired, err := eventRepository.FindExpiredEvents(models.RetentionRule{Tenant: "example.com",
+			Before: test.AddTime(initialTime, 10, time.Hour)}, 10)
+		req.NoError(err)
+		req.Len(expired, 4)
+
+		for _, event := range expired {
+			req.Equal("example.com", models.TenantOf(event.ToEventInfo().Username))
+			req.True(event.ToEventInfo().Timestamp < test.AddTime(initialTime, 2, time.Hour))
+		}
+
+		expired, err = eventRepository.FindExpiredEvents(models.RetentionRule{ExcludedTenants: []string{"example.com"},
+			Before: test.AddTime(initialTime, 1, time.Hour)}, 10)
+		req.NoError(err)
+		req.Len(expired, 2)
+
+		limited, err := eventRepository.FindExpiredEvents(models.RetentionRule{Before: test.AddTime(initialTime, 10,
+			time.Hour)}, 3)
+		req.NoError(err)
+		req.Len(limited, 3)
+		req.Equal(initialTime, limited[0].ToEventInfo().Timestamp)
+
+		deleted, err := eventRepository.DeleteEvents([]string{expired[0].ToEventInfo().UUID,
+			expired[1].ToEventInfo().UUID, uuid.New().String()})
+		req.NoError(err)
+		req.Equal(int64(2), deleted)
+		req.NoError(eventRepository.Vacuum())
+
+		remaining, err := eventRepository.FindExpiredEvents(models.RetentionRule{ExcludedTenants: []string{"example.com"},
+			Before: test.AddTime(initialTime, 1, time.Hour)}, 10)
+		req.NoError(err)
+		req.Empty(remaining)
+	})
+
+	t.Run("export resumes from a cursor in timestamp and uuid order", func(t *testing.T) {
+		eventRepository, cleanUp := newRepository(t)
+		defer cleanUp()
+
+		req := require.New(t)
+
+		var events []*models.Event
+
+		for _, username := range []string{"john", "jane"} {
+			for hours := 0; hours < 5; hours++ {
+				events = append(events, newTestEvent(models.EventInfo{UUID: uuid.New().String(), Username: username,
+					Timestamp: test.AddTime(initialTime, hours, time.Hour), IP: "1.0.0.0"}))
+			}
+		}
+
+		_, err := eventRepository.InsertEvents(events)
+		req.NoError(err)
+
+		exportAll := func(query models.ExportQuery) []models.EventInfo {
+			var exported []models.EventInfo
+
+			req.NoError(eventRepository.ExportEvents(query, func(event *models.Event) error {
+				exported = append(exported, event.ToEventInfo())
+				return nil
+			}))
+
+			return exported
+		}
+
+		all := exportAll(models.ExportQuery{})
+		req.Len(all, 10)
+
+		var paged []models.EventInfo
+
+		query := models.ExportQuery{Limit: 3}
+
+		for {
+			page := exportAll(query)
+			if len(page) == 0 {
+				break
+			}
+
+			paged = append(paged, page...)
+			query.After = models.EventCursor(page[len(page)-1])
+		}
+
+		req.Equal(all, paged)
+
+		for i := 1; i < len(all); i++ {
+			req.True(all[i-1].Timestamp < all[i].Timestamp ||
+				(all[i-1].Timestamp == all[i].Timestamp && all[i-1].UUID < all[i].UUID))
+		}
+
+		filtered := exportAll(models.ExportQuery{Username: "jane", From: test.AddTime(initialTime, 1, time.Hour),
+			To: test.AddTime(initialTime, 3, time.Hour)})
+		req.Len(filtered, 2)
+		req.Equal("jane", filtered[0].Username)
+		req.Equal(test.AddTime(initialTime, 1, time.Hour), filtered[0].Timestamp)
+	})
+
+	t.Run("empty store has no neighbours", func(t *testing.T) {
+		eventRepository, cleanUp := newRepository(t)
+		defer cleanUp()
+
+		req := require.New(t)
+		event := newTestEvent(models.EventInfo{UUID: uuid.New().String(), Username: "john",
+			Timestamp: initialTime, IP: "1.0.0.0"})
+		filter := NewRelatedEventsFilter(event)
+		req.NoError(eventRepository.FindRelatedEvents(event, filter))
+		req.Nil(filter.GetRelatedEvents().PreviousEvent)
+		req.Nil(filter.GetRelatedEvents().SubsequentEvent)
+	})
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+// builds the query of the events a retention rule expires, oldest first. the latest event of every user is never
+// selected so travel from the last known location can still be detected. placeholder returns the parameter marker
+// of the dialect for the 1 based position
+func expiredEventsQuery(rule models.RetentionRule, limit int, placeholder func(position int) string) (string,
+	[]interface{}) {
+	args := []interface{}{rule.Before}
+	query := strings.Builder{}
+	query.WriteString("SELECT uuid, username, timestamp, ip, session_id, event_type FROM events expired WHERE timestamp < ")
+	query.WriteString(placeholder(len(args)))
+
+	if rule.Tenant != "" {
+		args = append(args, tenantPattern(rule.Tenant))
+		query.WriteString(fmt.Sprintf(` AND lower(username) LIKE %s ESCAPE '\'`, placeholder(len(args))))
+	}
+
+	for _, excluded := range rule.ExcludedTenants {
+		args = append(args, tenantPattern(excluded))
+		query.WriteString(fmt.Sprintf(` AND lower(username) NOT LIKE %s ESCAPE '\'`, placeholder(len(args))))
+	}
+
+	args = append(args, limit)
+	query.WriteString(" AND timestamp < (SELECT MAX(timestamp) FROM events latest WHERE latest.username = " +
+		"expired.username) ORDER BY timestamp ASC LIMIT ")
+	query.WriteString(placeholder(len(args)))
+
+	return query.String(), args
+}
+
+// deletes the events with the uuids in one transaction and returns the number deleted
+func deleteEvents(tx *sql.Tx, deleteQuery string, uuids []string) (deleted int64, err error) {
+	stmt, err := tx.Prepare(deleteQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if closeErr := stmt.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for _, uuid := range uuids {
+		result, err := stmt.Exec(uuid)
+		if err != nil {
+			return 0, err
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		deleted += rows
+	}
+
+	return deleted, nil
+}
+
+func scanEvents(rows *sql.Rows) ([]*models.Event, error) {
+	var events []*models.Event
+
+	err := forEachEventRow(rows, func(event *models.Event) error {
+		events = append(events, event)
+		return nil
+	})
+
+	return events, err
+}
+
+func tenantPattern(tenant string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(tenant))
+	return "%@" + escaped
+}
+
+func sqLitePlaceholder(position int) string {
+	return "?"
+}
+
+func postgresPlaceholder(position int) string {
+	return fmt.Sprintf("$%d", position)
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+// the closest earlier and the closest later event of a user, each a bounded range scan of the
+// events_username_timestamp_unq index instead of a scan of the whole history of the user
+const (
+	sqLitePreviousEventQuery = "SELECT uuid, username, timestamp, ip, session_id, event_type FROM events " +
+		"WHERE username = ? AND timestamp < ? ORDER BY timestamp DESC LIMIT 1"
+	sqLiteSubsequentEventQuery = "SELECT uuid, username, timestamp, ip, session_id, event_type FROM events " +
+		"WHERE username = ? AND timestamp > ? ORDER BY timestamp ASC LIMIT 1"
+	postgresPreviousEventQuery = "SELECT uuid, username, timestamp, ip, session_id, event_type FROM events " +
+		"WHERE username = $1 AND timestamp < $2 ORDER BY timestamp DESC LIMIT 1"
+	postgresSubsequentEventQuery = "SELECT uuid, username, timestamp, ip, session_id, event_type FROM events " +
+		"WHERE username = $1 AND timestamp > $2 ORDER BY timestamp ASC LIMIT 1"
+)
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// runs the neighbour queries for the event and passes the events found to the filter
+func filterNeighbourEvents(db queryer, event *models.Event, filter core.EventFilter, queries ...string) error {
+	eventInfo := event.ToEventInfo()
+
+	for _, query := range queries {
+		rows, err := db.Query(query, eventInfo.Username, eventInfo.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		if err := filterEventRows(rows, filter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scans rows of the events table in column order and passes each event to the filter
+func filterEventRows(rows *sql.Rows, filter core.EventFilter) error {
+	return forEachEventRow(rows, func(event *models.Event) error {
+		filter.Filter(event)
+		return nil
+	})
+}
@@ -29,6 +29,7 @@ func (repo MaxMindIPGeoInfoRepository) FindGeoPoint(ip net.IP) (geoPoint *models
 			Latitude:       city.Location.Latitude,
 			Longitude:      city.Location.Longitude,
 			AccuracyRadius: city.Location.AccuracyRadius,
+			Country:        city.Country.IsoCode,
 		}
 		return nil
 	})
@@ -15,7 +15,7 @@ var IPToGeoPointTestCases = []struct {
 }{
 	{
 		IP:               "91.207.175.104",
-		expectedGeoPoint: &models.GeoPoint{Latitude: 34.0549, Longitude: -118.2578, AccuracyRadius: 200},
+		expectedGeoPoint: &models.GeoPoint{Latitude: 34.0549, Longitude: -118.2578, AccuracyRadius: 200, Country: "US"},
 	},
 }
 
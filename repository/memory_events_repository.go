@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+// provides services for storing and retrieving events in memory. the events of each user are kept sorted by
+// timestamp, and the same uuid and username/timestamp uniqueness as the database schemas is applied. nothing
+// survives a restart
+type MemoryEventsRepository struct {
+	lock       sync.RWMutex
+	uuids      map[string]*models.Event
+	userEvents map[string][]*models.Event
+}
+
+// result of an insert, the in memory store has no insert ids
+type memoryResult struct {
+	rowsAffected int64
+}
+
+func (result memoryResult) LastInsertId() (int64, error) {
+	return 0, errors.New("last insert id is not supported by the memory repository")
+}
+
+func (result memoryResult) RowsAffected() (int64, error) {
+	return result.rowsAffected, nil
+}
+
+func NewMemoryEventsRepository() *MemoryEventsRepository {
+	return &MemoryEventsRepository{uuids: make(map[string]*models.Event), userEvents: make(map[string][]*models.Event)}
+}
+
+func (eventRepository *MemoryEventsRepository) InsertAndFindRelatedEvents(event *models.Event,
+	filter core.EventFilter) error {
+	eventRepository.lock.Lock()
+	defer eventRepository.lock.Unlock()
+
+	eventRepository.insertEvent(event)
+	eventRepository.findAndFilter(event, filter)
+
+	return nil
+}
+
+func (eventRepository *MemoryEventsRepository) FindRelatedEvents(event *models.Event,
+	filter core.EventFilter) error {
+	eventRepository.lock.RLock()
+	defer eventRepository.lock.RUnlock()
+
+	eventRepository.findAndFilter(event, filter)
+
+	return nil
+}
+
+func (eventRepository *MemoryEventsRepository) InsertEvents(events []*models.Event) ([]sql.Result, error) {
+	eventRepository.lock.Lock()
+	defer eventRepository.lock.Unlock()
+
+	results := make([]sql.Result, 0, len(events))
+	for _, event := range events {
+		results = append(results, eventRepository.insertEvent(event))
+	}
+
+	return results, nil
+}
+
+// passes the stored events of the user nearest to the timestamp of the event to the filter, found by binary search
+func (eventRepository *MemoryEventsRepository) findAndFilter(event *models.Event, filter core.EventFilter) {
+	eventInfo := event.ToEventInfo()
+	events := eventRepository.userEvents[eventInfo.Username]
+	first := sort.Search(len(events), func(i int) bool {
+		return events[i].ToEventInfo().Timestamp >= eventInfo.Timestamp
+	})
+	last := first
+
+	for last < len(events) && events[last].ToEventInfo().Timestamp == eventInfo.Timestamp {
+		last++
+	}
+
+	if first > 0 {
+		first--
+	}
+
+	if last < len(events) {
+		last++
+	}
+
+	for _, related := range events[first:last] {
+		filter.Filter(related)
+	}
+}
+
+func (eventRepository *MemoryEventsRepository) FindExpiredEvents(rule models.RetentionRule,
+	limit int) ([]*models.Event, error) {
+	eventRepository.lock.RLock()
+	defer eventRepository.lock.RUnlock()
+
+	var expired []*models.Event
+
+	for username, events := range eventRepository.userEvents {
+		if !rule.Matches(username) {
+			continue
+		}
+
+		// the latest event of the user is always kept
+		for _, event := range events[:len(events)-1] {
+			if event.ToEventInfo().Timestamp >= rule.Before {
+				break
+			}
+
+			expired = append(expired, event)
+		}
+	}
+
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].ToEventInfo().Timestamp < expired[j].ToEventInfo().Timestamp
+	})
+
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+
+	return expired, nil
+}
+
+func (eventRepository *MemoryEventsRepository) DeleteEvents(uuids []string) (int64, error) {
+	eventRepository.lock.Lock()
+	defer eventRepository.lock.Unlock()
+
+	var deleted int64
+
+	for _, uuid := range uuids {
+		event, ok := eventRepository.uuids[uuid]
+		if !ok {
+			continue
+		}
+
+		eventInfo := event.ToEventInfo()
+		events := eventRepository.userEvents[eventInfo.Username]
+		index := sort.Search(len(events), func(i int) bool {
+			return events[i].ToEventInfo().Timestamp >= eventInfo.Timestamp
+		})
+
+		if len(events) == 1 {
+			delete(eventRepository.userEvents, eventInfo.Username)
+		} else {
+			eventRepository.userEvents[eventInfo.Username] = append(events[:index], events[index+1:]...)
+		}
+
+		delete(eventRepository.uuids, uuid)
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// the events are copied under the lock and passed to fnx after it is released, so a slow reader does not block
+// the detection
+func (eventRepository *MemoryEventsRepository) ExportEvents(query models.ExportQuery,
+	fnx func(event *models.Event) error) error {
+	var afterTimestamp int64
+
+	var afterUUID string
+
+	if query.After != "" {
+		timestamp, uuid, err := models.ParseEventCursor(query.After)
+		if err != nil {
+			return err
+		}
+
+		afterTimestamp, afterUUID = timestamp, uuid
+	}
+
+	var events []*models.Event
+
+	eventRepository.lock.RLock()
+
+	for username, userEvents := range eventRepository.userEvents {
+		if query.Username != "" && username != query.Username {
+			continue
+		}
+
+		for _, event := range userEvents {
+			eventInfo := event.ToEventInfo()
+			if (query.From != 0 && eventInfo.Timestamp < query.From) ||
+				(query.To != 0 && eventInfo.Timestamp >= query.To) {
+				continue
+			}
+
+			if query.After != "" && (eventInfo.Timestamp < afterTimestamp ||
+				(eventInfo.Timestamp == afterTimestamp && eventInfo.UUID <= afterUUID)) {
+				continue
+			}
+
+			events = append(events, event)
+		}
+	}
+
+	eventRepository.lock.RUnlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		left, right := events[i].ToEventInfo(), events[j].ToEventInfo()
+		if left.Timestamp != right.Timestamp {
+			return left.Timestamp < right.Timestamp
+		}
+
+		return left.UUID < right.UUID
+	})
+
+	if query.Limit > 0 && len(events) > query.Limit {
+		events = events[:query.Limit]
+	}
+
+	for _, event := range events {
+		if err := fnx(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nothing to reclaim in memory
+func (eventRepository *MemoryEventsRepository) Vacuum() error {
+	return nil
+}
+
+func (eventRepository *MemoryEventsRepository) insertEvent(event *models.Event) memoryResult {
+	eventInfo := event.ToEventInfo()
+	if _, ok := eventRepository.uuids[eventInfo.UUID]; ok {
+		return memoryResult{}
+	}
+
+	events := eventRepository.userEvents[eventInfo.Username]
+	index := sort.Search(len(events), func(i int) bool {
+		return events[i].ToEventInfo().Timestamp >= eventInfo.Timestamp
+	})
+
+	if index < len(events) && events[index].ToEventInfo().Timestamp == eventInfo.Timestamp {
+		return memoryResult{}
+	}
+
+	events = append(events, nil)
+	copy(events[index+1:], events[index:])
+	events[index] = event
+	eventRepository.userEvents[eventInfo.Username] = events
+	eventRepository.uuids[eventInfo.UUID] = event
+
+	return memoryResult{rowsAffected: 1}
+}
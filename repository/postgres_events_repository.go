@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/frankiennamdi/detection-api/core"
+	"github.com/frankiennamdi/detection-api/db"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+// provides services for storing and retrieving events from PostgreSQL database
+type PostgresEventsRepository struct {
+	postgresDb *db.PostgresDb
+}
+
+func NewPostgresEventsRepository(postgresDb *db.PostgresDb) *PostgresEventsRepository {
+	return &PostgresEventsRepository{postgresDb: postgresDb}
+}
+
+func (eventRepository PostgresEventsRepository) InsertAndFindRelatedEvents(event *models.Event,
+	filter core.EventFilter) error {
+	if _, err := eventRepository.InsertEvents([]*models.Event{event}); err != nil {
+		return err
+	}
+
+	return eventRepository.FindRelatedEvents(event, filter)
+}
+
+func (eventRepository PostgresEventsRepository) FindRelatedEvents(event *models.Event,
+	filter core.EventFilter) error {
+	return filterNeighbourEvents(eventRepository.postgresDb.Database(), event, filter,
+		postgresPreviousEventQuery, postgresSubsequentEventQuery)
+}
+
+func (eventRepository PostgresEventsRepository) InsertEvents(events []*models.Event) ([]sql.Result, error) {
+	var results []sql.Result
+
+	transactionErr := eventRepository.postgresDb.WithTransaction(func(tx *sql.Tx) (err error) {
+		stmt, err := tx.Prepare("INSERT INTO events(uuid, username, timestamp, ip, session_id, event_type) " +
+			"VALUES($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING")
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if closeErr := stmt.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}()
+
+		for _, event := range events {
+			eventInfo := event.ToEventInfo()
+			result, stmtErr := stmt.Exec(eventInfo.UUID, eventInfo.Username, eventInfo.Timestamp, eventInfo.IP,
+				eventInfo.SessionID, eventInfo.Type)
+			if stmtErr != nil {
+				return stmtErr
+			}
+			results = append(results, result)
+		}
+
+		return nil
+	})
+
+	if transactionErr != nil {
+		return nil, transactionErr
+	}
+
+	return results, nil
+}
+
+func (eventRepository PostgresEventsRepository) FindExpiredEvents(rule models.RetentionRule,
+	limit int) ([]*models.Event, error) {
+	query, args := expiredEventsQuery(rule, limit, postgresPlaceholder)
+
+	rows, err := eventRepository.postgresDb.Database().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanEvents(rows)
+}
+
+func (eventRepository PostgresEventsRepository) DeleteEvents(uuids []string) (int64, error) {
+	var deleted int64
+
+	err := eventRepository.postgresDb.WithTransaction(func(tx *sql.Tx) (err error) {
+		deleted, err = deleteEvents(tx, "DELETE FROM events WHERE uuid = $1", uuids)
+		return err
+	})
+
+	return deleted, err
+}
+
+// space of deleted rows is reclaimed by the postgres autovacuum
+func (eventRepository PostgresEventsRepository) Vacuum() error {
+	return nil
+}
+
+func (eventRepository PostgresEventsRepository) ExportEvents(query models.ExportQuery,
+	fnx func(event *models.Event) error) error {
+	return exportEvents(eventRepository.postgresDb.Database(), query, postgresPlaceholder, fnx)
+}
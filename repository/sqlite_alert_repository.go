@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/db"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+const alertColumns = "SELECT seq, event_uuid, username, from_timestamp, from_ip, from_lat, from_lon, to_timestamp, " +
+	"to_ip, to_lat, to_lon, speed, created_at"
+
+// provides services for storing and retrieving alerts from SQLite database
+type SqLiteAlertRepository struct {
+	sqLiteDb *db.SqLiteDb
+}
+
+func NewSQLLiteAlertRepository(sqLiteDb *db.SqLiteDb) *SqLiteAlertRepository {
+	return &SqLiteAlertRepository{sqLiteDb: sqLiteDb}
+}
+
+// an alert for a travel that already has one is ignored. returns the number of alerts inserted
+func (alertRepository SqLiteAlertRepository) InsertAlerts(alerts []*models.Alert) (int64, error) {
+	var inserted int64
+
+	fnxErr := alertRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) (err error) {
+			stmt, err := tx.Prepare("INSERT OR IGNORE INTO alerts(event_uuid, username, from_timestamp, from_ip, " +
+				"from_lat, from_lon, to_timestamp, to_ip, to_lat, to_lon, speed, created_at) " +
+				"VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			if err != nil {
+				return err
+			}
+
+			defer func() {
+				if closeErr := stmt.Close(); closeErr != nil && err == nil {
+					err = closeErr
+				}
+			}()
+
+			for _, alert := range alerts {
+				result, err := stmt.Exec(alert.EventUUID, alert.Username, alert.FromTimestamp, alert.FromIP,
+					alert.FromLatitude, alert.FromLongitude, alert.ToTimestamp, alert.ToIP, alert.ToLatitude,
+					alert.ToLongitude, alert.Speed, alert.CreatedAt)
+				if err != nil {
+					return err
+				}
+
+				rows, err := result.RowsAffected()
+				if err != nil {
+					return err
+				}
+
+				inserted += rows
+			}
+
+			return nil
+		})
+	}, "mode=rw")
+
+	return inserted, fnxErr
+}
+
+// the alerts of the user in the order of the travels
+func (alertRepository SqLiteAlertRepository) FindAlerts(username string) ([]*models.Alert, error) {
+	var alerts []*models.Alert
+
+	fnxErr := alertRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		rows, err := context.Database().Query(alertColumns+" FROM alerts WHERE username = ? "+
+			"ORDER BY from_timestamp ASC", username)
+		if err != nil {
+			return err
+		}
+
+		alerts, err = scanAlerts(rows)
+
+		return err
+	}, "mode=rw")
+
+	return alerts, fnxErr
+}
+
+// alerts are exported in the order of seq, the time range applies to the later login of the travel
+func (alertRepository SqLiteAlertRepository) ExportAlerts(query models.ExportQuery,
+	fnx func(alert *models.Alert) error) error {
+	var conditions []string
+
+	var args []interface{}
+
+	if query.Username != "" {
+		conditions, args = append(conditions, "username = ?"), append(args, query.Username)
+	}
+
+	if query.From != 0 {
+		conditions, args = append(conditions, "to_timestamp >= ?"), append(args, query.From)
+	}
+
+	if query.To != 0 {
+		conditions, args = append(conditions, "to_timestamp < ?"), append(args, query.To)
+	}
+
+	if query.After != "" {
+		seq, err := models.ParseAlertCursor(query.After)
+		if err != nil {
+			return err
+		}
+
+		conditions, args = append(conditions, "seq > ?"), append(args, seq)
+	}
+
+	statement := alertColumns + " FROM alerts"
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	statement += " ORDER BY seq ASC"
+
+	if query.Limit > 0 {
+		statement, args = statement+" LIMIT ?", append(args, query.Limit)
+	}
+
+	return alertRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		rows, err := context.Database().Query(statement, args...)
+		if err != nil {
+			return err
+		}
+
+		return forEachAlertRow(rows, fnx)
+	}, "mode=ro")
+}
+
+func scanAlerts(rows *sql.Rows) ([]*models.Alert, error) {
+	var alerts []*models.Alert
+
+	err := forEachAlertRow(rows, func(alert *models.Alert) error {
+		alerts = append(alerts, alert)
+		return nil
+	})
+
+	return alerts, err
+}
+
+func forEachAlertRow(rows *sql.Rows, fnx func(alert *models.Alert) error) (err error) {
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		alert := &models.Alert{}
+		if err := rows.Scan(&alert.Seq, &alert.EventUUID, &alert.Username, &alert.FromTimestamp, &alert.FromIP,
+			&alert.FromLatitude, &alert.FromLongitude, &alert.ToTimestamp, &alert.ToIP, &alert.ToLatitude,
+			&alert.ToLongitude, &alert.Speed, &alert.CreatedAt); err != nil {
+			return err
+		}
+
+		if err := fnx(alert); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/db"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+// provides services for storing and retrieving api keys from SQLite database
+type SqLiteAPIKeyRepository struct {
+	sqLiteDb *db.SqLiteDb
+}
+
+func NewSQLLiteAPIKeyRepository(sqLiteDb *db.SqLiteDb) *SqLiteAPIKeyRepository {
+	return &SqLiteAPIKeyRepository{sqLiteDb: sqLiteDb}
+}
+
+func (apiKeyRepository SqLiteAPIKeyRepository) InsertAPIKey(apiKey *models.APIKey, secretHash string) error {
+	return apiKeyRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) error {
+			_, err := tx.Exec("INSERT INTO api_keys(id, name, secret_hash, scopes, created_at, revoked) "+
+				"VALUES(?, ?, ?, ?, ?, ?)",
+				apiKey.ID, apiKey.Name, secretHash, strings.Join(apiKey.Scopes, ","), apiKey.CreatedAt, apiKey.Revoked)
+
+			return err
+		})
+	}, "mode=rw")
+}
+
+func (apiKeyRepository SqLiteAPIKeyRepository) FindAPIKey(id string) (*models.APIKey, string, error) {
+	var apiKey *models.APIKey
+
+	var secretHash string
+
+	fnxErr := apiKeyRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		row := context.Database().QueryRow("SELECT id, name, secret_hash, scopes, created_at, revoked "+
+			"FROM api_keys WHERE id = ?", id)
+
+		key, hash, err := scanAPIKey(row)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		apiKey = key
+		secretHash = hash
+
+		return nil
+	}, "mode=rw")
+
+	if fnxErr != nil {
+		return nil, "", fnxErr
+	}
+
+	return apiKey, secretHash, nil
+}
+
+func (apiKeyRepository SqLiteAPIKeyRepository) FindAPIKeys() ([]*models.APIKey, error) {
+	var apiKeys []*models.APIKey
+
+	fnxErr := apiKeyRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) (err error) {
+		rows, err := context.Database().Query("SELECT id, name, secret_hash, scopes, created_at, revoked " +
+			"FROM api_keys ORDER BY created_at ASC")
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if closeErr := rows.Close(); closeErr != nil {
+				err = closeErr
+			}
+		}()
+
+		for rows.Next() {
+			apiKey, _, err := scanAPIKey(rows)
+			if err != nil {
+				return err
+			}
+
+			apiKeys = append(apiKeys, apiKey)
+		}
+
+		return rows.Err()
+	}, "mode=rw")
+
+	if fnxErr != nil {
+		return nil, fnxErr
+	}
+
+	return apiKeys, nil
+}
+
+func (apiKeyRepository SqLiteAPIKeyRepository) RevokeAPIKey(id string) (bool, error) {
+	revoked := false
+
+	fnxErr := apiKeyRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) error {
+			result, err := tx.Exec("UPDATE api_keys SET revoked = 1 WHERE id = ?", id)
+			if err != nil {
+				return err
+			}
+
+			rows, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			revoked = rows > 0
+
+			return nil
+		})
+	}, "mode=rw")
+
+	if fnxErr != nil {
+		return false, fnxErr
+	}
+
+	return revoked, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, string, error) {
+	var apiKey models.APIKey
+
+	var secretHash, scopes string
+
+	err := row.Scan(&apiKey.ID, &apiKey.Name, &secretHash, &scopes, &apiKey.CreatedAt, &apiKey.Revoked)
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiKey.Scopes = strings.Split(scopes, ",")
+
+	return &apiKey, secretHash, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertAndFindAPIKey(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	apiKeyRepository := NewSQLLiteAPIKeyRepository(testSetup.AppServerContext().EventDb())
+	apiKey := &models.APIKey{
+		ID:        "4f1c0ad3b2e1a7c9",
+		Name:      "collector",
+		Scopes:    []string{models.ScopeEventsWrite, models.ScopeAlertsRead},
+		CreatedAt: 1514764800,
+	}
+
+	req.NoError(apiKeyRepository.InsertAPIKey(apiKey, "hash"))
+
+	found, secretHash, err := apiKeyRepository.FindAPIKey(apiKey.ID)
+	req.NoError(err)
+	req.Equal(apiKey, found)
+	req.Equal("hash", secretHash)
+
+	apiKeys, err := apiKeyRepository.FindAPIKeys()
+	req.NoError(err)
+	req.Equal([]*models.APIKey{apiKey}, apiKeys)
+}
+
+func TestFindAPIKey_When_Key_Does_Not_Exist(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	apiKeyRepository := NewSQLLiteAPIKeyRepository(testSetup.AppServerContext().EventDb())
+
+	found, secretHash, err := apiKeyRepository.FindAPIKey("missing")
+	req.NoError(err)
+	req.Nil(found)
+	req.Empty(secretHash)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	apiKeyRepository := NewSQLLiteAPIKeyRepository(testSetup.AppServerContext().EventDb())
+	req.NoError(apiKeyRepository.InsertAPIKey(&models.APIKey{
+		ID:        "4f1c0ad3b2e1a7c9",
+		Name:      "collector",
+		Scopes:    []string{models.ScopeEventsWrite},
+		CreatedAt: 1514764800,
+	}, "hash"))
+
+	revoked, err := apiKeyRepository.RevokeAPIKey("4f1c0ad3b2e1a7c9")
+	req.NoError(err)
+	req.True(revoked)
+
+	found, _, err := apiKeyRepository.FindAPIKey("4f1c0ad3b2e1a7c9")
+	req.NoError(err)
+	req.True(found.Revoked)
+
+	revoked, err = apiKeyRepository.RevokeAPIKey("missing")
+	req.NoError(err)
+	req.False(revoked)
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/frankiennamdi/detection-api/db"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+// provides the purge of the data derived from events in the SQLite database, whichever store holds the events
+type SqLiteDerivedRetentionRepository struct {
+	sqLiteDb *db.SqLiteDb
+}
+
+func NewSQLLiteDerivedRetentionRepository(sqLiteDb *db.SqLiteDb) *SqLiteDerivedRetentionRepository {
+	return &SqLiteDerivedRetentionRepository{sqLiteDb: sqLiteDb}
+}
+
+// deletes what was derived from the events the rule expires, by the time of those events: the alerts of travels
+// that ended before it, the finished entries of the ingestion queue, the travels of the risk scores and the users
+// scored last before it, and the events of the location profiles, whose counts are taken out of the profiles. like
+// the events, the latest event of the location profile of a user is kept. returns the number of rows deleted
+func (retentionRepository SqLiteDerivedRetentionRepository) PurgeDerivedData(
+	rule models.RetentionRule) (int64, error) {
+	var purged int64
+
+	filter, filterArgs := usernameFilter(rule)
+
+	fnxErr := retentionRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) error {
+			deleted, err := purgeFinishedQueuedEvents(tx, rule, filter, filterArgs)
+			if err != nil {
+				return err
+			}
+
+			purged += deleted
+
+			statements := []struct {
+				query string
+				args  []interface{}
+				count bool
+			}{
+				{"DELETE FROM alerts WHERE to_timestamp < ?", []interface{}{rule.Before}, true},
+				{"DELETE FROM user_risk_travels WHERE to_timestamp < ?", []interface{}{rule.Before}, true},
+				{"DELETE FROM user_risk WHERE as_of < ?", []interface{}{rule.Before}, true},
+				// the expired events are taken out of the counts of their cell and country
+				{"UPDATE user_locations SET count = count - (SELECT COUNT(*) FROM user_location_events observed " +
+					"WHERE observed.username = user_locations.username AND observed.timestamp < ? AND " +
+					"observed.timestamp < " + latestObservation("observed") + " AND " +
+					"((user_locations.kind = ? AND observed.geohash = user_locations.location) OR " +
+					"(user_locations.kind = ? AND observed.country = user_locations.location))) WHERE first_seen < ?",
+					[]interface{}{rule.Before, models.LocationKindCell, models.LocationKindCountry, rule.Before},
+					false},
+				// a location seen last by the latest event is kept with it
+				{"DELETE FROM user_locations WHERE (count <= 0 OR last_seen < ?) AND last_seen < COALESCE(" +
+					"(SELECT MAX(latest.timestamp) FROM user_location_events latest WHERE latest.username = " +
+					"user_locations.username), ?)", []interface{}{rule.Before, rule.Before}, true},
+				// the first time a location was seen becomes that of its first event still kept
+				{"UPDATE user_locations SET first_seen = COALESCE((SELECT MIN(observed.timestamp) " +
+					"FROM user_location_events observed WHERE observed.username = user_locations.username AND " +
+					"(observed.timestamp >= ? OR observed.timestamp >= " + latestObservation("observed") + ") AND " +
+					"((user_locations.kind = ? AND observed.geohash = user_locations.location) OR " +
+					"(user_locations.kind = ? AND observed.country = user_locations.location))), first_seen) " +
+					"WHERE first_seen < ?",
+					[]interface{}{rule.Before, models.LocationKindCell, models.LocationKindCountry, rule.Before},
+					false},
+				{"DELETE FROM user_location_events WHERE timestamp < ? AND timestamp < " +
+					latestObservation("user_location_events"), []interface{}{rule.Before}, true},
+			}
+
+			for _, statement := range statements {
+				result, err := tx.Exec(statement.query+filter, append(statement.args, filterArgs...)...)
+				if err != nil {
+					return err
+				}
+
+				if !statement.count {
+					continue
+				}
+
+				deleted, err := result.RowsAffected()
+				if err != nil {
+					return err
+				}
+
+				purged += deleted
+			}
+
+			return nil
+		})
+	}, "mode=rw")
+
+	if fnxErr != nil {
+		return 0, fnxErr
+	}
+
+	return purged, nil
+}
+
+// the time of the latest event of the location profile of the user of the table
+func latestObservation(table string) string {
+	return "(SELECT MAX(latest.timestamp) FROM user_location_events latest WHERE latest.username = " + table +
+		".username)"
+}
+
+// deletes the done and failed entries of the queue whose event the rule expires. the time of the event is only
+// held in the payload of the entry
+func purgeFinishedQueuedEvents(tx *sql.Tx, rule models.RetentionRule, filter string,
+	filterArgs []interface{}) (int64, error) {
+	rows, err := tx.Query("SELECT uuid, payload FROM event_queue WHERE status IN (?, ?)"+filter,
+		append([]interface{}{models.QueueStatusDone, models.QueueStatusFailed}, filterArgs...)...)
+	if err != nil {
+		return 0, err
+	}
+
+	var expired []string
+
+	for rows.Next() {
+		var uuid, payload string
+		if err := rows.Scan(&uuid, &payload); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+
+		event, err := models.EventFromJSON(payload)
+		if err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+
+		if event.ToEventInfo().Timestamp < rule.Before {
+			expired = append(expired, uuid)
+		}
+	}
+
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	return deleteEvents(tx, "DELETE FROM event_queue WHERE uuid = ?", expired)
+}
+
+// the condition on the username of the rows of the users the rule applies to
+func usernameFilter(rule models.RetentionRule) (string, []interface{}) {
+	var args []interface{}
+
+	filter := strings.Builder{}
+
+	if rule.Tenant != "" {
+		args = append(args, tenantPattern(rule.Tenant))
+		filter.WriteString(` AND lower(username) LIKE ? ESCAPE '\'`)
+	}
+
+	for _, excluded := range rule.ExcludedTenants {
+		args = append(args, tenantPattern(excluded))
+		filter.WriteString(` AND lower(username) NOT LIKE ? ESCAPE '\'`)
+	}
+
+	return filter.String(), args
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeDerivedData_Purges_Data_Of_Expired_Events(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	eventDb := testSetup.AppServerContext().EventDb()
+	alertRepository := NewSQLLiteAlertRepository(eventDb)
+	queueRepository := NewSQLLiteEventQueueRepository(eventDb)
+	riskRepository := NewSQLLiteUserRiskRepository(eventDb)
+	profileRepository := NewSQLLiteLocationProfileRepository(eventDb)
+	now := time.Now().Unix()
+	old, recent := now-10*riskDay, now+3600
+	halfLife := 24 * time.Hour
+	queuedUUIDs := map[string][]string{}
+
+	for _, username := range []string{"john@example.com", "bob"} {
+		_, err := alertRepository.InsertAlerts([]*models.Alert{
+			{EventUUID: uuid.New().String(), Username: username, FromTimestamp: old - 3600, ToTimestamp: old},
+			{EventUUID: uuid.New().String(), Username: username, FromTimestamp: old, ToTimestamp: recent},
+		})
+		req.NoError(err)
+
+		_, err = riskRepository.AddUserRisk(username, []models.TravelRisk{
+			{From: old - 3600, To: old, Score: 4},
+			{From: old, To: recent, Score: 1},
+		}, nil, halfLife)
+		req.NoError(err)
+
+		for _, observation := range []struct {
+			geohash   string
+			timestamp int64
+		}{{"dr5r", old}, {"9q5c", old + 3600}, {"dr5r", recent}} {
+			_, err = profileRepository.ObserveLocation(username, observation.geohash, "US", observation.timestamp)
+			req.NoError(err)
+		}
+
+		// entries of the queue are expired by the time of their event, not by the time they were processed
+		for _, queued := range []struct {
+			status    string
+			timestamp int64
+		}{{models.QueueStatusDone, old}, {models.QueueStatusDone, recent}, {models.QueueStatusPending, old}} {
+			event, err := models.NewEvent(models.EventInfo{UUID: uuid.New().String(), Username: username,
+				Timestamp: queued.timestamp, IP: "1.0.0.0"})
+			req.NoError(err)
+
+			_, err = queueRepository.Enqueue(event)
+			req.NoError(err)
+
+			if queued.status == models.QueueStatusDone {
+				req.NoError(queueRepository.Complete(event.ToEventInfo().UUID, &models.SuspiciousTravelResult{},
+					nil, 1))
+			}
+
+			queuedUUIDs[username] = append(queuedUUIDs[username], event.ToEventInfo().UUID)
+		}
+	}
+
+	purged, err := NewSQLLiteDerivedRetentionRepository(eventDb).PurgeDerivedData(models.RetentionRule{
+		Tenant: "example.com", Before: now + 1})
+	req.NoError(err)
+	// an alert, a queue entry, a travel, a location and two location events
+	req.Equal(int64(6), purged)
+
+	alerts, err := alertRepository.FindAlerts("john@example.com")
+	req.NoError(err)
+	req.Len(alerts, 1)
+	req.Equal(recent, alerts[0].ToTimestamp)
+
+	userRisk, err := riskRepository.FindUserRisk("john@example.com")
+	req.NoError(err)
+	req.Equal(recent, userRisk.AsOf)
+
+	userLocations, err := profileRepository.FindUserLocations("john@example.com")
+	req.NoError(err)
+	req.Equal([]*models.UserLocation{
+		{Username: "john@example.com", Kind: models.LocationKindCell, Location: "dr5r", Count: 1, FirstSeen: recent,
+			LastSeen: recent},
+		{Username: "john@example.com", Kind: models.LocationKindCountry, Location: "US", Count: 1,
+			FirstSeen: recent, LastSeen: recent},
+	}, userLocations)
+
+	for i, queuedUUID := range queuedUUIDs["john@example.com"] {
+		queuedEvent, err := queueRepository.FindQueuedEvent(queuedUUID)
+		req.NoError(err)
+		req.Equal(i > 0, queuedEvent != nil, "queued event %d", i)
+	}
+
+	// the users of other tenants keep everything
+	alerts, err = alertRepository.FindAlerts("bob")
+	req.NoError(err)
+	req.Len(alerts, 2)
+
+	userLocations, err = profileRepository.FindUserLocations("bob")
+	req.NoError(err)
+	req.Len(userLocations, 3)
+
+	purged, err = NewSQLLiteDerivedRetentionRepository(eventDb).PurgeDerivedData(models.RetentionRule{
+		ExcludedTenants: []string{"example.com"}, Before: now + 1})
+	req.NoError(err)
+	req.Equal(int64(6), purged)
+
+	purged, err = NewSQLLiteDerivedRetentionRepository(eventDb).PurgeDerivedData(models.RetentionRule{
+		Before: now + 1})
+	req.NoError(err)
+	req.Zero(purged)
+}
+
+func TestPurgeDerivedData_Drops_Risk_Of_Users_Scored_Before_Rule(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	eventDb := testSetup.AppServerContext().EventDb()
+	riskRepository := NewSQLLiteUserRiskRepository(eventDb)
+
+	_, err := riskRepository.AddUserRisk("bob", []models.TravelRisk{{From: 0, To: riskDay, Score: 4}}, nil,
+		24*time.Hour)
+	req.NoError(err)
+
+	purged, err := NewSQLLiteDerivedRetentionRepository(eventDb).PurgeDerivedData(models.RetentionRule{
+		Before: 2 * riskDay})
+	req.NoError(err)
+	req.Equal(int64(2), purged)
+
+	userRisk, err := riskRepository.FindUserRisk("bob")
+	req.NoError(err)
+	req.Nil(userRisk)
+}
+
+func TestPurgeDerivedData_Keeps_Latest_Location_Event_Of_User(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	eventDb := testSetup.AppServerContext().EventDb()
+	profileRepository := NewSQLLiteLocationProfileRepository(eventDb)
+
+	for i, geohash := range []string{"dr5r", "9q5c", "9q5c"} {
+		_, err := profileRepository.ObserveLocation("bob", geohash, "US", riskDay*int64(i+1))
+		req.NoError(err)
+	}
+
+	// every event is older than the rule, but the latest one is kept like the latest event of the user
+	purged, err := NewSQLLiteDerivedRetentionRepository(eventDb).PurgeDerivedData(models.RetentionRule{
+		Before: 10 * riskDay})
+	req.NoError(err)
+	// two location events and the cell they were the only events of
+	req.Equal(int64(3), purged)
+
+	userLocations, err := profileRepository.FindUserLocations("bob")
+	req.NoError(err)
+	req.Equal([]*models.UserLocation{
+		{Username: "bob", Kind: models.LocationKindCell, Location: "9q5c", Count: 1, FirstSeen: 3 * riskDay,
+			LastSeen: 3 * riskDay},
+		{Username: "bob", Kind: models.LocationKindCountry, Location: "US", Count: 1, FirstSeen: 3 * riskDay,
+			LastSeen: 3 * riskDay},
+	}, userLocations)
+
+	purged, err = NewSQLLiteDerivedRetentionRepository(eventDb).PurgeDerivedData(models.RetentionRule{
+		Before: 10 * riskDay})
+	req.NoError(err)
+	req.Zero(purged)
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/db"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+// provides a durable queue of events awaiting detection, stored in the SQLite database
+type SqLiteEventQueueRepository struct {
+	sqLiteDb *db.SqLiteDb
+}
+
+func NewSQLLiteEventQueueRepository(sqLiteDb *db.SqLiteDb) *SqLiteEventQueueRepository {
+	return &SqLiteEventQueueRepository{sqLiteDb: sqLiteDb}
+}
+
+// returns false when an event with the same uuid was already queued, the original entry is kept
+func (queueRepository SqLiteEventQueueRepository) Enqueue(event *models.Event) (bool, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return false, err
+	}
+
+	queued := false
+	eventInfo := event.ToEventInfo()
+
+	fnxErr := queueRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) error {
+			result, err := tx.Exec("INSERT OR IGNORE INTO event_queue(uuid, username, payload, status, enqueued_at) "+
+				"VALUES(?, ?, ?, ?, ?)", eventInfo.UUID, eventInfo.Username, string(payload),
+				models.QueueStatusPending, time.Now().Unix())
+			if err != nil {
+				return err
+			}
+
+			rows, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			queued = rows > 0
+
+			return nil
+		})
+	}, "mode=rw")
+
+	if fnxErr != nil {
+		return false, fnxErr
+	}
+
+	return queued, nil
+}
+
+// marks up to limit pending events as processing and returns them in the order they were queued
+func (queueRepository SqLiteEventQueueRepository) ClaimPending(limit int) ([]*models.QueuedEvent, error) {
+	var claimed []*models.QueuedEvent
+
+	fnxErr := queueRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) (err error) {
+			rows, err := tx.Query(queuedEventQuery+" WHERE status = ? ORDER BY seq ASC LIMIT ?",
+				models.QueueStatusPending, limit)
+			if err != nil {
+				return err
+			}
+
+			claimed, err = scanQueuedEvents(rows)
+			if err != nil {
+				return err
+			}
+
+			for _, queuedEvent := range claimed {
+				if _, err := tx.Exec("UPDATE event_queue SET status = ? WHERE seq = ?",
+					models.QueueStatusProcessing, queuedEvent.Seq); err != nil {
+					return err
+				}
+
+				queuedEvent.Status = models.QueueStatusProcessing
+			}
+
+			return nil
+		})
+	}, "mode=rw")
+
+	if fnxErr != nil {
+		return nil, fnxErr
+	}
+
+	return claimed, nil
+}
+
+// records the outcome of processing after the attempts made, the event is done or, with a non nil processErr,
+// failed
+func (queueRepository SqLiteEventQueueRepository) Complete(uuid string, result *models.SuspiciousTravelResult,
+	processErr error, attempts int) error {
+	if processErr != nil {
+		return queueRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+			return context.WithTransaction(func(tx *sql.Tx) error {
+				_, err := tx.Exec("UPDATE event_queue SET status = ?, error = ?, processed_at = ?, attempts = ? "+
+					"WHERE uuid = ?", models.QueueStatusFailed, processErr.Error(), time.Now().Unix(), attempts, uuid)
+				return err
+			})
+		}, "mode=rw")
+	}
+
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	return queueRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) error {
+			_, err := tx.Exec("UPDATE event_queue SET status = ?, result = ?, error = NULL, processed_at = ?, "+
+				"attempts = ? WHERE uuid = ?", models.QueueStatusDone, string(payload), time.Now().Unix(), attempts,
+				uuid)
+			return err
+		})
+	}, "mode=rw")
+}
+
+// deletes the done and failed events processed before the unix time, returns how many
+func (queueRepository SqLiteEventQueueRepository) PruneFinished(before int64) (int64, error) {
+	var pruned int64
+
+	fnxErr := queueRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) error {
+			result, err := tx.Exec("DELETE FROM event_queue WHERE status IN (?, ?) AND processed_at < ?",
+				models.QueueStatusDone, models.QueueStatusFailed, before)
+			if err != nil {
+				return err
+			}
+
+			pruned, err = result.RowsAffected()
+
+			return err
+		})
+	}, "mode=rw")
+
+	if fnxErr != nil {
+		return 0, fnxErr
+	}
+
+	return pruned, nil
+}
+
+// returns events claimed by a previous run that did not complete back to pending, returns how many
+func (queueRepository SqLiteEventQueueRepository) ResetProcessing() (int64, error) {
+	var reset int64
+
+	fnxErr := queueRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) error {
+			result, err := tx.Exec("UPDATE event_queue SET status = ? WHERE status = ?",
+				models.QueueStatusPending, models.QueueStatusProcessing)
+			if err != nil {
+				return err
+			}
+
+			reset, err = result.RowsAffected()
+
+			return err
+		})
+	}, "mode=rw")
+
+	if fnxErr != nil {
+		return 0, fnxErr
+	}
+
+	return reset, nil
+}
+
+func (queueRepository SqLiteEventQueueRepository) FindQueuedEvent(uuid string) (*models.QueuedEvent, error) {
+	var queuedEvent *models.QueuedEvent
+
+	fnxErr := queueRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		rows, err := context.Database().Query(queuedEventQuery+" WHERE uuid = ?", uuid)
+		if err != nil {
+			return err
+		}
+
+		queuedEvents, err := scanQueuedEvents(rows)
+		if err != nil {
+			return err
+		}
+
+		if len(queuedEvents) > 0 {
+			queuedEvent = queuedEvents[0]
+		}
+
+		return nil
+	}, "mode=rw")
+
+	if fnxErr != nil {
+		return nil, fnxErr
+	}
+
+	return queuedEvent, nil
+}
+
+const queuedEventQuery = "SELECT seq, uuid, payload, status, attempts, result, error, enqueued_at, processed_at " +
+	"FROM event_queue"
+
+func scanQueuedEvents(rows *sql.Rows) (queuedEvents []*models.QueuedEvent, err error) {
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		var queuedEvent models.QueuedEvent
+
+		var payload string
+
+		var result, errorValue sql.NullString
+
+		var processedAt sql.NullInt64
+
+		if err := rows.Scan(&queuedEvent.Seq, &queuedEvent.UUID, &payload, &queuedEvent.Status, &queuedEvent.Attempts,
+			&result, &errorValue, &queuedEvent.EnqueuedAt, &processedAt); err != nil {
+			return nil, err
+		}
+
+		event, err := models.EventFromJSON(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		queuedEvent.Event = event
+		queuedEvent.Error = errorValue.String
+		queuedEvent.ProcessedAt = processedAt.Int64
+
+		if result.Valid {
+			if err := json.Unmarshal([]byte(result.String), &queuedEvent.Result); err != nil {
+				return nil, err
+			}
+		}
+
+		queuedEvents = append(queuedEvents, &queuedEvent)
+	}
+
+	return queuedEvents, rows.Err()
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/frankiennamdi/detection-api/models"
 )
 
+// value of PRAGMA auto_vacuum for incremental vacuum
+const sqLiteIncrementalAutoVacuum = 2
+
 // provides services for storing and retrieving events from SQLite database
 type SqLiteEventsRepository struct {
 	sqLiteDb *db.SqLiteDb
@@ -67,49 +70,9 @@ func (eventRepository SqLiteEventsRepository) InsertEvents(events []*models.Even
 
 func (eventRepository SqLiteEventsRepository) findAndFilter(event *models.Event,
 	filter core.EventFilter,
-	context *db.SqLiteDbContext) (err error) {
-	stmt, err := context.Database().Prepare("SELECT * FROM events WHERE username = ? ORDER BY " +
-		"timestamp ASC")
-
-	if err != nil {
-		return err
-	}
-
-	defer func() {
-		if closeErr := stmt.Close(); closeErr != nil {
-			err = closeErr
-		}
-	}()
-
-	rows, err := stmt.Query(event.ToEventInfo().Username)
-
-	if err != nil {
-		return err
-	}
-
-	for rows.Next() {
-		var eventInfo models.EventInfo
-		err = rows.Scan(&eventInfo.UUID, &eventInfo.Username, &eventInfo.Timestamp, &eventInfo.IP)
-
-		if err != nil {
-			return err
-		}
-
-		event, err := models.NewEvent(eventInfo)
-		if err != nil {
-			return err
-		}
-
-		filter.Filter(event)
-	}
-
-	err = rows.Err()
-
-	if err != nil {
-		return err
-	}
-
-	return nil
+	context *db.SqLiteDbContext) error {
+	return filterNeighbourEvents(context.Database(), event, filter,
+		sqLitePreviousEventQuery, sqLiteSubsequentEventQuery)
 }
 
 func (eventRepository SqLiteEventsRepository) insertEvents(events []*models.Event,
@@ -117,8 +80,8 @@ func (eventRepository SqLiteEventsRepository) insertEvents(events []*models.Even
 	var results []sql.Result
 
 	transactionErr := context.WithTransaction(func(tx *sql.Tx) (err error) {
-		stmt, err := tx.Prepare("INSERT OR IGNORE INTO events(uuid, username, timestamp, ip) " +
-			"VALUES(?, ?, ?, ?)")
+		stmt, err := tx.Prepare("INSERT OR IGNORE INTO events(uuid, username, timestamp, ip, session_id, " +
+			"event_type) VALUES(?, ?, ?, ?, ?, ?)")
 
 		if err != nil {
 			return err
@@ -126,7 +89,8 @@ func (eventRepository SqLiteEventsRepository) insertEvents(events []*models.Even
 
 		for _, event := range events {
 			eventInfo := event.ToEventInfo()
-			result, stmtErr := stmt.Exec(eventInfo.UUID, eventInfo.Username, eventInfo.Timestamp, eventInfo.IP)
+			result, stmtErr := stmt.Exec(eventInfo.UUID, eventInfo.Username, eventInfo.Timestamp, eventInfo.IP,
+				eventInfo.SessionID, eventInfo.Type)
 			if stmtErr != nil {
 				return stmtErr
 			}
@@ -148,3 +112,63 @@ func (eventRepository SqLiteEventsRepository) insertEvents(events []*models.Even
 
 	return results, nil
 }
+
+func (eventRepository SqLiteEventsRepository) FindExpiredEvents(rule models.RetentionRule,
+	limit int) ([]*models.Event, error) {
+	var events []*models.Event
+
+	fnxErr := eventRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		query, args := expiredEventsQuery(rule, limit, sqLitePlaceholder)
+
+		rows, err := context.Database().Query(query, args...)
+		if err != nil {
+			return err
+		}
+
+		events, err = scanEvents(rows)
+
+		return err
+	}, "mode=rw")
+
+	return events, fnxErr
+}
+
+func (eventRepository SqLiteEventsRepository) DeleteEvents(uuids []string) (int64, error) {
+	var deleted int64
+
+	fnxErr := eventRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) (err error) {
+			deleted, err = deleteEvents(tx, "DELETE FROM events WHERE uuid = ?", uuids)
+			return err
+		})
+	}, "mode=rw")
+
+	return deleted, fnxErr
+}
+
+// releases the free pages of the database file, incrementally when the database was created with incremental
+// auto vacuum and with a full VACUUM otherwise
+func (eventRepository SqLiteEventsRepository) Vacuum() error {
+	return eventRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		var autoVacuum int
+		if err := context.Database().QueryRow("PRAGMA auto_vacuum").Scan(&autoVacuum); err != nil {
+			return err
+		}
+
+		statement := "VACUUM"
+		if autoVacuum == sqLiteIncrementalAutoVacuum {
+			statement = "PRAGMA incremental_vacuum"
+		}
+
+		_, err := context.Database().Exec(statement)
+
+		return err
+	}, "mode=rw")
+}
+
+func (eventRepository SqLiteEventsRepository) ExportEvents(query models.ExportQuery,
+	fnx func(event *models.Event) error) error {
+	return eventRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return exportEvents(context.Database(), query, sqLitePlaceholder, fnx)
+	}, "mode=ro")
+}
@@ -1,8 +1,10 @@
 package repository
 
 import (
+	"github.com/frankiennamdi/detection-api/core"
 	"github.com/frankiennamdi/detection-api/test"
 	"log"
+	"math/rand"
 	"testing"
 	"time"
 
@@ -13,7 +15,7 @@ import (
 )
 
 func TestInsertEvent(t *testing.T) {
-	testSetup := test.SetUp()
+	testSetup := test.SetUpWithFileDb()
 	defer testSetup.CleanUp()
 
 	req := require.New(t)
@@ -35,7 +37,7 @@ func TestInsertEvent(t *testing.T) {
 
 func TestInsertEvent_Maintain_Original_When_Uuid_Is_Duplicated(t *testing.T) {
 	initialTime := int64(1514764800)
-	testSetup := test.SetUp()
+	testSetup := test.SetUpWithFileDb()
 
 	defer testSetup.CleanUp()
 
@@ -63,7 +65,7 @@ func TestInsertEvent_Maintain_Original_When_Uuid_Is_Duplicated(t *testing.T) {
 
 func TestInsertEvent_Maintain_Original_When_User_Timestamp_Is_Duplicated(t *testing.T) {
 	initialTime := int64(1514764800)
-	testSetup := test.SetUp()
+	testSetup := test.SetUpWithFileDb()
 
 	defer testSetup.CleanUp()
 
@@ -94,7 +96,7 @@ func TestInsertEvent_Maintain_Original_When_User_Timestamp_Is_Duplicated(t *test
 }
 
 func TestInsertAndQueryEvent(t *testing.T) {
-	testSetup := test.SetUp()
+	testSetup := test.SetUpWithFileDb()
 	defer testSetup.CleanUp()
 
 	req := require.New(t)
@@ -123,9 +125,36 @@ func TestInsertAndQueryEvent(t *testing.T) {
 	req.Equal(eventInfo, actualEventInfo)
 }
 
+func TestInsertAndExportEvent_With_Session(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	eventRepository := NewSQLLiteEventsRepository(testSetup.AppServerContext().EventDb())
+	eventInfo := models.EventInfo{
+		UUID:      "85ad929a-db03-4bf4-9541-8f728fa12e42",
+		Username:  "john",
+		Timestamp: 1514764800,
+		IP:        "1.0.0.0",
+		SessionID: "s1",
+		Type:      models.EventTypeLogout,
+	}
+
+	_, insertErr := eventRepository.InsertEvents([]*models.Event{newTestEvent(eventInfo)})
+	req.NoError(insertErr)
+
+	var exported []models.EventInfo
+
+	req.NoError(eventRepository.ExportEvents(models.ExportQuery{Username: "john"}, func(event *models.Event) error {
+		exported = append(exported, event.ToEventInfo())
+		return nil
+	}))
+	req.Equal([]models.EventInfo{eventInfo}, exported)
+}
+
 func TestInsertAndQueryEvent_That_Events_Are_Returned_In_Order(t *testing.T) {
 	initialTime := int64(1514764800)
-	testSetup := test.SetUp()
+	testSetup := test.SetUpWithFileDb()
 
 	defer testSetup.CleanUp()
 
@@ -161,7 +190,7 @@ func TestInsertAndQueryEvent_That_Events_Are_Returned_In_Order(t *testing.T) {
 }
 
 func TestFindEventByUsername_In_Empty_Database(t *testing.T) {
-	testSetup := test.SetUp()
+	testSetup := test.SetUpWithFileDb()
 
 	defer testSetup.CleanUp()
 
@@ -189,3 +218,54 @@ func newTestEvent(eventInfo models.EventInfo) *models.Event {
 
 	return event
 }
+
+// neighbour lookup for users with a long history, run with go test -bench FindRelatedEvents ./repository/...
+func BenchmarkSQLiteEventsRepository_FindRelatedEvents(b *testing.B) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	benchmarkFindRelatedEvents(b, NewSQLLiteEventsRepository(testSetup.AppServerContext().EventDb()))
+}
+
+func BenchmarkMemoryEventsRepository_FindRelatedEvents(b *testing.B) {
+	benchmarkFindRelatedEvents(b, NewMemoryEventsRepository())
+}
+
+func benchmarkFindRelatedEvents(b *testing.B, eventRepository core.EventRepository) {
+	const eventsPerUser = 100000
+
+	initialTime := int64(1514764800)
+	usernames := []string{"john", "jane"}
+
+	for _, username := range usernames {
+		events := make([]*models.Event, 0, eventsPerUser)
+		for i := 0; i < eventsPerUser; i++ {
+			events = append(events, newTestEvent(models.EventInfo{
+				UUID:      uuid.New().String(),
+				Username:  username,
+				Timestamp: test.AddTime(initialTime, i*2, time.Minute),
+				IP:        "1.0.0.0",
+			}))
+		}
+
+		if _, err := eventRepository.InsertEvents(events); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		event := newTestEvent(models.EventInfo{
+			UUID:      uuid.New().String(),
+			Username:  usernames[i%len(usernames)],
+			Timestamp: test.AddTime(initialTime, rand.Intn(eventsPerUser*2), time.Minute),
+			IP:        "1.0.0.0",
+		})
+		filter := NewRelatedEventsFilter(event)
+
+		if err := eventRepository.FindRelatedEvents(event, filter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/frankiennamdi/detection-api/db"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+const userLocationColumns = "SELECT username, kind, location, count, first_seen, last_seen"
+
+// provides services for storing and retrieving the location profiles of users from SQLite database
+type SqLiteLocationProfileRepository struct {
+	sqLiteDb *db.SqLiteDb
+}
+
+func NewSQLLiteLocationProfileRepository(sqLiteDb *db.SqLiteDb) *SqLiteLocationProfileRepository {
+	return &SqLiteLocationProfileRepository{sqLiteDb: sqLiteDb}
+}
+
+// the rows of the locations are written before they are read, so concurrent events of a user wait for each other
+// and each one sees the ones before it. an event observed before is not added again, its history is the one it got
+// the first time
+func (profileRepository SqLiteLocationProfileRepository) ObserveLocation(username, geohash, country string,
+	timestamp int64) (*models.LocationHistory, error) {
+	history := &models.LocationHistory{Geohash: geohash, Country: country}
+	locations := map[string]string{models.LocationKindCell: geohash}
+
+	if country != "" {
+		locations[models.LocationKindCountry] = country
+	}
+
+	fnxErr := profileRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) error {
+			observed, err := tx.Exec("INSERT OR IGNORE INTO user_location_events(username, timestamp, geohash, "+
+				"country, profile_events, location_count, location_last_seen, country_count) "+
+				"VALUES(?, ?, ?, ?, 0, 0, 0, 0)", username, timestamp, geohash, country)
+			if err != nil {
+				return err
+			}
+
+			added, err := observed.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			if added == 0 {
+				return tx.QueryRow("SELECT geohash, country, profile_events, location_count, location_last_seen, "+
+					"country_count FROM user_location_events WHERE username = ? AND timestamp = ?", username,
+					timestamp).Scan(&history.Geohash, &history.Country, &history.ProfileEvents, &history.LocationCount,
+					&history.LocationLastSeen, &history.CountryCount)
+			}
+
+			for kind, location := range locations {
+				if _, err := tx.Exec("INSERT OR IGNORE INTO user_locations(username, kind, location, count, "+
+					"first_seen, last_seen) VALUES(?, ?, ?, 0, ?, ?)", username, kind, location, timestamp,
+					timestamp); err != nil {
+					return err
+				}
+			}
+
+			if err := tx.QueryRow("SELECT COALESCE(SUM(count), 0) FROM user_locations WHERE username = ? "+
+				"AND kind = ?", username, models.LocationKindCell).Scan(&history.ProfileEvents); err != nil {
+				return err
+			}
+
+			if err := tx.QueryRow("SELECT count, last_seen FROM user_locations WHERE username = ? AND kind = ? "+
+				"AND location = ?", username, models.LocationKindCell, geohash).Scan(&history.LocationCount,
+				&history.LocationLastSeen); err != nil {
+				return err
+			}
+
+			if history.LocationCount == 0 {
+				history.LocationLastSeen = 0
+			}
+
+			if country != "" {
+				if err := tx.QueryRow("SELECT count FROM user_locations WHERE username = ? AND kind = ? "+
+					"AND location = ?", username, models.LocationKindCountry, country).Scan(
+					&history.CountryCount); err != nil {
+					return err
+				}
+			}
+
+			for kind, location := range locations {
+				if _, err := tx.Exec("UPDATE user_locations SET count = count + 1, "+
+					"first_seen = MIN(first_seen, ?), last_seen = MAX(last_seen, ?) "+
+					"WHERE username = ? AND kind = ? AND location = ?", timestamp, timestamp, username, kind,
+					location); err != nil {
+					return err
+				}
+			}
+
+			_, err = tx.Exec("UPDATE user_location_events SET profile_events = ?, location_count = ?, "+
+				"location_last_seen = ?, country_count = ? WHERE username = ? AND timestamp = ?",
+				history.ProfileEvents, history.LocationCount, history.LocationLastSeen, history.CountryCount, username,
+				timestamp)
+
+			return err
+		})
+	}, "mode=rw")
+
+	if fnxErr != nil {
+		return nil, fnxErr
+	}
+
+	return history, nil
+}
+
+// the locations of the user, the most frequent first
+func (profileRepository SqLiteLocationProfileRepository) FindUserLocations(
+	username string) ([]*models.UserLocation, error) {
+	var userLocations []*models.UserLocation
+
+	fnxErr := profileRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		rows, err := context.Database().Query(userLocationColumns+" FROM user_locations WHERE username = ? "+
+			"AND count > 0 ORDER BY kind ASC, count DESC, last_seen DESC", username)
+		if err != nil {
+			return err
+		}
+
+		userLocations, err = scanUserLocations(rows)
+
+		return err
+	}, "mode=rw")
+
+	return userLocations, fnxErr
+}
+
+// replaces the profiles of every user with the locations, and the events they hold with the observations
+func (profileRepository SqLiteLocationProfileRepository) ReplaceUserLocations(userLocations []*models.UserLocation,
+	observations []*models.LocationObservation) error {
+	return profileRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) error {
+			for _, table := range []string{"user_locations", "user_location_events"} {
+				if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+					return err
+				}
+			}
+
+			if err := insertRows(tx, "INSERT INTO user_locations(username, kind, location, count, first_seen, "+
+				"last_seen) VALUES(?, ?, ?, ?, ?, ?)", len(userLocations), func(i int) []interface{} {
+				userLocation := userLocations[i]
+				return []interface{}{userLocation.Username, userLocation.Kind, userLocation.Location,
+					userLocation.Count, userLocation.FirstSeen, userLocation.LastSeen}
+			}); err != nil {
+				return err
+			}
+
+			return insertRows(tx, "INSERT INTO user_location_events(username, timestamp, geohash, country, "+
+				"profile_events, location_count, location_last_seen, country_count) VALUES(?, ?, ?, ?, ?, ?, ?, ?)",
+				len(observations), func(i int) []interface{} {
+					observation := observations[i]
+					return []interface{}{observation.Username, observation.Timestamp, observation.Geohash,
+						observation.Country, observation.ProfileEvents, observation.LocationCount,
+						observation.LocationLastSeen, observation.CountryCount}
+				})
+		})
+	}, "mode=rw")
+}
+
+// runs the statement for each of the rows, with the values of the row
+func insertRows(tx *sql.Tx, query string, rows int, values func(i int) []interface{}) (err error) {
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if closeErr := stmt.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for i := 0; i < rows; i++ {
+		if _, err := stmt.Exec(values(i)...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanUserLocations(rows *sql.Rows) (userLocations []*models.UserLocation, err error) {
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		userLocation := &models.UserLocation{}
+		if err := rows.Scan(&userLocation.Username, &userLocation.Kind, &userLocation.Location,
+			&userLocation.Count, &userLocation.FirstSeen, &userLocation.LastSeen); err != nil {
+			return nil, err
+		}
+
+		userLocations = append(userLocations, userLocation)
+	}
+
+	return userLocations, rows.Err()
+}
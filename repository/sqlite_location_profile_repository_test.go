@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveLocation_Returns_History_Before_Event(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	profileRepository := NewSQLLiteLocationProfileRepository(testSetup.AppServerContext().EventDb())
+
+	history, err := profileRepository.ObserveLocation("bob", "dr5r", "US", 200)
+	req.NoError(err)
+	req.Equal(&models.LocationHistory{Geohash: "dr5r", Country: "US"}, history)
+
+	_, err = profileRepository.ObserveLocation("bob", "dr5r", "US", 100)
+	req.NoError(err)
+
+	history, err = profileRepository.ObserveLocation("bob", "9q5c", "US", 300)
+	req.NoError(err)
+	req.Equal(&models.LocationHistory{Geohash: "9q5c", Country: "US", ProfileEvents: 2, CountryCount: 2}, history)
+
+	history, err = profileRepository.ObserveLocation("bob", "dr5r", "", 400)
+	req.NoError(err)
+	req.Equal(&models.LocationHistory{Geohash: "dr5r", ProfileEvents: 3, LocationCount: 2, LocationLastSeen: 200},
+		history)
+
+	userLocations, err := profileRepository.FindUserLocations("bob")
+	req.NoError(err)
+	req.Equal([]*models.UserLocation{
+		{Username: "bob", Kind: models.LocationKindCell, Location: "dr5r", Count: 3, FirstSeen: 100, LastSeen: 400},
+		{Username: "bob", Kind: models.LocationKindCell, Location: "9q5c", Count: 1, FirstSeen: 300, LastSeen: 300},
+		{Username: "bob", Kind: models.LocationKindCountry, Location: "US", Count: 3, FirstSeen: 100, LastSeen: 300},
+	}, userLocations)
+}
+
+func TestReplaceUserLocations(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	profileRepository := NewSQLLiteLocationProfileRepository(testSetup.AppServerContext().EventDb())
+
+	_, err := profileRepository.ObserveLocation("bob", "dr5r", "US", 100)
+	req.NoError(err)
+
+	alice := &models.UserLocation{Username: "alice", Kind: models.LocationKindCell, Location: "9q5c", Count: 4,
+		FirstSeen: 100, LastSeen: 400}
+	observation := &models.LocationObservation{Username: "alice", Timestamp: 400,
+		LocationHistory: models.LocationHistory{Geohash: "9q5c", ProfileEvents: 3, LocationCount: 3,
+			LocationLastSeen: 300}}
+	req.NoError(profileRepository.ReplaceUserLocations([]*models.UserLocation{alice},
+		[]*models.LocationObservation{observation}))
+
+	userLocations, err := profileRepository.FindUserLocations("bob")
+	req.NoError(err)
+	req.Empty(userLocations)
+
+	userLocations, err = profileRepository.FindUserLocations("alice")
+	req.NoError(err)
+	req.Equal([]*models.UserLocation{alice}, userLocations)
+
+	// the events of the replaced profiles can be observed again, the ones of the observations cannot
+	history, err := profileRepository.ObserveLocation("bob", "dr5r", "US", 100)
+	req.NoError(err)
+	req.Equal(&models.LocationHistory{Geohash: "dr5r", Country: "US"}, history)
+
+	history, err = profileRepository.ObserveLocation("alice", "9q5c", "", 400)
+	req.NoError(err)
+	req.Equal(&observation.LocationHistory, history)
+
+	userLocations, err = profileRepository.FindUserLocations("alice")
+	req.NoError(err)
+	req.Equal([]*models.UserLocation{alice}, userLocations)
+}
+
+func TestObserveLocation_Adds_Each_Event_Once(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	profileRepository := NewSQLLiteLocationProfileRepository(testSetup.AppServerContext().EventDb())
+
+	_, err := profileRepository.ObserveLocation("bob", "dr5r", "US", 100)
+	req.NoError(err)
+
+	history, err := profileRepository.ObserveLocation("bob", "dr5r", "US", 200)
+	req.NoError(err)
+
+	_, err = profileRepository.ObserveLocation("bob", "9q5c", "US", 300)
+	req.NoError(err)
+
+	// the event at 200 again, as resubmitted or replayed, gets the history it got the first time
+	replayed, err := profileRepository.ObserveLocation("bob", "dr5r", "US", 200)
+	req.NoError(err)
+	req.Equal(&models.LocationHistory{Geohash: "dr5r", Country: "US", ProfileEvents: 1, LocationCount: 1,
+		LocationLastSeen: 100, CountryCount: 1}, replayed)
+	req.Equal(history, replayed)
+
+	userLocations, err := profileRepository.FindUserLocations("bob")
+	req.NoError(err)
+	req.Equal([]*models.UserLocation{
+		{Username: "bob", Kind: models.LocationKindCell, Location: "dr5r", Count: 2, FirstSeen: 100, LastSeen: 200},
+		{Username: "bob", Kind: models.LocationKindCell, Location: "9q5c", Count: 1, FirstSeen: 300, LastSeen: 300},
+		{Username: "bob", Kind: models.LocationKindCountry, Location: "US", Count: 3, FirstSeen: 100, LastSeen: 300},
+	}, userLocations)
+}
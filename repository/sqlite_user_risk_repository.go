@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/db"
+	"github.com/frankiennamdi/detection-api/models"
+)
+
+const userRiskColumns = "SELECT username, score, as_of, rule_hits, travels, updated_at"
+
+// provides services for storing and retrieving the rolling risk of users from SQLite database
+type SqLiteUserRiskRepository struct {
+	sqLiteDb *db.SqLiteDb
+}
+
+func NewSQLLiteUserRiskRepository(sqLiteDb *db.SqLiteDb) *SqLiteUserRiskRepository {
+	return &SqLiteUserRiskRepository{sqLiteDb: sqLiteDb}
+}
+
+// the row of the user is written before it is read, so concurrent updates of a user wait for each other instead of
+// overwriting one another. a travel already added is skipped, and the superseded travel is taken out when it was
+// added
+func (riskRepository SqLiteUserRiskRepository) AddUserRisk(username string, travelRisks []models.TravelRisk,
+	superseded *models.TravelRisk, halfLife time.Duration) (*models.UserRisk, error) {
+	var userRisk *models.UserRisk
+
+	fnxErr := riskRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		return context.WithTransaction(func(tx *sql.Tx) (err error) {
+			updatedAt := time.Now().Unix()
+
+			if _, err := tx.Exec("INSERT OR IGNORE INTO user_risk(username, score, as_of, rank_key, rule_hits, "+
+				"travels, updated_at) VALUES(?, 0, 0, 0, 0, 0, ?)", username, updatedAt); err != nil {
+				return err
+			}
+
+			rows, err := tx.Query(userRiskColumns+" FROM user_risk WHERE username = ?", username)
+			if err != nil {
+				return err
+			}
+
+			userRisks, err := scanUserRisks(rows)
+			if err != nil {
+				return err
+			}
+
+			userRisk = userRisks[0]
+
+			if superseded != nil {
+				if err := removeTravelRisk(tx, userRisk, *superseded, halfLife); err != nil {
+					return err
+				}
+			}
+
+			for _, travelRisk := range travelRisks {
+				result, err := tx.Exec("INSERT OR IGNORE INTO user_risk_travels(username, from_timestamp, "+
+					"to_timestamp, score, rule_hits) VALUES(?, ?, ?, ?, ?)", username, travelRisk.From, travelRisk.To,
+					travelRisk.Score, travelRisk.RuleHits)
+				if err != nil {
+					return err
+				}
+
+				added, err := result.RowsAffected()
+				if err != nil {
+					return err
+				}
+
+				if added > 0 {
+					userRisk.Add(models.EventRisk{Score: travelRisk.Score, RuleHits: travelRisk.RuleHits, Travels: 1},
+						travelRisk.To, halfLife)
+				}
+			}
+
+			userRisk.UpdatedAt = updatedAt
+
+			_, err = tx.Exec("UPDATE user_risk SET score = ?, as_of = ?, rank_key = ?, rule_hits = ?, travels = ?, "+
+				"updated_at = ? WHERE username = ?", userRisk.Score, userRisk.AsOf, userRisk.RankKey(halfLife),
+				userRisk.RuleHits, userRisk.Travels, userRisk.UpdatedAt, username)
+
+			return err
+		})
+	}, "mode=rw")
+
+	if fnxErr != nil {
+		return nil, fnxErr
+	}
+
+	return userRisk, nil
+}
+
+// takes the stored risk of the travel out of the user risk, nothing when the travel was never added
+func removeTravelRisk(tx *sql.Tx, userRisk *models.UserRisk, superseded models.TravelRisk,
+	halfLife time.Duration) error {
+	travelRisk := models.TravelRisk{From: superseded.From, To: superseded.To}
+
+	err := tx.QueryRow("SELECT score, rule_hits FROM user_risk_travels WHERE username = ? AND from_timestamp = ? "+
+		"AND to_timestamp = ?", userRisk.Username, superseded.From, superseded.To).Scan(&travelRisk.Score,
+		&travelRisk.RuleHits)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM user_risk_travels WHERE username = ? AND from_timestamp = ? "+
+		"AND to_timestamp = ?", userRisk.Username, superseded.From, superseded.To); err != nil {
+		return err
+	}
+
+	userRisk.Remove(travelRisk, halfLife)
+
+	return nil
+}
+
+// nil when no risk was recorded for the user
+func (riskRepository SqLiteUserRiskRepository) FindUserRisk(username string) (*models.UserRisk, error) {
+	var userRisks []*models.UserRisk
+
+	fnxErr := riskRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		rows, err := context.Database().Query(userRiskColumns+" FROM user_risk WHERE username = ?", username)
+		if err != nil {
+			return err
+		}
+
+		userRisks, err = scanUserRisks(rows)
+
+		return err
+	}, "mode=rw")
+
+	if fnxErr != nil || len(userRisks) == 0 {
+		return nil, fnxErr
+	}
+
+	return userRisks[0], nil
+}
+
+// the users of the highest risk now, in the order of their risk
+func (riskRepository SqLiteUserRiskRepository) FindTopUserRisks(limit int) ([]*models.UserRisk, error) {
+	var userRisks []*models.UserRisk
+
+	fnxErr := riskRepository.sqLiteDb.WithSqLiteDbContext(func(context *db.SqLiteDbContext) error {
+		rows, err := context.Database().Query(userRiskColumns+" FROM user_risk WHERE score > 0 "+
+			"ORDER BY rank_key DESC, username ASC LIMIT ?", limit)
+		if err != nil {
+			return err
+		}
+
+		userRisks, err = scanUserRisks(rows)
+
+		return err
+	}, "mode=rw")
+
+	return userRisks, fnxErr
+}
+
+func scanUserRisks(rows *sql.Rows) (userRisks []*models.UserRisk, err error) {
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		userRisk := &models.UserRisk{}
+		if err := rows.Scan(&userRisk.Username, &userRisk.Score, &userRisk.AsOf, &userRisk.RuleHits,
+			&userRisk.Travels, &userRisk.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		userRisks = append(userRisks, userRisk)
+	}
+
+	return userRisks, rows.Err()
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/frankiennamdi/detection-api/models"
+	"github.com/frankiennamdi/detection-api/test"
+	"github.com/stretchr/testify/require"
+)
+
+const riskDay = int64(24 * 3600)
+
+func TestAddUserRisk_Accumulates_Decayed_Risk(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	riskRepository := NewSQLLiteUserRiskRepository(testSetup.AppServerContext().EventDb())
+	halfLife := 24 * time.Hour
+
+	found, err := riskRepository.FindUserRisk("bob")
+	req.NoError(err)
+	req.Nil(found)
+
+	_, err = riskRepository.AddUserRisk("bob", []models.TravelRisk{{From: 0, To: riskDay, Score: 8, RuleHits: 1}},
+		nil, halfLife)
+	req.NoError(err)
+
+	userRisk, err := riskRepository.AddUserRisk("bob", []models.TravelRisk{{From: riskDay, To: 2 * riskDay,
+		Score: 1}}, nil, halfLife)
+	req.NoError(err)
+	req.Equal(5.0, userRisk.Score)
+	req.Equal(2*riskDay, userRisk.AsOf)
+	req.Equal(int64(1), userRisk.RuleHits)
+	req.Equal(int64(2), userRisk.Travels)
+
+	found, err = riskRepository.FindUserRisk("bob")
+	req.NoError(err)
+	req.Equal(userRisk, found)
+}
+
+func TestAddUserRisk_Adds_Each_Travel_Once(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	riskRepository := NewSQLLiteUserRiskRepository(testSetup.AppServerContext().EventDb())
+	halfLife := 24 * time.Hour
+	travelRisk := models.TravelRisk{From: 0, To: 2 * riskDay, Score: 8, RuleHits: 1}
+
+	added, err := riskRepository.AddUserRisk("bob", []models.TravelRisk{travelRisk}, nil, halfLife)
+	req.NoError(err)
+
+	replayed, err := riskRepository.AddUserRisk("bob", []models.TravelRisk{travelRisk}, nil, halfLife)
+	req.NoError(err)
+	req.Equal(added.Score, replayed.Score)
+	req.Equal(added.RuleHits, replayed.RuleHits)
+	req.Equal(added.Travels, replayed.Travels)
+
+	// an event a day after the first one arrives late, its travels replace the one between its neighbours
+	userRisk, err := riskRepository.AddUserRisk("bob", []models.TravelRisk{
+		{From: 0, To: riskDay, Score: 1},
+		{From: riskDay, To: 2 * riskDay, Score: 2},
+	}, &models.TravelRisk{From: 0, To: 2 * riskDay}, halfLife)
+	req.NoError(err)
+	req.Equal(2.5, userRisk.Score)
+	req.Equal(2*riskDay, userRisk.AsOf)
+	req.Equal(int64(0), userRisk.RuleHits)
+	req.Equal(int64(2), userRisk.Travels)
+
+	replayed, err = riskRepository.AddUserRisk("bob", []models.TravelRisk{
+		{From: 0, To: riskDay, Score: 1},
+		{From: riskDay, To: 2 * riskDay, Score: 2},
+	}, &models.TravelRisk{From: 0, To: 2 * riskDay}, halfLife)
+	req.NoError(err)
+	req.Equal(2.5, replayed.Score)
+	req.Equal(int64(2), replayed.Travels)
+}
+
+func TestAddUserRisk_Concurrently(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	riskRepository := NewSQLLiteUserRiskRepository(testSetup.AppServerContext().EventDb())
+	waitGroup := sync.WaitGroup{}
+	errs := make(chan error, 20)
+
+	for i := 0; i < 20; i++ {
+		waitGroup.Add(1)
+
+		go func(from int64) {
+			defer waitGroup.Done()
+
+			_, err := riskRepository.AddUserRisk("bob", []models.TravelRisk{{From: from, To: riskDay, Score: 1}},
+				nil, 24*time.Hour)
+			errs <- err
+		}(int64(i))
+	}
+
+	waitGroup.Wait()
+	close(errs)
+
+	for err := range errs {
+		req.NoError(err)
+	}
+
+	userRisk, err := riskRepository.FindUserRisk("bob")
+	req.NoError(err)
+	req.Equal(20.0, userRisk.Score)
+	req.Equal(int64(20), userRisk.Travels)
+}
+
+func TestFindTopUserRisks_Orders_By_Decayed_Risk(t *testing.T) {
+	testSetup := test.SetUpWithFileDb()
+	defer testSetup.CleanUp()
+
+	req := require.New(t)
+	riskRepository := NewSQLLiteUserRiskRepository(testSetup.AppServerContext().EventDb())
+	halfLife := 24 * time.Hour
+
+	for _, userRisk := range []struct {
+		username  string
+		score     float64
+		timestamp int64
+	}{
+		// 16 two days ago is 4 now, less than 5 today
+		{"old", 16, riskDay},
+		{"recent", 5, 3 * riskDay},
+		{"low", 1, 3 * riskDay},
+		{"none", 0, 3 * riskDay},
+	} {
+		_, err := riskRepository.AddUserRisk(userRisk.username, []models.TravelRisk{{From: 0,
+			To: userRisk.timestamp, Score: userRisk.score}}, nil, halfLife)
+		req.NoError(err)
+	}
+
+	top, err := riskRepository.FindTopUserRisks(2)
+	req.NoError(err)
+	req.Len(top, 2)
+	req.Equal("recent", top[0].Username)
+	req.Equal("old", top[1].Username)
+
+	top, err = riskRepository.FindTopUserRisks(10)
+	req.NoError(err)
+	req.Len(top, 3)
+	req.Equal("low", top[2].Username)
+}
@@ -0,0 +1,9 @@
+// the resources embedded into the binary
+package resources
+
+import _ "embed"
+
+// the default config, read when no config file is given
+//
+//go:embed config.yml
+var DefaultConfig []byte
@@ -40,6 +40,8 @@ func GetEnvironmentValue(key, defaultValue string) string {
 	return defaultValue
 }
 
+// resolves a relative path against the root of the source tree, e.g. for go run and the tests, or against the
+// working directory when the binary runs where the source tree is not
 func Resolve(path string) string {
 	if filepath.IsAbs(path) {
 		return path
@@ -49,6 +51,10 @@ func Resolve(path string) string {
 	callerDir := filepath.Join(filepath.Dir(callerFile))
 	rootDir := filepath.Dir(callerDir)
 
+	if info, err := os.Stat(filepath.Join(rootDir, "go.mod")); err != nil || info.IsDir() {
+		return path
+	}
+
 	return fmt.Sprintf("%s/%s", rootDir, path)
 }
 
@@ -0,0 +1,31 @@
+package support
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// a fixed set of mutexes selected by hashing the key. work for the same key is serialized while unrelated keys
+// rarely contend, and the memory used does not grow with the number of keys
+type KeyedMutex struct {
+	shards []sync.Mutex
+}
+
+func NewKeyedMutex(shards int) *KeyedMutex {
+	if shards < 1 {
+		shards = 1
+	}
+
+	return &KeyedMutex{shards: make([]sync.Mutex, shards)}
+}
+
+// locks the shard of the key and returns the function that unlocks it
+func (keyedMutex *KeyedMutex) Lock(key string) func() {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+
+	shard := &keyedMutex.shards[hash.Sum32()%uint32(len(keyedMutex.shards))]
+	shard.Lock()
+
+	return shard.Unlock
+}
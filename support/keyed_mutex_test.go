@@ -0,0 +1,37 @@
+package support
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyedMutex_Serializes_Same_Key(t *testing.T) {
+	keyedMutex := NewKeyedMutex(8)
+	counters := map[string]*int{"bob": new(int), "mary": new(int)}
+	waitGroup := sync.WaitGroup{}
+
+	for i := 0; i < 100; i++ {
+		for _, key := range []string{"bob", "mary"} {
+			waitGroup.Add(1)
+
+			go func(key string) {
+				defer waitGroup.Done()
+
+				unlock := keyedMutex.Lock(key)
+				defer unlock()
+
+				// unsynchronized read, modify, write that only adds up when the key is locked
+				value := *counters[key]
+				*counters[key] = value + 1
+			}(key)
+		}
+	}
+
+	waitGroup.Wait()
+
+	req := require.New(t)
+	req.Equal(100, *counters["bob"])
+	req.Equal(100, *counters["mary"])
+}
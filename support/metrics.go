@@ -0,0 +1,29 @@
+package support
+
+import "expvar"
+
+// counters of the application, published with the other expvar variables
+var metrics = expvar.NewMap("detection_api")
+
+const (
+	ThrottledRequests = "throttled_requests"
+	OversizedRequests = "oversized_requests"
+	PurgedEvents      = "purged_events"
+	ArchivedEvents    = "archived_events"
+)
+
+func IncrementCounter(name string) {
+	metrics.Add(name, 1)
+}
+
+func AddToCounter(name string, delta int64) {
+	metrics.Add(name, delta)
+}
+
+func CounterValue(name string) int64 {
+	if value, ok := metrics.Get(name).(*expvar.Int); ok {
+		return value.Value()
+	}
+
+	return 0
+}
@@ -17,24 +17,55 @@ type Test struct {
 }
 
 func SetUp() *Test {
+	return SetUpWithConfig(func(appConfig *config.AppConfig) {})
+}
+
+// sets up like SetUp, but lets the caller adjust the config before the server is configured
+func SetUpWithConfig(configure func(appConfig *config.AppConfig)) *Test {
+	return setUp(false, configure)
+}
+
+// sets up like SetUp, but keeps the event db in a SQLite file of the test directory like the sqlite3 driver does,
+// for the tests of the repositories over the file
+func SetUpWithFileDb() *Test {
+	return setUp(true, func(appConfig *config.AppConfig) {})
+}
+
+// the event db is held in memory unless fileDb is set, the directory then takes it next to the files a test writes
+// like backups
+func setUp(fileDb bool, configure func(appConfig *config.AppConfig)) *Test {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	log.Println("setting up")
 
-	temporaryDir := support.NewTemporaryDir("", "sqlite3-driver-test")
+	temporaryDir := support.NewTemporaryDir("", "detection-api-test")
 	path := support.Resolve("migrations")
 	appConfig := config.AppConfig{
 		EventDb: config.EventDbConfig{
-			File:          filepath.Join(temporaryDir.Path(), "sqlite3.db"),
+			Driver:        config.MemoryDriver,
 			Name:          "event_db",
 			MigrationLoc:  path,
 			MaxConnection: 100,
+			AutoMigrate:   true,
 		},
 		IPGeoDbConfig: config.IPGeoDbConfig{
 			Location:      "resources/geo-database/GeoLite2-City.mmdb",
 			MaxConnection: 100},
 		SuspiciousSpeed: 500,
+		Backup:          config.BackupConfig{Dir: filepath.Join(temporaryDir.Path(), "backups")},
+		Risk: config.RiskConfig{Enabled: true, HalfLifeHours: 24, LeaderboardSize: 10,
+			MaxLeaderboardSize: 100},
+		Novelty: config.NoveltyConfig{Enabled: true, GeohashPrecision: 4, MinEvents: 5, RareShare: 0.05},
+		Window:  config.WindowConfig{Enabled: true, Events: 20, Hours: 24},
+		Session: config.SessionConfig{Enabled: true, IdleMinutes: 30, MinMiles: 100},
+	}
+
+	if fileDb {
+		appConfig.EventDb.Driver = config.SQLiteDriver
+		appConfig.EventDb.File = filepath.Join(temporaryDir.Path(), "sqlite3.db")
 	}
 
+	configure(&appConfig)
+
 	server := core.NewServer(appConfig)
 	appServerContext := server.Configure()
 
@@ -47,9 +78,19 @@ func SetUp() *Test {
 
 func (test *Test) CleanUp() {
 	log.Println("cleaning up")
+
+	if err := test.appServerContext.EventDb().Close(); err != nil {
+		log.Println(err)
+	}
+
 	test.temporaryDir.Clean()
 }
 
+// directory for the files of a test, removed on clean up
+func (test *Test) Dir() string {
+	return test.temporaryDir.Path()
+}
+
 func (test *Test) AppConfig() config.AppConfig {
 	return test.appConfig
 }